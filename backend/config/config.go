@@ -55,3 +55,16 @@ func EnsureBucketExists(client *minio.Client, bucketName string) {
 		log.Printf("bucket已存在: %s", bucketName)
 	}
 }
+
+// EnsureBucketPrivate 删除bucket的匿名访问策略，对象只能通过服务端或临时URL访问
+func EnsureBucketPrivate(client *minio.Client, bucketName string) {
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, bucketName)
+	if err != nil || !exists {
+		return
+	}
+
+	if err := client.SetBucketPolicy(ctx, bucketName, ""); err != nil {
+		log.Printf("设置bucket为私有失败: %v, 桶名: %s", err, bucketName)
+	}
+}
@@ -1,6 +1,10 @@
 package controllers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,16 +14,22 @@ import (
 	"gorm.io/gorm"
 
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
 // AdminController 管理员控制器
 type AdminController struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Notifier *utils.Notifier
+	Files    *utils.MinioUtils    // 论坛附件存储
+	Filter   *utils.ContentFilter // 敏感词过滤，修改词表后重新加载
+	Spam     *utils.SpamGuard     // 反垃圾，查看和解除用户的发帖冷却
+	Forum    *ForumController     // 审核通过的回复由论坛发布和推送
 }
 
 // NewAdminController 创建管理员控制器实例
-func NewAdminController(db *gorm.DB) *AdminController {
-	return &AdminController{DB: db}
+func NewAdminController(db *gorm.DB, notifier *utils.Notifier, files *utils.MinioUtils, filter *utils.ContentFilter, spam *utils.SpamGuard, forum *ForumController) *AdminController {
+	return &AdminController{DB: db, Notifier: notifier, Files: files, Filter: filter, Spam: spam, Forum: forum}
 }
 
 // GetPendingResources 获取待审核资源列表
@@ -113,6 +123,9 @@ func (c *AdminController) ReviewResource(ctx *gin.Context) {
 		return
 	}
 
+	// 资源命中审核词的记录随资源审核一并处理
+	resolveModerationLogs(c.DB, models.ModerationSourceResource, resource.ID, admin.ID, input.Status, input.Message)
+
 	// 如果审核通过，奖励用户积分
 	if input.Status == "approved" {
 		// 添加积分记录
@@ -131,6 +144,20 @@ func (c *AdminController) ReviewResource(ctx *gin.Context) {
 		c.DB.Model(&models.User{}).Where("id = ?", resource.UserID).Update("points", gorm.Expr("points + ?", 20))
 	}
 
+	// 通知上传者审核结果
+	notification := &models.Notification{
+		UserID:     resource.UserID,
+		Type:       models.NotificationReview,
+		Title:      fmt.Sprintf("你的资源《%s》未通过审核", resource.Title),
+		Content:    input.Message,
+		TargetType: models.NotificationTargetResource,
+		TargetID:   resource.ID,
+	}
+	if input.Status == "approved" {
+		notification.Title = fmt.Sprintf("你的资源《%s》已通过审核，获得20积分", resource.Title)
+	}
+	c.Notifier.Notify(notification)
+
 	// 返回更新后的资源
 	c.DB.Preload("User").Preload("Category").First(&resource, id)
 	ctx.JSON(http.StatusOK, resource)
@@ -426,6 +453,40 @@ func (c *AdminController) DeleteResource(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "资源已删除"})
 }
 
+// SetResourceSharing 设置资源是否允许通过分享链接下载
+func (c *AdminController) SetResourceSharing(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	// 获取资源ID
+	id := ctx.Param("id")
+
+	// 绑定请求数据
+	var input struct {
+		Disabled bool `json:"disabled"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var resource models.Resource
+	if err := c.DB.First(&resource, id).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+
+	// 禁止分享后，已有的分享链接在下载时会被拒绝
+	if err := c.DB.Model(&resource).Update("sharing_disabled", input.Disabled).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新分享设置失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "分享设置已更新", "sharing_disabled": input.Disabled})
+}
+
 // GetTopics 获取论坛话题列表
 func (c *AdminController) GetTopics(ctx *gin.Context) {
 	// 验证是否为管理员
@@ -514,15 +575,30 @@ func (c *AdminController) DeleteTopic(ctx *gin.Context) {
 	// 获取话题ID
 	id := ctx.Param("id")
 
-	// 删除话题
-	result := c.DB.Delete(&models.Topic{}, id)
-	if result.Error != nil {
+	var topic models.Topic
+	if err := c.DB.First(&topic, id).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "话题不存在"})
+		return
+	}
+
+	// 退回托管中的悬赏，然后删除话题和相关回复
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := refundBounty(tx, &topic); err != nil {
+			return err
+		}
+		if err := tx.Delete(&topic).Error; err != nil {
+			return err
+		}
+		return tx.Where("topic_id = ?", topic.ID).Delete(&models.Reply{}).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除话题失败"})
 		return
 	}
 
-	// 删除相关回复
-	c.DB.Where("topic_id = ?", id).Delete(&models.Reply{})
+	removeTopicWatches(c.DB, topic.ID)
+	removeTopicAttachments(c.DB, c.Files, topic.ID)
+	unlinkDiscussionTopic(c.DB, topic.ID)
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "话题已删除"})
 }
@@ -657,6 +733,8 @@ func (c *AdminController) AdjustPoints(ctx *gin.Context) {
 	// 更新用户积分
 	c.DB.Model(&models.User{}).Where("id = ?", input.UserID).Update("points", gorm.Expr("points + ?", input.Points))
 
+	c.Notifier.Notify(pointsAdjustedNotification(input.UserID, input.Points, input.Description))
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "积分已调整"})
 }
 
@@ -820,3 +898,56 @@ func (c *AdminController) GetForumStats(ctx *gin.Context) {
 		"daily_reply_stats": dailyReplyStats,
 	})
 }
+
+// ExtractWatermark 从泄露的PDF文件中提取水印，定位来源下载
+func (c *AdminController) ExtractWatermark(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	file, _, err := ctx.Request.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return
+	}
+
+	traceCode, err := utils.ExtractPDFWatermark(bytes.NewReader(content))
+	if err != nil {
+		if errors.Is(err, utils.ErrWatermarkNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "文件中未找到水印"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无法解析PDF文件"})
+		return
+	}
+
+	response := gin.H{"trace_code": traceCode}
+
+	// 追踪码本身携带下载用户和时间，即使下载记录已删除也能定位
+	// 通过分享链接匿名下载的副本追踪码中用户ID为0，下载来源见记录中的分享链接
+	if downloaderID, downloadedAt, ok := utils.ParseTraceCode(traceCode); ok {
+		if downloaderID != 0 {
+			response["user_id"] = downloaderID
+		}
+		response["downloaded_at"] = downloadedAt
+	}
+
+	var record models.WatermarkRecord
+	if err := c.DB.Preload("User").Preload("Resource").Preload("ShareLink").Where("trace_code = ?", traceCode).First(&record).Error; err == nil {
+		response["record"] = record
+		if record.ShareLink != nil {
+			// 分享链接的创建者对泄露负责
+			response["share_link_id"] = record.ShareLink.ID
+			response["sharer_id"] = record.ShareLink.UserID
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// featuredRewardPoints 主题被加精时奖励作者的积分
+const featuredRewardPoints = 20
+
+// PinTopic 置顶或取消置顶主题
+// scope为global时在所有列表中置顶，为category时只在所属分类中置顶，为空时取消置顶
+func (c *AdminController) PinTopic(ctx *gin.Context) {
+	topic, ok := c.loadModerationTopic(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Scope string `json:"scope" binding:"omitempty,oneof=global category"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.DB.Model(topic).Update("pin_scope", input.Scope).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新置顶状态失败"})
+		return
+	}
+
+	c.DB.Preload("User").Preload("Category").First(topic, topic.ID)
+	ctx.JSON(http.StatusOK, topic)
+}
+
+// LockTopic 锁定或解锁主题，锁定后普通用户不能回复
+func (c *AdminController) LockTopic(ctx *gin.Context) {
+	topic, ok := c.loadModerationTopic(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Locked bool `json:"locked"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.DB.Model(topic).Update("is_locked", input.Locked).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新锁定状态失败"})
+		return
+	}
+
+	c.DB.Preload("User").Preload("Category").First(topic, topic.ID)
+	ctx.JSON(http.StatusOK, topic)
+}
+
+// FeatureTopic 设置或取消精华
+// 第一次加精时奖励作者积分，取消精华不扣回，再次加精也不重复奖励
+func (c *AdminController) FeatureTopic(ctx *gin.Context) {
+	topic, ok := c.loadModerationTopic(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Featured bool `json:"featured"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward := input.Featured && !topic.FeatureRewarded
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"is_featured": input.Featured}
+		if reward {
+			updates["feature_rewarded"] = true
+		}
+		if err := tx.Model(topic).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if !reward {
+			return nil
+		}
+
+		if err := tx.Create(&models.PointRecord{
+			UserID:      topic.UserID,
+			Points:      featuredRewardPoints,
+			Type:        "featured",
+			Description: "主题被设为精华奖励",
+			CreatedAt:   time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", topic.UserID).
+			Update("points", gorm.Expr("points + ?", featuredRewardPoints)).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新精华状态失败"})
+		return
+	}
+
+	if input.Featured {
+		title := fmt.Sprintf("你的主题《%s》被设为精华", topic.Title)
+		if reward {
+			title += fmt.Sprintf("，获得%d积分", featuredRewardPoints)
+		}
+		c.notifyModeration(topic, title, "")
+	}
+
+	c.DB.Preload("User").Preload("Category").First(topic, topic.ID)
+	ctx.JSON(http.StatusOK, topic)
+}
+
+// MoveTopic 将主题移动到其他分类
+func (c *AdminController) MoveTopic(ctx *gin.Context) {
+	topic, ok := c.loadModerationTopic(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CategoryID uint `json:"category_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var category models.Category
+	if err := c.DB.First(&category, input.CategoryID).Error; err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+		return
+	}
+
+	if topic.CategoryID == category.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "主题已在该分类中"})
+		return
+	}
+
+	// 分类内置顶只对原分类有效，移动后取消
+	updates := map[string]interface{}{"category_id": category.ID}
+	if topic.PinScope == models.PinScopeCategory {
+		updates["pin_scope"] = ""
+	}
+
+	if err := c.DB.Model(topic).Updates(updates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "移动主题失败"})
+		return
+	}
+
+	c.notifyModeration(topic, fmt.Sprintf("你的主题《%s》被移动到「%s」分类", topic.Title, category.Name), "")
+
+	c.DB.Preload("User").Preload("Category").First(topic, topic.ID)
+	ctx.JSON(http.StatusOK, topic)
+}
+
+// HideTopic 隐藏或恢复主题
+// 隐藏的主题不出现在列表中，只有作者和管理员可以查看，作者可以看到隐藏原因
+func (c *AdminController) HideTopic(ctx *gin.Context) {
+	topic, ok := c.loadModerationTopic(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Hidden bool   `json:"hidden"`
+		Reason string `json:"reason" binding:"max=255"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Hidden && input.Reason == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "隐藏主题需要填写原因"})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"is_hidden":     input.Hidden,
+		"hidden_reason": "",
+		"hidden_at":     nil,
+	}
+	if input.Hidden {
+		updates["hidden_reason"] = input.Reason
+		updates["hidden_at"] = time.Now()
+	}
+
+	if err := c.DB.Model(topic).Updates(updates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新隐藏状态失败"})
+		return
+	}
+
+	if input.Hidden {
+		c.notifyModeration(topic, fmt.Sprintf("你的主题《%s》已被隐藏", topic.Title), input.Reason)
+	} else {
+		c.notifyModeration(topic, fmt.Sprintf("你的主题《%s》已恢复显示", topic.Title), "")
+	}
+
+	c.DB.Preload("User").Preload("Category").First(topic, topic.ID)
+	ctx.JSON(http.StatusOK, topic)
+}
+
+// loadModerationTopic 校验管理员权限并查询要操作的主题，失败时直接写入错误响应
+func (c *AdminController) loadModerationTopic(ctx *gin.Context) (*models.Topic, bool) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return nil, false
+	}
+
+	var topic models.Topic
+	if err := c.DB.First(&topic, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return nil, false
+	}
+
+	return &topic, true
+}
+
+// notifyModeration 通知作者主题的版务变化，作为系统通知发送，不受作者屏蔽设置影响
+func (c *AdminController) notifyModeration(topic *models.Topic, title, content string) {
+	c.Notifier.Notify(&models.Notification{
+		UserID:     topic.UserID,
+		Type:       models.NotificationModeration,
+		Title:      title,
+		Content:    content,
+		TargetType: models.NotificationTargetTopic,
+		TargetID:   topic.ID,
+		TopicID:    &topic.ID,
+	})
+}
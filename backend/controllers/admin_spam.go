@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+)
+
+// setUserContentShadowed 设置用户全部主题、回复和评论的静默状态
+// 静默和待审核的回复不计入主题回复数，隐藏或恢复回复时同步调整
+func setUserContentShadowed(tx *gorm.DB, userID uint, shadowed bool) error {
+	delta := "+"
+	if shadowed {
+		delta = "-"
+	}
+	if err := tx.Exec("UPDATE topics JOIN (SELECT topic_id, COUNT(*) AS count FROM replies"+
+		" WHERE user_id = ? AND shadowed = ? AND pending = ? AND is_deleted = ? AND deleted_at IS NULL GROUP BY topic_id) AS changed"+
+		" ON changed.topic_id = topics.id SET topics.reply_count = topics.reply_count "+delta+" changed.count",
+		userID, !shadowed, false, false).Error; err != nil {
+		return err
+	}
+
+	for _, model := range []interface{}{&models.Topic{}, &models.Reply{}, &models.Comment{}} {
+		if err := tx.Model(model).Where("user_id = ? AND shadowed = ?", userID, !shadowed).
+			UpdateColumn("shadowed", shadowed).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetShadowBans 获取有效的静默封禁列表
+func (c *AdminController) GetShadowBans(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := c.DB.Model(&models.ShadowBan{}).Where("expires_at IS NULL OR expires_at > ?", time.Now())
+
+	var total int64
+	query.Count(&total)
+
+	bans := []models.ShadowBan{}
+	query.Preload("User").Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&bans)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"bans":     bans,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// ShadowBanUser 静默封禁用户
+// 封禁期间用户可以正常发帖，但内容只有自己和管理员能看到；include_existing为true时同时隐藏已发布的内容
+func (c *AdminController) ShadowBanUser(ctx *gin.Context) {
+	admin, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := c.DB.First(&user, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.Role == "admin" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不能封禁管理员"})
+		return
+	}
+
+	var input struct {
+		Reason          string `json:"reason" binding:"max=255"`
+		Days            int    `json:"days" binding:"min=0,max=3650"` // 0表示永久
+		IncludeExisting bool   `json:"include_existing"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ban := models.ShadowBan{
+		UserID:    user.ID,
+		Reason:    input.Reason,
+		CreatedBy: admin.ID,
+		CreatedAt: time.Now(),
+	}
+	if input.Days > 0 {
+		expiresAt := ban.CreatedAt.AddDate(0, 0, input.Days)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	// 已有封禁记录时覆盖
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "created_by", "expires_at", "created_at"}),
+		}).Create(&ban).Error; err != nil {
+			return err
+		}
+		if input.IncludeExisting {
+			return setUserContentShadowed(tx, user.ID, true)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "封禁失败"})
+		return
+	}
+
+	ban = models.ShadowBan{}
+	c.DB.Preload("User").Where("user_id = ?", user.ID).First(&ban)
+	ctx.JSON(http.StatusOK, ban)
+}
+
+// LiftShadowBan 解除静默封禁，restore为true时恢复显示该用户被隐藏的全部内容
+func (c *AdminController) LiftShadowBan(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.ShadowBan{}).Error; err != nil {
+			return err
+		}
+		if ctx.Query("restore") == "true" {
+			return setUserContentShadowed(tx, uint(userID), false)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "解除封禁失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已解除静默封禁"})
+}
+
+// GetUserSpamStatus 查看用户的反垃圾状态：信誉等级、违规次数、冷却和静默封禁
+func (c *AdminController) GetUserSpamStatus(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	var user models.User
+	if err := c.DB.First(&user, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	strikes, cooldowns := c.Spam.Strikes(ctx.Request.Context(), user.ID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"user_id":           user.ID,
+		"tier":              c.Spam.Tier(user.CreatedAt, user.Points),
+		"strikes":           strikes,
+		"cooldowns":         cooldowns,
+		"cooldown_seconds":  int(c.Spam.Cooldown(ctx.Request.Context(), user.ID).Seconds()),
+		"shadow_ban":        activeShadowBan(c.DB, user.ID),
+		"shadowed_topics":   countShadowed(c.DB, &models.Topic{}, user.ID),
+		"shadowed_replies":  countShadowed(c.DB, &models.Reply{}, user.ID),
+		"shadowed_comments": countShadowed(c.DB, &models.Comment{}, user.ID),
+	})
+}
+
+// countShadowed 用户被隐藏的内容数
+func countShadowed(db *gorm.DB, model interface{}, userID uint) int64 {
+	var count int64
+	db.Model(model).Where("user_id = ? AND shadowed = ?", userID, true).Count(&count)
+	return count
+}
+
+// ResetUserSpam 清除用户的违规记录并解除冷却
+func (c *AdminController) ResetUserSpam(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if err := c.Spam.Reset(ctx.Request.Context(), uint(userID)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "解除冷却失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已解除发帖冷却"})
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// normalizeTagName 整理单个标签名，不合法时返回错误信息
+func normalizeTagName(name string) (string, string) {
+	names, msg := normalizeTagNames([]string{name})
+	if msg != "" {
+		return "", msg
+	}
+	if len(names) == 0 {
+		return "", "标签名不能为空"
+	}
+	return names[0], ""
+}
+
+// CreateTag 创建标签，保留标签只有管理员能添加到主题和资源上
+func (c *AdminController) CreateTag(ctx *gin.Context) {
+	admin, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description" binding:"max=255"`
+		Reserved    bool   `json:"reserved"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, msg := normalizeTagName(input.Name)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var count int64
+	c.DB.Model(&models.Tag{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "标签已存在"})
+		return
+	}
+
+	tag := models.Tag{
+		Name:        name,
+		Description: input.Description,
+		Reserved:    input.Reserved,
+		CreatedBy:   admin.ID,
+	}
+	if err := c.DB.Create(&tag).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建标签失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag 修改标签的名称、描述或是否为保留标签，不传的字段保持不变
+func (c *AdminController) UpdateTag(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	var tag models.Tag
+	if err := c.DB.First(&tag, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description" binding:"omitempty,max=255"`
+		Reserved    *bool   `json:"reserved"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		name, msg := normalizeTagName(*input.Name)
+		if msg != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		var count int64
+		c.DB.Model(&models.Tag{}).Where("name = ? AND id <> ?", name, tag.ID).Count(&count)
+		if count > 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "标签已存在，可以合并到该标签"})
+			return
+		}
+		updates["name"] = name
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.Reserved != nil {
+		updates["reserved"] = *input.Reserved
+	}
+
+	if len(updates) > 0 {
+		if err := c.DB.Model(&tag).Updates(updates).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "修改标签失败"})
+			return
+		}
+	}
+
+	c.DB.First(&tag, tag.ID)
+	ctx.JSON(http.StatusOK, tag)
+}
+
+// MergeTag 将标签合并到另一个标签，原标签的主题和资源改为使用目标标签，原标签被删除
+func (c *AdminController) MergeTag(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	var tag models.Tag
+	if err := c.DB.First(&tag, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	var input struct {
+		TargetID uint `json:"target_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target models.Tag
+	if err := c.DB.First(&target, input.TargetID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "目标标签不存在"})
+		return
+	}
+	if target.ID == tag.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不能合并到自身"})
+		return
+	}
+
+	// 已经同时带有两个标签的内容忽略重复的关联
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT IGNORE INTO topic_tags (topic_id, tag_id) SELECT topic_id, ? FROM topic_tags WHERE tag_id = ?",
+			target.ID, tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT IGNORE INTO resource_tags (resource_id, tag_id) SELECT resource_id, ? FROM resource_tags WHERE tag_id = ?",
+			target.ID, tag.ID).Error; err != nil {
+			return err
+		}
+		return deleteTag(tx, tag.ID)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "合并标签失败"})
+		return
+	}
+
+	tags := []models.Tag{target}
+	fillTagCounts(c.DB, tags)
+	ctx.JSON(http.StatusOK, tags[0])
+}
+
+// deleteTag 删除标签及其与主题和资源的关联
+func deleteTag(tx *gorm.DB, tagID uint) error {
+	if err := tx.Exec("DELETE FROM topic_tags WHERE tag_id = ?", tagID).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM resource_tags WHERE tag_id = ?", tagID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Tag{}, tagID).Error
+}
+
+// DeleteTag 删除标签，带有该标签的主题和资源不受影响
+func (c *AdminController) DeleteTag(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	var tag models.Tag
+	if err := c.DB.First(&tag, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	if err := c.DB.Transaction(func(tx *gorm.DB) error { return deleteTag(tx, tag.ID) }); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "标签已删除"})
+}
@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// maxSensitiveWordsPerRequest 一次最多添加的敏感词数
+const maxSensitiveWordsPerRequest = 500
+
+var errModerationReviewed = errors.New("该记录已处理")
+
+// requireAdmin 校验管理员权限，失败时直接写入错误响应
+func (c *AdminController) requireAdmin(ctx *gin.Context) (*models.User, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return nil, false
+	}
+
+	var admin models.User
+	c.DB.First(&admin, userID)
+	if admin.Role != "admin" {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+		return nil, false
+	}
+	return &admin, true
+}
+
+// GetSensitiveWords 获取敏感词列表，可按处理方式、分类和关键字筛选
+func (c *AdminController) GetSensitiveWords(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	query := c.DB.Model(&models.SensitiveWord{})
+	if action := ctx.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if category := ctx.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if search := ctx.Query("search"); search != "" {
+		query = query.Where("word LIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	words := []models.SensitiveWord{}
+	query.Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&words)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"words":    words,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// AddSensitiveWords 批量添加敏感词，已存在的词更新处理方式和分类
+func (c *AdminController) AddSensitiveWords(ctx *gin.Context) {
+	admin, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Words    []string `json:"words" binding:"required,min=1,dive,max=100"`
+		Action   string   `json:"action" binding:"required,oneof=block review mask"`
+		Category string   `json:"category" binding:"max=50"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.Words) > maxSensitiveWordsPerRequest {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("一次最多添加%d个敏感词", maxSensitiveWordsPerRequest)})
+		return
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var words []models.SensitiveWord
+	for _, word := range input.Words {
+		word = strings.TrimSpace(word)
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, models.SensitiveWord{
+			Word:      word,
+			Action:    input.Action,
+			Category:  input.Category,
+			CreatedBy: admin.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	if len(words) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "敏感词不能为空"})
+		return
+	}
+
+	if err := c.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"action", "category", "updated_at"}),
+	}).Create(&words).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "添加敏感词失败"})
+		return
+	}
+	c.Filter.Reload()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("已添加%d个敏感词", len(words)),
+		"count":   len(words),
+	})
+}
+
+// UpdateSensitiveWord 修改敏感词的处理方式或分类
+func (c *AdminController) UpdateSensitiveWord(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	var word models.SensitiveWord
+	if err := c.DB.First(&word, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "敏感词不存在"})
+		return
+	}
+
+	var input struct {
+		Action   string  `json:"action" binding:"omitempty,oneof=block review mask"`
+		Category *string `json:"category" binding:"omitempty,max=50"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if input.Action != "" {
+		updates["action"] = input.Action
+	}
+	if input.Category != nil {
+		updates["category"] = *input.Category
+	}
+
+	if err := c.DB.Model(&word).Updates(updates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新敏感词失败"})
+		return
+	}
+	c.Filter.Reload()
+
+	c.DB.First(&word, word.ID)
+	ctx.JSON(http.StatusOK, word)
+}
+
+// DeleteSensitiveWord 删除敏感词
+func (c *AdminController) DeleteSensitiveWord(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	result := c.DB.Delete(&models.SensitiveWord{}, ctx.Param("id"))
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除敏感词失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "敏感词不存在"})
+		return
+	}
+	c.Filter.Reload()
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "敏感词已删除"})
+}
+
+// GetModerationLogs 获取敏感词命中记录，status=pending即为待人工审核的队列
+func (c *AdminController) GetModerationLogs(ctx *gin.Context) {
+	if _, ok := c.requireAdmin(ctx); !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := c.DB.Model(&models.ModerationLog{})
+	if action := ctx.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if source := ctx.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID := ctx.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	logs := []models.ModerationLog{}
+	query.Preload("User").Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&logs)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"logs":     logs,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// ReviewModerationLog 处理审核队列中的一项，同一内容的其他待审核记录一并处理
+// 通过时恢复因待审核而隐藏的主题；不通过时隐藏主题、删除回复或评论、驳回资源，并通知作者
+func (c *AdminController) ReviewModerationLog(ctx *gin.Context) {
+	admin, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var entry models.ModerationLog
+	if err := c.DB.First(&entry, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "记录不存在"})
+		return
+	}
+
+	var input struct {
+		Approved bool   `json:"approved"`
+		Note     string `json:"note" binding:"max=255"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := models.ModerationStatusRejected
+	if input.Approved {
+		status = models.ModerationStatusApproved
+	}
+
+	var notification *models.Notification
+	var publish func()
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		// 只处理仍在队列中的记录，并发审核时只有一个生效
+		result := tx.Model(&models.ModerationLog{}).
+			Where("id = ? AND status = ?", entry.ID, models.ModerationStatusPending).
+			Updates(moderationReview(admin.ID, status, input.Note))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errModerationReviewed
+		}
+		if err := resolveModerationLogs(tx, entry.Source, entry.SourceID, admin.ID, status, input.Note); err != nil {
+			return err
+		}
+
+		var err error
+		notification, publish, err = c.applyModeration(tx, &entry, input.Approved, input.Note)
+		return err
+	})
+	if errors.Is(err, errModerationReviewed) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "该记录已处理"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "审核失败"})
+		return
+	}
+
+	if notification != nil {
+		c.Notifier.Notify(notification)
+	}
+	if publish != nil {
+		publish()
+	}
+
+	c.DB.Preload("User").First(&entry, entry.ID)
+	ctx.JSON(http.StatusOK, entry)
+}
+
+// applyModeration 按审核结果处理内容，返回需要发送给作者的通知和事务提交后的发布操作，内容已不存在时不处理
+// 待审核的回复和评论审核通过后才发送通知、推送和发放积分，不通过时按作者删除的方式删除
+func (c *AdminController) applyModeration(tx *gorm.DB, entry *models.ModerationLog, approved bool, note string) (*models.Notification, func(), error) {
+	reason := "内容违规"
+	if note != "" {
+		reason = note
+	}
+
+	switch entry.Source {
+	case models.ModerationSourceTopic:
+		var topic models.Topic
+		if err := tx.First(&topic, entry.SourceID).Error; err != nil {
+			return nil, nil, nil
+		}
+		if approved {
+			if !topic.IsHidden || topic.HiddenReason != moderationHiddenReason {
+				return nil, nil, nil
+			}
+			if err := tx.Model(&topic).Updates(map[string]interface{}{
+				"is_hidden": false, "hidden_reason": "", "hidden_at": nil,
+			}).Error; err != nil {
+				return nil, nil, err
+			}
+			return moderationNotification(&topic, fmt.Sprintf("你的主题《%s》已通过审核", topic.Title), ""), nil, nil
+		}
+		if err := tx.Model(&topic).Updates(map[string]interface{}{
+			"is_hidden": true, "hidden_reason": reason, "hidden_at": time.Now(),
+		}).Error; err != nil {
+			return nil, nil, err
+		}
+		return moderationNotification(&topic, fmt.Sprintf("你的主题《%s》未通过审核，已被隐藏", topic.Title), reason), nil, nil
+
+	case models.ModerationSourceReply:
+		var reply models.Reply
+		if err := tx.First(&reply, entry.SourceID).Error; err != nil || reply.IsDeleted {
+			return nil, nil, nil
+		}
+		if approved {
+			if !reply.Pending {
+				return nil, nil, nil
+			}
+			if err := approveReply(tx, &reply); err != nil {
+				return nil, nil, err
+			}
+			return nil, func() { c.Forum.publishApprovedReply(reply.ID) }, nil
+		}
+
+		// 未通过审核的回复按作者删除处理：扣回积分，更新回复数，通知浏览主题的用户
+		if err := removeReply(tx, &reply, "回复未通过审核扣除积分"); err != nil {
+			return nil, nil, err
+		}
+		publish := func() {
+			removeReplyAttachments(c.DB, c.Files, reply.ID)
+			publishReplyDeleted(c.Forum.Hub, &reply, reply.ChildCount > 0)
+		}
+		return &models.Notification{
+			UserID:     reply.UserID,
+			Type:       models.NotificationModeration,
+			Title:      "你的回复未通过审核，已被删除",
+			Content:    reason,
+			TargetType: models.NotificationTargetTopic,
+			TargetID:   reply.TopicID,
+			TopicID:    &reply.TopicID,
+		}, publish, nil
+
+	case models.ModerationSourceComment:
+		var comment models.Comment
+		if err := tx.First(&comment, entry.SourceID).Error; err != nil {
+			return nil, nil, nil
+		}
+		if approved {
+			if !comment.Pending {
+				return nil, nil, nil
+			}
+			if err := tx.Model(&comment).UpdateColumn("pending", false).Error; err != nil {
+				return nil, nil, err
+			}
+			comment.Pending = false
+			return nil, func() {
+				var resource models.Resource
+				if err := c.DB.Select("id", "title").First(&resource, comment.ResourceID).Error; err == nil {
+					notifyCommentMentions(c.DB, c.Notifier, &comment, &resource, utils.ResolveMentions(c.DB, comment.Content))
+				}
+			}, nil
+		}
+		if err := tx.Delete(&comment).Error; err != nil {
+			return nil, nil, err
+		}
+		resourceID, _ := strconv.ParseUint(comment.ResourceID, 10, 64)
+		return &models.Notification{
+			UserID:     comment.UserID,
+			Type:       models.NotificationModeration,
+			Title:      "你的评论未通过审核，已被删除",
+			Content:    reason,
+			TargetType: models.NotificationTargetResource,
+			TargetID:   uint(resourceID),
+		}, nil, nil
+
+	case models.ModerationSourceResource:
+		// 资源的发布由资源审核决定，这里只在不通过时驳回
+		if approved {
+			return nil, nil, nil
+		}
+		var resource models.Resource
+		if err := tx.First(&resource, entry.SourceID).Error; err != nil {
+			return nil, nil, nil
+		}
+		if err := tx.Model(&resource).Updates(map[string]interface{}{
+			"status": "rejected", "updated_at": time.Now(),
+		}).Error; err != nil {
+			return nil, nil, err
+		}
+		return &models.Notification{
+			UserID:     resource.UserID,
+			Type:       models.NotificationReview,
+			Title:      fmt.Sprintf("你的资源《%s》未通过审核", resource.Title),
+			Content:    reason,
+			TargetType: models.NotificationTargetResource,
+			TargetID:   resource.ID,
+		}, nil, nil
+	}
+	return nil, nil, nil
+}
+
+// moderationNotification 主题审核结果的通知
+func moderationNotification(topic *models.Topic, title, content string) *models.Notification {
+	return &models.Notification{
+		UserID:     topic.UserID,
+		Type:       models.NotificationModeration,
+		Title:      title,
+		Content:    content,
+		TargetType: models.NotificationTargetTopic,
+		TargetID:   topic.ID,
+		TopicID:    &topic.ID,
+	}
+}
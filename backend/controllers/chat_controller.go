@@ -15,19 +15,22 @@ import (
 
 	"g/front/backend/config"
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
 // ChatController AI聊天控制器
 type ChatController struct {
 	DB       *gorm.DB
 	AIConfig config.AIConfig
+	Hub      *utils.RealtimeHub
 }
 
 // NewChatController 创建聊天控制器实例
-func NewChatController(db *gorm.DB) *ChatController {
+func NewChatController(db *gorm.DB, hub *utils.RealtimeHub) *ChatController {
 	return &ChatController{
 		DB:       db,
 		AIConfig: config.GetAIConfig(),
+		Hub:      hub,
 	}
 }
 
@@ -134,13 +137,43 @@ func (c *ChatController) GetSessionMessages(ctx *gin.Context) {
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "50"))
 
+	// 游标分页
+	keys := keyset{{Expr: "created_at", Time: true}, {Expr: "id"}}
+	after, cursorSize, useCursor, err := cursorParams(ctx, keys, 50)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if useCursor {
+		var messages []models.ChatMessage
+		keys.after(c.DB.Where("session_id = ?", sessionID), after).
+			Order(keys.order()).
+			Limit(cursorSize + 1).
+			Find(&messages)
+
+		nextCursor := ""
+		hasMore := len(messages) > cursorSize
+		if hasMore {
+			messages = messages[:cursorSize]
+			last := &messages[cursorSize-1]
+			nextCursor = keys.encode(last.CreatedAt, last.ID)
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"messages":    messages,
+			"next_cursor": nextCursor,
+			"has_more":    hasMore,
+			"pageSize":    cursorSize,
+		})
+		return
+	}
+
 	// 查询会话消息
 	var messages []models.ChatMessage
 	var total int64
 
 	c.DB.Model(&models.ChatMessage{}).Where("session_id = ?", sessionID).Count(&total)
 	c.DB.Where("session_id = ?", sessionID).
-		Order("created_at ASC").
+		Order(keys.order()).
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
 		Find(&messages)
@@ -234,6 +267,9 @@ func (c *ChatController) SendMessage(ctx *gin.Context) {
 
 	c.DB.Create(&userMessage)
 
+	// 同一会话在其他页面打开时也能看到新消息
+	c.Hub.Publish(utils.ChatChannel(session.ID), utils.EventChatMessage, userMessage)
+
 	// 获取会话历史消息（最近10条）
 	var history []models.ChatMessage
 	c.DB.Where("session_id = ?", input.SessionID).
@@ -268,6 +304,7 @@ func (c *ChatController) SendMessage(ctx *gin.Context) {
 	}
 
 	c.DB.Create(&aiMessage)
+	c.Hub.Publish(utils.ChatChannel(session.ID), utils.EventChatMessage, aiMessage)
 
 	// 更新会话最后更新时间
 	c.DB.Model(&session).Update("updated_at", time.Now())
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+const (
+	// moderationHiddenReason 命中审核词的主题在审核通过前隐藏，隐藏原因为该文字
+	moderationHiddenReason = "内容待审核"
+	// maxModerationExcerpt 命中记录中保存的原文最多字符数
+	maxModerationExcerpt = 500
+)
+
+// textField 需要检查敏感词的字段，Value指向的文本中的屏蔽词会被直接替换
+type textField struct {
+	Name  string
+	Value *string
+}
+
+// moderationCheck 一次提交的敏感词检查结果
+type moderationCheck struct {
+	Review bool // 命中审核词，内容发布后进入审核队列
+	logs   []models.ModerationLog
+}
+
+// truncateRunes 截取前n个字符
+func truncateRunes(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n])
+}
+
+// moderationEntry 生成一条命中记录
+func moderationEntry(userID uint, source, field, original string, result utils.FilterResult) models.ModerationLog {
+	return models.ModerationLog{
+		UserID:  userID,
+		Source:  source,
+		Field:   field,
+		Action:  result.Action,
+		Words:   truncateRunes(strings.Join(result.Words, ","), 100),
+		Excerpt: truncateRunes(original, maxModerationExcerpt),
+	}
+}
+
+// checkContent 检查提交的各个字段，命中屏蔽词的字段被替换
+// 命中禁止词时记录命中并返回命中的禁止词，调用方拒绝提交；命中审核词时Review为true，
+// 由调用方决定内容发布后的状态。内容保存后调用record记录其他命中
+func checkContent(db *gorm.DB, filter *utils.ContentFilter, userID uint, source string, fields ...textField) (*moderationCheck, []string) {
+	check := &moderationCheck{}
+	var blocked []string
+	for _, field := range fields {
+		original := *field.Value
+		result := filter.Check(original)
+		if result.Action == "" {
+			continue
+		}
+
+		check.logs = append(check.logs, moderationEntry(userID, source, field.Name, original, result))
+		switch result.Action {
+		case models.SensitiveActionBlock:
+			blocked = append(blocked, result.Words...)
+		case models.SensitiveActionReview:
+			check.Review = true
+		}
+		*field.Value = result.Text
+	}
+
+	if len(blocked) > 0 {
+		// 被拒绝的提交没有内容ID，只保存命中禁止词的字段
+		var logs []models.ModerationLog
+		for _, entry := range check.logs {
+			if entry.Action == models.SensitiveActionBlock {
+				logs = append(logs, entry)
+			}
+		}
+		db.Create(&logs)
+	}
+	return check, blocked
+}
+
+// blockedMessage 拒绝提交时的错误信息
+func blockedMessage(blocked []string) string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range blocked {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return "内容包含违禁词：" + strings.Join(words, "、")
+}
+
+// filterContent 检查提交的各个字段，命中禁止词时直接写入400响应并返回false
+func filterContent(ctx *gin.Context, db *gorm.DB, filter *utils.ContentFilter, userID uint, source string, fields ...textField) (*moderationCheck, bool) {
+	check, blocked := checkContent(db, filter, userID, source, fields...)
+	if len(blocked) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": blockedMessage(blocked)})
+		return nil, false
+	}
+	return check, true
+}
+
+// record 内容保存后记录命中的敏感词，命中审核词的记录进入审核队列
+func (m *moderationCheck) record(db *gorm.DB, sourceID uint) {
+	if len(m.logs) == 0 {
+		return
+	}
+	for i := range m.logs {
+		m.logs[i].SourceID = sourceID
+		if m.logs[i].Action == models.SensitiveActionReview {
+			m.logs[i].Status = models.ModerationStatusPending
+		}
+	}
+	db.Create(&m.logs)
+}
+
+// moderationReview 审核队列中的记录被处理后需要更新的字段
+func moderationReview(reviewerID uint, status, note string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":      status,
+		"reviewer_id": reviewerID,
+		"review_note": truncateRunes(note, 255),
+		"reviewed_at": time.Now(),
+	}
+}
+
+// resolveModerationLogs 处理某个内容在审核队列中的全部记录，管理员审核资源等操作时调用
+func resolveModerationLogs(db *gorm.DB, source string, sourceID, reviewerID uint, status, note string) error {
+	return db.Model(&models.ModerationLog{}).
+		Where("source = ? AND source_id = ? AND status = ?", source, sourceID, models.ModerationStatusPending).
+		Updates(moderationReview(reviewerID, status, note)).Error
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// voteInput 投票请求，value为1赞同、-1反对、0取消投票
+type voteInput struct {
+	Value int `json:"value" binding:"oneof=-1 0 1"`
+}
+
+// castContentVote 记录用户对回复或评论的投票并更新内容上的投票计数
+// target为*models.Reply或*models.Comment，投票与原来相同时不做修改
+func castContentVote(db *gorm.DB, target interface{}, targetType string, targetID, userID uint, value int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 锁定被投票的内容，同一内容上的投票依次处理，避免计数错误
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(target, targetID).Error; err != nil {
+			return err
+		}
+
+		var vote models.ContentVote
+		found := tx.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+			Limit(1).Find(&vote).RowsAffected > 0
+
+		previous := 0
+		if found {
+			previous = vote.Value
+		}
+		if previous == value {
+			return nil
+		}
+
+		var err error
+		switch {
+		case value == 0:
+			err = tx.Delete(&vote).Error
+		case found:
+			err = tx.Model(&vote).Update("value", value).Error
+		default:
+			err = tx.Create(&models.ContentVote{
+				UserID:     userID,
+				TargetType: targetType,
+				TargetID:   targetID,
+				Value:      value,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// 撤回原来的投票，计入新的投票
+		up, down := 0, 0
+		switch previous {
+		case 1:
+			up--
+		case -1:
+			down--
+		}
+		switch value {
+		case 1:
+			up++
+		case -1:
+			down++
+		}
+
+		// 投票不算编辑，不更新updated_at
+		return tx.Model(target).UpdateColumns(map[string]interface{}{
+			"upvote_count":   gorm.Expr("upvote_count + ?", up),
+			"downvote_count": gorm.Expr("downvote_count + ?", down),
+			"vote_score":     gorm.Expr("vote_score + ?", value-previous),
+		}).Error
+	})
+}
+
+// userVotes 查询用户对一组内容的投票，返回内容ID到投票值的映射
+func userVotes(db *gorm.DB, userID uint, targetType string, targetIDs []uint) map[uint]int {
+	votes := make(map[uint]int)
+	if userID == 0 || len(targetIDs) == 0 {
+		return votes
+	}
+
+	var records []models.ContentVote
+	db.Where("user_id = ? AND target_type = ? AND target_id IN ?", userID, targetType, targetIDs).Find(&records)
+	for _, record := range records {
+		votes[record.TargetID] = record.Value
+	}
+	return votes
+}
+
+// fillReplyVotes 填充当前用户对回复及其展开的子回复的投票
+func fillReplyVotes(db *gorm.DB, userID uint, replies []models.Reply) {
+	if userID == 0 {
+		return
+	}
+
+	var all []*models.Reply
+	var collect func(list []models.Reply)
+	collect = func(list []models.Reply) {
+		for i := range list {
+			all = append(all, &list[i])
+			collect(list[i].Children)
+		}
+	}
+	collect(replies)
+
+	ids := make([]uint, 0, len(all))
+	for _, reply := range all {
+		ids = append(ids, reply.ID)
+	}
+
+	votes := userVotes(db, userID, models.VoteTargetReply, ids)
+	for _, reply := range all {
+		reply.MyVote = votes[reply.ID]
+	}
+}
+
+// fillCommentVotes 填充当前用户对评论的投票
+func fillCommentVotes(db *gorm.DB, userID uint, comments []models.Comment) {
+	if userID == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+
+	votes := userVotes(db, userID, models.VoteTargetComment, ids)
+	for i := range comments {
+		comments[i].MyVote = votes[comments[i].ID]
+	}
+}
+
+// replyKeyset 回复的排序方式：best 采纳的答案在前，其余按得分从高到低；默认按时间顺序
+func replyKeyset(sort string) keyset {
+	if sort == "best" {
+		return keyset{
+			{Expr: "is_accepted", Desc: true},
+			{Expr: "vote_score", Desc: true},
+			{Expr: "created_at", Time: true},
+			{Expr: "id"},
+		}
+	}
+	return keyset{{Expr: "created_at", Time: true}, {Expr: "id"}}
+}
+
+// replyKeyValues 回复在排序方式下的排序键
+func replyKeyValues(sort string, reply *models.Reply) []interface{} {
+	if sort == "best" {
+		return []interface{}{boolKey(reply.IsAccepted), reply.VoteScore, reply.CreatedAt, reply.ID}
+	}
+	return []interface{}{reply.CreatedAt, reply.ID}
+}
+
+// replyOrder 回复排序的ORDER BY子句
+func replyOrder(sort string) string {
+	return replyKeyset(sort).order()
+}
+
+// findRepliesAfter 按游标读取一页回复，返回下一页的游标，没有更多回复时为空
+func findRepliesAfter(query *gorm.DB, sort string, after []interface{}, pageSize int) ([]models.Reply, string) {
+	keys := replyKeyset(sort)
+	var replies []models.Reply
+	preloadReplyUsers(keys.after(query, after)).
+		Order(keys.order()).
+		Limit(pageSize + 1).
+		Find(&replies)
+
+	if len(replies) <= pageSize {
+		return replies, ""
+	}
+	replies = replies[:pageSize]
+	return replies, keys.encode(replyKeyValues(sort, &replies[pageSize-1])...)
+}
+
+// VoteReply 赞同或反对回复，不能给自己的回复投票
+func (c *ForumController) VoteReply(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input voteInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 静默、待审核的回复和看不到的主题中的回复按不存在处理
+	viewer := getResourceViewer(ctx, c.DB)
+	var reply models.Reply
+	if err := visiblePublished(c.DB, "replies", viewer).First(&reply, ctx.Param("id")).Error; err != nil || reply.IsDeleted {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+		return
+	}
+	var topic models.Topic
+	if err := c.DB.First(&topic, reply.TopicID).Error; err != nil || !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+		return
+	}
+
+	if reply.UserID == userID.(uint) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不能给自己的回复投票"})
+		return
+	}
+
+	if err := castContentVote(c.DB, &reply, models.VoteTargetReply, reply.ID, userID.(uint), input.Value); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "投票失败"})
+		return
+	}
+
+	c.DB.First(&reply, reply.ID)
+	result := gin.H{
+		"id":             reply.ID,
+		"upvote_count":   reply.UpvoteCount,
+		"downvote_count": reply.DownvoteCount,
+		"vote_score":     reply.VoteScore,
+	}
+	c.Hub.Publish(utils.TopicChannel(reply.TopicID), utils.EventReplyVotes, result)
+
+	result["my_vote"] = input.Value
+	ctx.JSON(http.StatusOK, result)
+}
+
+// VoteComment 赞同或反对资源评论，不能给自己的评论投票
+func (c *ResourceController) VoteComment(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input voteInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 静默和待审核的评论按不存在处理
+	viewer := getResourceViewer(ctx, c.DB)
+	var comment models.Comment
+	if err := visiblePublished(c.DB, "comments", viewer).First(&comment, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return
+	}
+
+	// 不可见资源的评论同样不能投票
+	var resource models.Resource
+	if err := c.DB.First(&resource, comment.ResourceID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+	if !checkResourceAccess(ctx, c.DB, &resource, viewer) {
+		return
+	}
+
+	if comment.UserID == userID.(uint) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不能给自己的评论投票"})
+		return
+	}
+
+	if err := castContentVote(c.DB, &comment, models.VoteTargetComment, comment.ID, userID.(uint), input.Value); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "投票失败"})
+		return
+	}
+
+	c.DB.First(&comment, comment.ID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"id":             comment.ID,
+		"upvote_count":   comment.UpvoteCount,
+		"downvote_count": comment.DownvoteCount,
+		"vote_score":     comment.VoteScore,
+		"my_vote":        input.Value,
+	})
+}
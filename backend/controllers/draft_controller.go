@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/config"
+	"g/front/backend/models"
+)
+
+const (
+	// maxDraftsPerUser 每个用户最多保存的草稿数
+	maxDraftsPerUser = 50
+	// maxDraftDataSize 草稿中其他表单字段的最大字节数
+	maxDraftDataSize = 20000
+	// draftCleanupInterval 清理过期草稿的间隔
+	draftCleanupInterval = time.Hour
+)
+
+// DraftController 草稿控制器
+type DraftController struct {
+	DB       *gorm.DB
+	TTL      time.Duration // 草稿最后一次保存后的有效期
+	stopChan chan struct{} // 用于停止定时任务的通道
+}
+
+// NewDraftController 创建草稿控制器实例
+// 草稿有效期通过环境变量DRAFT_EXPIRE_DAYS配置，默认30天
+func NewDraftController(db *gorm.DB) *DraftController {
+	days, err := strconv.Atoi(config.GetEnv("DRAFT_EXPIRE_DAYS", "30"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+
+	dc := &DraftController{
+		DB:       db,
+		TTL:      time.Duration(days) * 24 * time.Hour,
+		stopChan: make(chan struct{}),
+	}
+	go dc.cleanupDrafts() // 启动过期草稿清理任务
+	return dc
+}
+
+// discardDraft 删除用户在某个编辑场景下的草稿，发布主题、回复或资源后调用
+// 草稿中仍未被发布内容引用的附件恢复为未使用状态，由附件清理任务处理
+func discardDraft(db *gorm.DB, userID uint, contextType string, contextID uint) {
+	var draft models.Draft
+	if db.Where("user_id = ? AND context_type = ? AND context_id = ?", userID, contextType, contextID).
+		Limit(1).
+		Find(&draft).RowsAffected == 0 {
+		return
+	}
+	removeDrafts(db, []uint{draft.ID})
+}
+
+// removeDrafts 删除草稿并释放草稿中的附件
+func removeDrafts(db *gorm.DB, ids []uint) {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Attachment{}).
+			Where("target_type = ? AND target_id IN ?", models.AttachmentTargetDraft, ids).
+			Updates(map[string]interface{}{"target_type": "", "target_id": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Draft{}, ids).Error
+	}); err != nil {
+		log.Printf("删除草稿失败: %v", err)
+	}
+}
+
+// validateDraftContext 检查草稿的编辑场景是否有效，返回错误信息
+func validateDraftContext(db *gorm.DB, userID uint, contextType string, contextID uint) string {
+	switch contextType {
+	case models.DraftContextTopic:
+		if contextID != 0 {
+			return "新主题的草稿不需要context_id"
+		}
+	case models.DraftContextReply:
+		var topic models.Topic
+		if err := db.Select("id").First(&topic, contextID).Error; err != nil {
+			return "主题不存在"
+		}
+	case models.DraftContextResource:
+		if contextID != 0 {
+			var resource models.Resource
+			if err := db.Select("id", "user_id").First(&resource, contextID).Error; err != nil {
+				return "资源不存在"
+			}
+			if resource.UserID != userID {
+				return "无权修改此资源"
+			}
+		}
+	default:
+		return "草稿类型不正确"
+	}
+	return ""
+}
+
+// SaveDraft 自动保存草稿，同一编辑场景下已有草稿时覆盖
+func (c *DraftController) SaveDraft(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input struct {
+		ContextType string          `json:"context_type" binding:"required"`
+		ContextID   uint            `json:"context_id"`
+		Title       string          `json:"title" binding:"max=200"`
+		Content     string          `json:"content" binding:"max=50000"`
+		Data        json.RawMessage `json:"data"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(input.Data) > maxDraftDataSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "草稿数据过大"})
+		return
+	}
+	if msg := validateDraftContext(c.DB, userID.(uint), input.ContextType, input.ContextID); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	now := time.Now()
+	scope := c.DB.Where("user_id = ? AND context_type = ? AND context_id = ?", userID, input.ContextType, input.ContextID).
+		Session(&gorm.Session{})
+
+	// 新的编辑场景才占用草稿数量
+	var existing int64
+	scope.Model(&models.Draft{}).Where("expires_at > ?", now).Count(&existing)
+	if existing == 0 {
+		var count int64
+		c.DB.Model(&models.Draft{}).Where("user_id = ? AND expires_at > ?", userID, now).Count(&count)
+		if count >= maxDraftsPerUser {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "草稿数量已达上限，请先删除不需要的草稿"})
+			return
+		}
+	}
+
+	draft := models.Draft{
+		UserID:      userID.(uint),
+		ContextType: input.ContextType,
+		ContextID:   input.ContextID,
+		Title:       input.Title,
+		Content:     input.Content,
+		Data:        input.Data,
+		ExpiresAt:   now.Add(c.TTL),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	// 多个标签页同时保存时以最后一次为准；过期未清理的草稿视为重新创建
+	// created_at需要在expires_at之前赋值，才能读取到原来的过期时间
+	if err := c.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "created_at"}, Value: gorm.Expr("IF(expires_at > ?, created_at, ?)", now, now)},
+			{Column: clause.Column{Name: "title"}, Value: draft.Title},
+			{Column: clause.Column{Name: "content"}, Value: draft.Content},
+			{Column: clause.Column{Name: "data"}, Value: draft.Data},
+			{Column: clause.Column{Name: "expires_at"}, Value: draft.ExpiresAt},
+			{Column: clause.Column{Name: "updated_at"}, Value: now},
+		},
+	}).Create(&draft).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return
+	}
+
+	// 覆盖已有草稿时Create不会返回原来的ID，重新查询
+	draft = models.Draft{}
+	if err := scope.First(&draft).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return
+	}
+	bindAttachments(c.DB, draft.UserID, models.AttachmentTargetDraft, draft.ID, draft.Content)
+	ctx.JSON(http.StatusOK, draft)
+}
+
+// GetDrafts 获取当前用户未过期的草稿列表，不含正文
+// 可按context_type和context_id筛选，用于打开编辑器时查找可以恢复的草稿
+func (c *DraftController) GetDrafts(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query := c.DB.Where("user_id = ? AND expires_at > ?", userID, time.Now())
+	if contextType := ctx.Query("context_type"); contextType != "" {
+		query = query.Where("context_type = ?", contextType)
+	}
+	if contextID := ctx.Query("context_id"); contextID != "" {
+		query = query.Where("context_id = ?", contextID)
+	}
+
+	drafts := []models.Draft{}
+	query.Omit("content", "data").Order("updated_at DESC").Find(&drafts)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"drafts": drafts,
+		"total":  len(drafts),
+	})
+}
+
+// GetDraft 获取草稿的完整内容，用于恢复编辑
+func (c *DraftController) GetDraft(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var draft models.Draft
+	if err := c.DB.Where("id = ? AND user_id = ? AND expires_at > ?", ctx.Param("id"), userID, time.Now()).
+		First(&draft).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "草稿不存在"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, draft)
+}
+
+// DeleteDraft 放弃草稿
+func (c *DraftController) DeleteDraft(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var draft models.Draft
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&draft).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "草稿不存在"})
+		return
+	}
+
+	removeDrafts(c.DB, []uint{draft.ID})
+	ctx.JSON(http.StatusOK, gin.H{"message": "草稿已删除"})
+}
+
+// cleanupDrafts 定时清理过期的草稿
+func (c *DraftController) cleanupDrafts() {
+	ticker := time.NewTicker(draftCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.removeExpiredDrafts()
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// removeExpiredDrafts 删除已过期的草稿，每次最多处理500份
+func (c *DraftController) removeExpiredDrafts() {
+	var ids []uint
+	c.DB.Model(&models.Draft{}).Where("expires_at <= ?", time.Now()).Limit(500).Pluck("id", &ids)
+
+	if len(ids) > 0 {
+		removeDrafts(c.DB, ids)
+		log.Printf("已清理%d份过期草稿", len(ids))
+	}
+}
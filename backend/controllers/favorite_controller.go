@@ -0,0 +1,579 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// FavoriteController 收藏夹控制器
+type FavoriteController struct {
+	DB *gorm.DB
+}
+
+// NewFavoriteController 创建收藏夹控制器实例
+func NewFavoriteController(db *gorm.DB) *FavoriteController {
+	return &FavoriteController{DB: db}
+}
+
+// GetFolders 获取当前用户的收藏夹列表
+func (c *FavoriteController) GetFolders(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	// 确保默认收藏夹存在
+	if _, err := models.GetDefaultFavoriteFolder(c.DB, userID.(uint)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏夹失败"})
+		return
+	}
+
+	var folders []models.FavoriteFolder
+	c.DB.Where("user_id = ?", userID).Order("is_default DESC, sort_order ASC, id ASC").Find(&folders)
+
+	// 统计每个收藏夹的条目数
+	var counts []struct {
+		FolderID uint
+		Count    int64
+	}
+	c.DB.Model(&models.FavoriteItem{}).
+		Select("folder_id, count(*) as count").
+		Where("user_id = ?", userID).
+		Group("folder_id").
+		Scan(&counts)
+
+	countMap := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		countMap[count.FolderID] = count.Count
+	}
+	for i := range folders {
+		folders[i].ItemCount = countMap[folders[i].ID]
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+// CreateFolder 创建收藏夹
+func (c *FavoriteController) CreateFolder(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var input struct {
+		Name        string `json:"name" binding:"required,max=50"`
+		Description string `json:"description" binding:"max=255"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 新收藏夹排在最后
+	var maxOrder int
+	c.DB.Model(&models.FavoriteFolder{}).Where("user_id = ?", userID).Select("COALESCE(MAX(sort_order), 0)").Scan(&maxOrder)
+
+	folder := models.FavoriteFolder{
+		UserID:      userID.(uint),
+		Name:        input.Name,
+		Description: input.Description,
+		SortOrder:   maxOrder + 1,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := c.DB.Create(&folder).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建收藏夹失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, folder)
+}
+
+// UpdateFolder 修改收藏夹名称和描述
+func (c *FavoriteController) UpdateFolder(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var folder models.FavoriteFolder
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&folder).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏夹不存在"})
+		return
+	}
+
+	var input struct {
+		Name        string  `json:"name" binding:"max=50"`
+		Description *string `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"updated_at": time.Now(),
+	}
+	if input.Name != "" {
+		updates["name"] = input.Name
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+
+	if err := c.DB.Model(&folder).Updates(updates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新收藏夹失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder 删除收藏夹，其中的条目移动到默认收藏夹
+func (c *FavoriteController) DeleteFolder(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var folder models.FavoriteFolder
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&folder).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏夹不存在"})
+		return
+	}
+
+	if folder.IsDefault {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "默认收藏夹不能删除"})
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		defaultFolder, err := models.GetDefaultFavoriteFolder(tx, userID.(uint))
+		if err != nil {
+			return err
+		}
+
+		// 移动到默认收藏夹末尾，保持原有的相对顺序
+		nextOrder := models.NextFavoriteSortOrder(tx, defaultFolder.ID)
+		if err := tx.Model(&models.FavoriteItem{}).
+			Where("folder_id = ?", folder.ID).
+			Updates(map[string]interface{}{
+				"folder_id":  defaultFolder.ID,
+				"sort_order": gorm.Expr("sort_order + ?", nextOrder),
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&folder).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除收藏夹失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "收藏夹已删除"})
+}
+
+// ReorderFolders 调整收藏夹顺序
+func (c *FavoriteController) ReorderFolders(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var input struct {
+		FolderIDs []uint `json:"folder_ids" binding:"required,min=1"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 只允许调整自己的收藏夹
+	var count int64
+	c.DB.Model(&models.FavoriteFolder{}).Where("id IN ? AND user_id = ?", input.FolderIDs, userID).Count(&count)
+	if int(count) != len(input.FolderIDs) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "包含无效的收藏夹"})
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range input.FolderIDs {
+			if err := tx.Model(&models.FavoriteFolder{}).Where("id = ?", id).Update("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "调整顺序失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "顺序已更新"})
+}
+
+// ReorderItems 调整收藏夹内条目的顺序
+func (c *FavoriteController) ReorderItems(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var folder models.FavoriteFolder
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&folder).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏夹不存在"})
+		return
+	}
+
+	var input struct {
+		ItemIDs []uint `json:"item_ids" binding:"required,min=1"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	c.DB.Model(&models.FavoriteItem{}).Where("id IN ? AND folder_id = ?", input.ItemIDs, folder.ID).Count(&count)
+	if int(count) != len(input.ItemIDs) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "包含不属于该收藏夹的条目"})
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range input.ItemIDs {
+			if err := tx.Model(&models.FavoriteItem{}).Where("id = ?", id).Update("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "调整顺序失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "顺序已更新"})
+}
+
+// GetFavorites 获取收藏列表，可按类型和收藏夹筛选
+func (c *FavoriteController) GetFavorites(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	itemType := ctx.Query("type")
+	folderID := ctx.Query("folder_id")
+
+	query := c.DB.Model(&models.FavoriteItem{}).Where("user_id = ?", userID)
+
+	// 类型筛选
+	if itemType != "" {
+		if itemType != models.FavoriteItemResource && itemType != models.FavoriteItemTopic {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的收藏类型"})
+			return
+		}
+		query = query.Where("item_type = ?", itemType)
+	}
+
+	// 收藏夹筛选，指定收藏夹时按自定义顺序排列
+	if folderID != "" {
+		query = query.Where("folder_id = ?", folderID).Order("sort_order ASC")
+	} else {
+		query = query.Order("created_at DESC")
+	}
+
+	var items []models.FavoriteItem
+	var total int64
+
+	query.Count(&total)
+	query.Order("id DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&items)
+
+	c.attachFavoriteTargets(items, getResourceViewer(ctx, c.DB))
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"items":     items,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"totalPage": int(math.Ceil(float64(total) / float64(pageSize))),
+	})
+}
+
+// AddFavorite 收藏资源或主题
+func (c *FavoriteController) AddFavorite(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var input struct {
+		ItemType string `json:"item_type" binding:"required,oneof=resource topic"`
+		ItemID   uint   `json:"item_id" binding:"required"`
+		FolderID uint   `json:"folder_id"`
+		Note     string `json:"note" binding:"max=1000"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 检查收藏目标是否存在
+	if input.ItemType == models.FavoriteItemResource {
+		if err := c.DB.First(&models.Resource{}, input.ItemID).Error; err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+			return
+		}
+	} else {
+		if err := c.DB.First(&models.Topic{}, input.ItemID).Error; err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+			return
+		}
+	}
+
+	folder, ok := c.resolveFolder(ctx, userID.(uint), input.FolderID)
+	if !ok {
+		return
+	}
+
+	item, err := addFavoriteItem(c.DB, userID.(uint), folder.ID, input.ItemType, input.ItemID, input.Note)
+	if err == errFavoriteExists {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "已收藏", "isFavorited": true})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item)
+}
+
+// UpdateFavoriteNote 修改收藏条目的备注
+func (c *FavoriteController) UpdateFavoriteNote(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var item models.FavoriteItem
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&item).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏不存在"})
+		return
+	}
+
+	var input struct {
+		Note string `json:"note" binding:"max=1000"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.DB.Model(&item).Updates(map[string]interface{}{
+		"note":       input.Note,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新备注失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+// MoveFavorite 将收藏条目移动到其他收藏夹
+func (c *FavoriteController) MoveFavorite(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	var item models.FavoriteItem
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&item).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏不存在"})
+		return
+	}
+
+	var input struct {
+		FolderID uint `json:"folder_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, ok := c.resolveFolder(ctx, userID.(uint), input.FolderID)
+	if !ok {
+		return
+	}
+
+	if folder.ID != item.FolderID {
+		if err := c.DB.Model(&item).Updates(map[string]interface{}{
+			"folder_id":  folder.ID,
+			"sort_order": models.NextFavoriteSortOrder(c.DB, folder.ID),
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "移动收藏失败"})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+// RemoveFavorite 删除收藏条目
+func (c *FavoriteController) RemoveFavorite(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权访问"})
+		return
+	}
+
+	result := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).Delete(&models.FavoriteItem{})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消收藏失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏不存在"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已取消收藏", "isFavorited": false})
+}
+
+// resolveFolder 获取用户指定的收藏夹，未指定时使用默认收藏夹
+func (c *FavoriteController) resolveFolder(ctx *gin.Context, userID uint, folderID uint) (*models.FavoriteFolder, bool) {
+	if folderID == 0 {
+		folder, err := models.GetDefaultFavoriteFolder(c.DB, userID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏夹失败"})
+			return nil, false
+		}
+		return folder, true
+	}
+
+	var folder models.FavoriteFolder
+	if err := c.DB.Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "收藏夹不存在"})
+		return nil, false
+	}
+	return &folder, true
+}
+
+// attachFavoriteTargets 批量加载收藏条目对应的资源和主题
+// 已删除或访问者不能再查看的内容（如改为私有的资源、被隐藏或静默的主题）不返回，条目标记为不可用
+func (c *FavoriteController) attachFavoriteTargets(items []models.FavoriteItem, viewer resourceViewer) {
+	var resourceIDs, topicIDs []uint
+	for _, item := range items {
+		if item.ItemType == models.FavoriteItemResource {
+			resourceIDs = append(resourceIDs, item.ItemID)
+		} else {
+			topicIDs = append(topicIDs, item.ItemID)
+		}
+	}
+
+	resourceMap := make(map[uint]*models.Resource)
+	if len(resourceIDs) > 0 {
+		var resources []models.Resource
+		visibleResources(c.DB.Model(&models.Resource{}).Where("resources.id IN ?", resourceIDs), viewer).
+			Preload("User").Preload("Category").Find(&resources)
+		for i := range resources {
+			resourceMap[resources[i].ID] = &resources[i]
+		}
+	}
+
+	topicMap := make(map[uint]*models.Topic)
+	if len(topicIDs) > 0 {
+		var topics []models.Topic
+		c.DB.Preload("User").Preload("Category").Where("id IN ?", topicIDs).Find(&topics)
+		for i := range topics {
+			if canViewTopic(&topics[i], viewer) {
+				topicMap[topics[i].ID] = &topics[i]
+			}
+		}
+	}
+
+	for i := range items {
+		if items[i].ItemType == models.FavoriteItemResource {
+			items[i].Resource = resourceMap[items[i].ItemID]
+		} else {
+			items[i].Topic = topicMap[items[i].ItemID]
+		}
+		items[i].Unavailable = items[i].Resource == nil && items[i].Topic == nil
+	}
+}
+
+// errFavoriteExists 重复收藏时返回的错误
+var errFavoriteExists = errors.New("已收藏")
+
+// addFavoriteItem 将资源或主题加入指定收藏夹，供各控制器共用
+func addFavoriteItem(db *gorm.DB, userID, folderID uint, itemType string, itemID uint, note string) (*models.FavoriteItem, error) {
+	var count int64
+	db.Model(&models.FavoriteItem{}).Where("user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID).Count(&count)
+	if count > 0 {
+		return nil, errFavoriteExists
+	}
+
+	item := models.FavoriteItem{
+		UserID:    userID,
+		FolderID:  folderID,
+		ItemType:  itemType,
+		ItemID:    itemID,
+		Note:      note,
+		SortOrder: models.NextFavoriteSortOrder(db, folderID),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.Create(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// isFavorited 检查用户是否已收藏指定资源或主题
+func isFavorited(db *gorm.DB, userID interface{}, itemType string, itemID interface{}) bool {
+	var count int64
+	db.Model(&models.FavoriteItem{}).Where("user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,378 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+const (
+	// maxAttachmentSize 单个附件的最大字节数
+	maxAttachmentSize = 10 << 20
+	// maxUnusedAttachments 每个用户最多保留的未使用附件数
+	maxUnusedAttachments = 50
+	// attachmentThumbnailSize 缩略图长边的最大像素
+	attachmentThumbnailSize = 400
+	// attachmentURLExpires 附件临时访问地址的有效期
+	attachmentURLExpires = time.Hour
+	// unusedAttachmentTTL 上传后超过该时间仍未被引用的附件会被清理
+	unusedAttachmentTTL = 24 * time.Hour
+	// attachmentCleanupInterval 清理未使用附件的间隔
+	attachmentCleanupInterval = time.Hour
+	// attachmentPathPrefix 内容中引用附件的路径前缀
+	attachmentPathPrefix = "/api/forum/attachments/"
+)
+
+// attachmentImageTypes 允许上传的图片格式，上传时按实际内容识别
+var attachmentImageTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// attachmentFileExtensions 允许上传的其他文件类型
+var attachmentFileExtensions = map[string]bool{
+	".pdf": true, ".txt": true, ".md": true,
+	".zip": true, ".rar": true, ".7z": true,
+	".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
+}
+
+// attachmentKeyPattern 匹配内容中引用的附件地址
+var attachmentKeyPattern = regexp.MustCompile(regexp.QuoteMeta(attachmentPathPrefix) + `([0-9a-f-]{36})`)
+
+// attachmentResponse 附件的返回数据，包含访问地址和可以直接插入Markdown的文本
+func attachmentResponse(attachment *models.Attachment) gin.H {
+	url := attachmentPathPrefix + attachment.Key
+	// 文件名中的方括号会破坏Markdown链接的语法
+	label := strings.NewReplacer("[", "", "]", "").Replace(attachment.FileName)
+	markdown := fmt.Sprintf("[%s](%s)", label, url)
+	thumbnailURL := ""
+	if attachment.IsImage {
+		// 正文中显示缩略图，点击查看原图
+		thumbnailURL = url + "/thumbnail"
+		markdown = fmt.Sprintf("[![%s](%s)](%s)", label, thumbnailURL, url)
+	}
+
+	return gin.H{
+		"id":            attachment.ID,
+		"key":           attachment.Key,
+		"file_name":     attachment.FileName,
+		"content_type":  attachment.ContentType,
+		"size":          attachment.Size,
+		"is_image":      attachment.IsImage,
+		"width":         attachment.Width,
+		"height":        attachment.Height,
+		"target_type":   attachment.TargetType,
+		"target_id":     attachment.TargetID,
+		"url":           url,
+		"thumbnail_url": thumbnailURL,
+		"markdown":      markdown,
+		"created_at":    attachment.CreatedAt,
+	}
+}
+
+// bindAttachments 将内容中引用的、当前用户上传且尚未使用的附件关联到主题、回复或草稿
+// 发布主题或回复时，草稿中的附件转为关联到发布的内容
+// 编辑后不再引用的附件保持关联，编辑历史中的旧版本仍然可以显示
+func bindAttachments(db *gorm.DB, userID uint, targetType string, targetID uint, content string) {
+	var keys []string
+	for _, match := range attachmentKeyPattern.FindAllStringSubmatch(content, -1) {
+		keys = append(keys, match[1])
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	sources := []string{""}
+	if targetType != models.AttachmentTargetDraft {
+		sources = append(sources, models.AttachmentTargetDraft)
+	}
+	if err := db.Model(&models.Attachment{}).
+		Where("`key` IN ? AND user_id = ? AND target_type IN ?", keys, userID, sources).
+		Updates(map[string]interface{}{"target_type": targetType, "target_id": targetID}).Error; err != nil {
+		log.Printf("关联附件失败: %v", err)
+	}
+}
+
+// removeAttachments 删除附件的文件和记录
+// 文件删除失败的附件改为未使用状态，由定时清理任务重试
+func removeAttachments(db *gorm.DB, files *utils.MinioUtils, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		err := files.DeleteFile(attachment.ObjectName)
+		if err == nil && attachment.ThumbnailObject != "" {
+			err = files.DeleteFile(attachment.ThumbnailObject)
+		}
+		if err != nil {
+			log.Printf("删除附件%d的文件失败: %v", attachment.ID, err)
+			db.Model(&attachment).Updates(map[string]interface{}{"target_type": "", "target_id": 0})
+			continue
+		}
+		db.Delete(&attachment)
+	}
+}
+
+// removeTopicAttachments 删除主题及其全部回复的附件
+func removeTopicAttachments(db *gorm.DB, files *utils.MinioUtils, topicID uint) {
+	var attachments []models.Attachment
+	db.Where("(target_type = ? AND target_id = ?) OR (target_type = ? AND target_id IN (SELECT id FROM replies WHERE topic_id = ?))",
+		models.AttachmentTargetTopic, topicID, models.AttachmentTargetReply, topicID).
+		Find(&attachments)
+	removeAttachments(db, files, attachments)
+}
+
+// removeReplyAttachments 删除回复的附件
+func removeReplyAttachments(db *gorm.DB, files *utils.MinioUtils, replyID uint) {
+	var attachments []models.Attachment
+	db.Where("target_type = ? AND target_id = ?", models.AttachmentTargetReply, replyID).Find(&attachments)
+	removeAttachments(db, files, attachments)
+}
+
+// UploadAttachment 上传主题或回复的附件，图片会生成缩略图
+// 上传后在内容中引用返回的地址，发布时自动关联到主题或回复
+func (c *ForumController) UploadAttachment(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请选择要上传的文件"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAttachmentSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("附件大小不能超过%dMB", maxAttachmentSize>>20)})
+		return
+	}
+
+	var unused int64
+	c.DB.Model(&models.Attachment{}).Where("user_id = ? AND target_type = ?", userID, "").Count(&unused)
+	if unused >= maxUnusedAttachments {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未使用的附件过多，请先发布或删除已上传的附件"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil || len(data) > maxAttachmentSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return
+	}
+
+	key := uuid.New().String()
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	attachment := models.Attachment{
+		Key:       key,
+		UserID:    userID.(uint),
+		FileName:  filepath.Base(header.Filename),
+		Size:      int64(len(data)),
+		CreatedAt: time.Now(),
+	}
+
+	// 图片按实际内容识别格式，其他文件按扩展名检查
+	var thumbnail []byte
+	if format, width, height, err := utils.ImageConfig(data); err == nil && attachmentImageTypes[format] != "" {
+		if width*height > utils.MaxImagePixels {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "图片尺寸过大"})
+			return
+		}
+		thumbnail, err = utils.MakeThumbnail(data, attachmentThumbnailSize)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无法识别的图片"})
+			return
+		}
+		attachment.IsImage = true
+		attachment.ContentType = attachmentImageTypes[format]
+		attachment.Width = width
+		attachment.Height = height
+	} else if attachmentFileExtensions[ext] {
+		attachment.ContentType = mime.TypeByExtension(ext)
+		if attachment.ContentType == "" {
+			attachment.ContentType = "application/octet-stream"
+		}
+	} else {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件类型"})
+		return
+	}
+
+	attachment.ObjectName = fmt.Sprintf("forum/attachments/%s%s", key, ext)
+	if err := c.Files.PutObject(attachment.ObjectName, bytes.NewReader(data), attachment.Size, attachment.ContentType); err != nil {
+		log.Printf("上传附件失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "上传附件失败"})
+		return
+	}
+
+	if thumbnail != nil {
+		attachment.ThumbnailObject = fmt.Sprintf("forum/thumbnails/%s.jpg", key)
+		if err := c.Files.PutObject(attachment.ThumbnailObject, bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg"); err != nil {
+			log.Printf("上传缩略图失败: %v", err)
+			c.Files.DeleteFile(attachment.ObjectName)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "上传附件失败"})
+			return
+		}
+	}
+
+	if err := c.DB.Create(&attachment).Error; err != nil {
+		removeAttachments(c.DB, c.Files, []models.Attachment{attachment})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "上传附件失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, attachmentResponse(&attachment))
+}
+
+// GetMyAttachments 获取当前用户上传的附件，unused=true时只返回未使用的附件
+func (c *ForumController) GetMyAttachments(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := c.DB.Model(&models.Attachment{}).Where("user_id = ?", userID)
+	if ctx.Query("unused") == "true" {
+		query = query.Where("target_type = ?", "")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var attachments []models.Attachment
+	query.Order("created_at DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&attachments)
+
+	results := make([]gin.H, 0, len(attachments))
+	for i := range attachments {
+		results = append(results, attachmentResponse(&attachments[i]))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"attachments": results,
+		"total":       total,
+		"page":        page,
+		"pageSize":    pageSize,
+	})
+}
+
+// DeleteAttachment 删除自己上传的未使用附件，已关联的附件随主题或回复一起删除
+func (c *ForumController) DeleteAttachment(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var attachment models.Attachment
+	if err := c.DB.Where("`key` = ? AND user_id = ?", ctx.Param("key"), userID).First(&attachment).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "附件不存在"})
+		return
+	}
+	if attachment.TargetType != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "附件已在内容中使用，不能单独删除"})
+		return
+	}
+
+	removeAttachments(c.DB, c.Files, []models.Attachment{attachment})
+	ctx.JSON(http.StatusOK, gin.H{"message": "附件已删除"})
+}
+
+// GetAttachment 跳转到附件的临时访问地址，图片直接显示，其他文件以原文件名下载
+func (c *ForumController) GetAttachment(ctx *gin.Context) {
+	c.redirectAttachment(ctx, false)
+}
+
+// GetAttachmentThumbnail 跳转到图片附件的缩略图，图片本身较小时跳转到原图
+func (c *ForumController) GetAttachmentThumbnail(ctx *gin.Context) {
+	c.redirectAttachment(ctx, true)
+}
+
+// redirectAttachment 按附件标识查找附件并跳转到MinIO的临时地址
+// 附件标识是随机生成的，知道地址即可访问，和内容中其他外链图片的行为一致
+func (c *ForumController) redirectAttachment(ctx *gin.Context, thumbnail bool) {
+	var attachment models.Attachment
+	if err := c.DB.Where("`key` = ?", ctx.Param("key")).First(&attachment).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "附件不存在"})
+		return
+	}
+
+	var url string
+	var err error
+	switch {
+	case thumbnail && !attachment.IsImage:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "附件没有缩略图"})
+		return
+	case thumbnail && attachment.ThumbnailObject != "":
+		url, err = c.Files.GetFileURL(attachment.ThumbnailObject, attachmentURLExpires)
+	case attachment.IsImage:
+		url, err = c.Files.GetFileURL(attachment.ObjectName, attachmentURLExpires)
+	default:
+		url, err = c.Files.GetDownloadURL(attachment.ObjectName, attachment.FileName, attachmentURLExpires)
+	}
+	if err != nil {
+		log.Printf("生成附件%d的访问地址失败: %v", attachment.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取附件失败"})
+		return
+	}
+
+	// 临时地址会过期，跳转本身不能被长期缓存
+	ctx.Header("Cache-Control", "private, max-age=600")
+	ctx.Redirect(http.StatusFound, url)
+}
+
+// cleanupAttachments 定时清理长时间未被引用的附件
+func (c *ForumController) cleanupAttachments() {
+	ticker := time.NewTicker(attachmentCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.removeUnusedAttachments()
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// removeUnusedAttachments 删除上传后超过unusedAttachmentTTL仍未使用的附件
+func (c *ForumController) removeUnusedAttachments() {
+	var attachments []models.Attachment
+	c.DB.Where("target_type = ? AND created_at < ?", "", time.Now().Add(-unusedAttachmentTTL)).
+		Limit(100).
+		Find(&attachments)
+
+	if len(attachments) > 0 {
+		removeAttachments(c.DB, c.Files, attachments)
+		log.Printf("已清理%d个未使用的附件", len(attachments))
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -15,19 +16,41 @@ import (
 	"gorm.io/gorm"
 
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
 // ForumController 论坛控制器
 type ForumController struct {
 	DB       *gorm.DB
 	Redis    *redis.Client
-	stopChan chan struct{} // 用于停止定时任务的通道
+	Notifier *utils.Notifier
+	Hub      *utils.RealtimeHub
+	Votes    *utils.TopicVoteStore
+	Views    *utils.ViewCounter
+	Files    *utils.MinioUtils    // 附件存储
+	Filter   *utils.ContentFilter // 敏感词过滤
+	Spam     *utils.SpamGuard     // 发帖反垃圾
+	stopChan chan struct{}        // 用于停止定时任务的通道
 }
 
 // NewForumController 创建论坛控制器实例
-func NewForumController(db *gorm.DB, redisClient *redis.Client) *ForumController {
-	fc := &ForumController{DB: db, Redis: redisClient, stopChan: make(chan struct{})}
-	go fc.syncLikesToDB() // 启动定时同步任务
+func NewForumController(db *gorm.DB, redisClient *redis.Client, notifier *utils.Notifier, hub *utils.RealtimeHub, views *utils.ViewCounter, files *utils.MinioUtils, filter *utils.ContentFilter, spam *utils.SpamGuard) *ForumController {
+	fc := &ForumController{
+		DB:       db,
+		Redis:    redisClient,
+		Notifier: notifier,
+		Hub:      hub,
+		Votes:    utils.NewTopicVoteStore(db, redisClient),
+		Views:    views,
+		Files:    files,
+		Filter:   filter,
+		Spam:     spam,
+		stopChan: make(chan struct{}),
+	}
+	go fc.syncLikesToDB()      // 启动定时同步任务
+	go fc.settleBounties()     // 启动悬赏到期结算任务
+	go fc.sendWatchDigests()   // 启动订阅摘要发送任务
+	go fc.cleanupAttachments() // 启动未使用附件清理任务
 	return fc
 }
 
@@ -61,12 +84,78 @@ func (c *ForumController) GetTopics(ctx *gin.Context) {
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
 	categoryID := ctx.Query("category")
 
-	// 构建查询
-	query := c.DB.Model(&models.Topic{})
+	// 构建查询，隐藏的主题只有管理员能在列表中看到，静默封禁期间发布的主题只有作者和管理员能看到
+	viewer := getResourceViewer(ctx, c.DB)
+	query := visibleShadowed(c.DB.Model(&models.Topic{}), "topics", viewer)
+	if !viewer.IsAdmin {
+		query = query.Where("is_hidden = ?", false)
+	}
 
-	// 分类过滤
+	// 分类过滤，分类列表中全站置顶和分类置顶的主题都排在最前，其余列表只有全站置顶的排在最前
+	pinned := func(topic *models.Topic) bool { return topic.PinScope == models.PinScopeGlobal }
+	pinExpr := "(pin_scope = 'global')"
 	if categoryID != "" {
 		query = query.Where("category_id = ?", categoryID)
+		pinned = func(topic *models.Topic) bool { return topic.PinScope != "" }
+		pinExpr = "(pin_scope <> '')"
+	}
+	keys := keyset{{Expr: pinExpr, Desc: true}, {Expr: "created_at", Desc: true, Time: true}, {Expr: "id", Desc: true}}
+
+	// 标签过滤，多个标签以逗号分隔，只返回带有全部标签的主题
+	if tags := ctx.Query("tag"); tags != "" {
+		query = filterTagged(query, "topics", "topic_tags", "topic_id", tags)
+	}
+
+	// 只看精华
+	if ctx.Query("featured") == "true" {
+		query = query.Where("is_featured = ?", true)
+	}
+
+	// 主题类型过滤
+	if topicType := ctx.Query("type"); topicType != "" {
+		query = query.Where("type = ?", topicType)
+	}
+
+	// 问答的解决状态过滤
+	switch ctx.Query("solved") {
+	case "true":
+		query = query.Where("type = ? AND is_solved = ?", models.TopicTypeQuestion, true)
+	case "false":
+		query = query.Where("type = ? AND is_solved = ?", models.TopicTypeQuestion, false)
+	}
+
+	// 只看悬赏中的问题
+	if ctx.Query("bounty") == "true" {
+		query = query.Where("bounty_status = ?", models.BountyStatusOpen)
+	}
+
+	// 游标分页：不统计总数，数据变化时不会重复或遗漏
+	after, cursorSize, useCursor, err := cursorParams(ctx, keys, 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if useCursor {
+		var topics []models.Topic
+		keys.after(query, after).Preload("User").Preload("Category").Preload("Tags").
+			Order(keys.order()).
+			Limit(cursorSize + 1).
+			Find(&topics)
+
+		nextCursor := ""
+		hasMore := len(topics) > cursorSize
+		if hasMore {
+			topics = topics[:cursorSize]
+			last := &topics[cursorSize-1]
+			nextCursor = keys.encode(boolKey(pinned(last)), last.CreatedAt, last.ID)
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"topics":      topics,
+			"next_cursor": nextCursor,
+			"has_more":    hasMore,
+			"pageSize":    cursorSize,
+		})
+		return
 	}
 
 	// 执行查询
@@ -74,8 +163,8 @@ func (c *ForumController) GetTopics(ctx *gin.Context) {
 	var total int64
 
 	query.Count(&total)
-	query.Preload("User").Preload("Category").
-		Order("created_at DESC").
+	query.Preload("User").Preload("Category").Preload("Tags").
+		Order(keys.order()).
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
 		Find(&topics)
@@ -89,114 +178,9 @@ func (c *ForumController) GetTopics(ctx *gin.Context) {
 	})
 }
 
-// LikeTopic 点赞主题
+// DislikeTopic 点踩主题，已点踩时取消点踩，已点赞时改为点踩
 func (c *ForumController) DislikeTopic(ctx *gin.Context) {
-	// 从上下文获取用户ID
-	userID, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
-		return
-	}
-
-	// 获取主题ID
-	topicID := ctx.Param("id")
-
-	// Redis键名
-	likeKey := "topic_likes:" + topicID
-	dislikeKey := "topic_dislikes:" + topicID
-	userLikeKey := "user_likes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-	userDislikeKey := "user_dislikes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-
-	// 检查用户是否已点赞
-	isLiked, err := c.Redis.SIsMember(ctx, userLikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "检查点赞状态失败"})
-		return
-	}
-
-	// 如果已点赞，先取消点赞
-	if isLiked {
-		// 调试日志：打印当前Redis键值
-		fmt.Printf("[DEBUG] Before remove like - userLikeKey: %s, members: %v\n", userLikeKey, c.Redis.SMembers(ctx, userLikeKey).Val())
-		fmt.Printf("[DEBUG] Before remove like - likeKey: %s, value: %v\n", likeKey, c.Redis.Get(ctx, likeKey).Val())
-
-		_, err = c.Redis.SRem(ctx, userLikeKey, topicID).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消点赞失败"})
-			return
-		}
-		_, err = c.Redis.Decr(ctx, likeKey).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点赞数失败"})
-			return
-		}
-
-		// 调试日志：打印操作后的Redis键值
-		fmt.Printf("[DEBUG] After remove like - userLikeKey: %s, members: %v\n", userLikeKey, c.Redis.SMembers(ctx, userLikeKey).Val())
-		fmt.Printf("[DEBUG] After remove like - likeKey: %s, value: %v\n", likeKey, c.Redis.Get(ctx, likeKey).Val())
-	}
-
-	// 检查用户是否已点踩
-	isDisliked, err := c.Redis.SIsMember(ctx, userDislikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "检查点踩状态失败"})
-		return
-	}
-
-	if isDisliked {
-		// 取消点踩
-		_, err = c.Redis.SRem(ctx, userDislikeKey, topicID).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消点踩失败"})
-			return
-		}
-
-		_, err = c.Redis.Decr(ctx, dislikeKey).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点踩数失败"})
-			return
-		}
-
-		ctx.JSON(http.StatusOK, gin.H{
-			"message":  "取消点踩成功",
-			"disliked": false,
-			"liked":    false,
-			"likes":    c.getLikeCount(topicID),
-			"dislikes": c.getDislikeCount(topicID),
-		})
-	} else {
-		// 点踩
-		// 调试日志：打印当前Redis键值
-		fmt.Printf("[DEBUG] Before add dislike - userDislikeKey: %s, members: %v\n", userDislikeKey, c.Redis.SMembers(ctx, userDislikeKey).Val())
-		fmt.Printf("[DEBUG] Before add dislike - dislikeKey: %s, value: %v\n", dislikeKey, c.Redis.Get(ctx, dislikeKey).Val())
-
-		_, err = c.Redis.SAdd(ctx, userDislikeKey, topicID).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "点踩失败"})
-			return
-		}
-
-		_, err = c.Redis.Incr(ctx, dislikeKey).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点踩数失败"})
-			return
-		}
-
-		// 调试日志：打印操作后的Redis键值
-		fmt.Printf("[DEBUG] After add dislike - userDislikeKey: %s, members: %v\n", userDislikeKey, c.Redis.SMembers(ctx, userDislikeKey).Val())
-		fmt.Printf("[DEBUG] After add dislike - dislikeKey: %s, value: %v\n", dislikeKey, c.Redis.Get(ctx, dislikeKey).Val())
-
-		ctx.JSON(http.StatusOK, gin.H{
-			"message":  "点踩成功",
-			"disliked": true,
-			"liked":    false,
-			"likes":    c.getLikeCount(topicID),
-			"dislikes": c.getDislikeCount(topicID),
-		})
-	}
-
-	// 立即同步点踩数据
-	c.syncAllDislikes()
+	c.voteTopic(ctx, -1, utils.TopicVoteToggle)
 }
 
 // AddFavorite 收藏主题
@@ -218,21 +202,18 @@ func (c *ForumController) AddFavorite(ctx *gin.Context) {
 		return
 	}
 
-	// 检查是否已收藏
-	var existingFavorite models.UserTopicFavorite
-	if err := c.DB.Where("user_id = ? AND topic_id = ?", userID, topic.ID).First(&existingFavorite).Error; err == nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "已收藏该主题", "isFavorited": true})
+	// 收藏到默认收藏夹
+	folder, err := models.GetDefaultFavoriteFolder(c.DB, userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
 		return
 	}
 
-	// 创建收藏记录
-	favorite := models.UserTopicFavorite{
-		UserID:    userID.(uint),
-		TopicID:   topic.ID,
-		CreatedAt: time.Now(),
-	}
-
-	if err := c.DB.Create(&favorite).Error; err != nil {
+	if _, err := addFavoriteItem(c.DB, userID.(uint), folder.ID, models.FavoriteItemTopic, topic.ID, ""); err != nil {
+		if err == errFavoriteExists {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "已收藏该主题", "isFavorited": true})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
 		return
 	}
@@ -260,7 +241,7 @@ func (c *ForumController) RemoveFavorite(ctx *gin.Context) {
 	}
 
 	// 删除收藏记录
-	if err := c.DB.Where("user_id = ? AND topic_id = ?", userID, topic.ID).Delete(&models.UserTopicFavorite{}).Error; err != nil {
+	if err := c.DB.Where("user_id = ? AND item_type = ? AND item_id = ?", userID, models.FavoriteItemTopic, topic.ID).Delete(&models.FavoriteItem{}).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消收藏失败"})
 		return
 	}
@@ -281,8 +262,7 @@ func (c *ForumController) GetFavoriteStatus(ctx *gin.Context) {
 	topicID := ctx.Param("id")
 
 	// 检查收藏状态
-	var favorite models.UserTopicFavorite
-	if err := c.DB.Where("user_id = ? AND topic_id = ?", userID, topicID).First(&favorite).Error; err != nil {
+	if !isFavorited(c.DB, userID, models.FavoriteItemTopic, topicID) {
 		ctx.JSON(http.StatusOK, gin.H{"isFavorited": false})
 		return
 	}
@@ -290,7 +270,7 @@ func (c *ForumController) GetFavoriteStatus(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"isFavorited": true})
 }
 
-// syncLikesToDB 定时将Redis中的点赞数据同步到MySQL
+// syncLikesToDB 定时将投票有变化的主题的点赞点踩数同步到MySQL
 func (c *ForumController) syncLikesToDB() {
 	ticker := time.NewTicker(30 * time.Second) // 每30秒同步一次
 	defer ticker.Stop()
@@ -298,105 +278,39 @@ func (c *ForumController) syncLikesToDB() {
 	for {
 		select {
 		case <-ticker.C:
-			c.syncAllLikes()
+			c.Votes.SyncDirty(context.Background())
 		case <-c.stopChan:
 			return
 		}
 	}
 }
 
-// syncAllLikes 同步所有主题的点赞数到数据库
-func (c *ForumController) syncAllLikes() {
-	// 获取所有主题ID
-	var topicIDs []string
-	iter := c.Redis.Scan(context.Background(), 0, "topic_likes:*", 0).Iterator()
-	for iter.Next(context.Background()) {
-		key := iter.Val()
-		topicID := strings.TrimPrefix(key, "topic_likes:")
-		topicIDs = append(topicIDs, topicID)
-	}
-
-	// 同步每个主题的点赞数
-	for _, topicID := range topicIDs {
-		likeKey := "topic_likes:" + topicID
-		dislikeKey := "topic_dislikes:" + topicID
-
-		// 获取点赞数
-		likeCount, err := c.Redis.Get(context.Background(), likeKey).Int64()
-		if err != nil && err != redis.Nil {
-			continue
-		}
-
-		// 获取点踩数
-		dislikeCount, err := c.Redis.Get(context.Background(), dislikeKey).Int64()
-		if err != nil && err != redis.Nil {
-			continue
-		}
-
-		// 更新数据库
-		c.DB.Model(&models.Topic{}).Where("id = ?", topicID).
-			Updates(map[string]interface{}{
-				"like_count":    likeCount,
-				"dislike_count": dislikeCount,
-			})
-	}
+// publishTopicVotes 推送主题最新的点赞点踩数
+func (c *ForumController) publishTopicVotes(topicID uint, counts utils.TopicVoteCounts) {
+	c.Hub.Publish(utils.TopicChannel(topicID), utils.EventTopicVotes, gin.H{
+		"topic_id": topicID,
+		"likes":    counts.Likes,
+		"dislikes": counts.Dislikes,
+	})
 }
 
-func (c *ForumController) syncAllDislikes() {
-	// 获取所有主题ID
-	var topicIDs []string
-	iter := c.Redis.Scan(context.Background(), 0, "topic_dislikes:*", 0).Iterator()
-	for iter.Next(context.Background()) {
-		key := iter.Val()
-		topicID := strings.TrimPrefix(key, "topic_dislikes:")
-		topicIDs = append(topicIDs, topicID)
-	}
-
-	// 同步每个主题的点踩数
-	for _, topicID := range topicIDs {
-		likeKey := "topic_likes:" + topicID
-		dislikeKey := "topic_dislikes:" + topicID
-
-		// 获取点赞数
-		likeCount, err := c.Redis.Get(context.Background(), likeKey).Int64()
-		if err != nil && err != redis.Nil {
-			continue
-		}
-
-		// 获取点踩数
-		dislikeCount, err := c.Redis.Get(context.Background(), dislikeKey).Int64()
-		if err != nil && err != redis.Nil {
-			continue
-		}
-
-		// 更新数据库
-		c.DB.Model(&models.Topic{}).Where("id = ?", topicID).
-			Updates(map[string]interface{}{
-				"like_count":    likeCount,
-				"dislike_count": dislikeCount,
-			})
-	}
+// UnlikeTopic 取消点赞主题
+func (c *ForumController) UnlikeTopic(ctx *gin.Context) {
+	c.voteTopic(ctx, 1, utils.TopicVoteClear)
 }
 
-func (c *ForumController) getLikeCount(topicID string) int64 {
-	likeKey := "topic_likes:" + topicID
-	likeCount, err := c.Redis.Get(context.Background(), likeKey).Int64()
-	if err != nil && err != redis.Nil {
-		return 0
-	}
-	return likeCount
+// UnDislikeTopic 取消点踩主题
+func (c *ForumController) UnDislikeTopic(ctx *gin.Context) {
+	c.voteTopic(ctx, -1, utils.TopicVoteClear)
 }
 
-func (c *ForumController) getDislikeCount(topicID string) int64 {
-	dislikeKey := "topic_dislikes:" + topicID
-	dislikeCount, err := c.Redis.Get(context.Background(), dislikeKey).Int64()
-	if err != nil && err != redis.Nil {
-		return 0
-	}
-	return dislikeCount
+// LikeTopic 点赞主题，已点赞时取消点赞，已点踩时改为点赞
+func (c *ForumController) LikeTopic(ctx *gin.Context) {
+	c.voteTopic(ctx, 1, utils.TopicVoteToggle)
 }
 
-func (c *ForumController) UnlikeTopic(ctx *gin.Context) {
+// voteTopic 修改当前用户对主题的投票，value为1点赞、-1点踩
+func (c *ForumController) voteTopic(ctx *gin.Context, value int, op string) {
 	// 从上下文获取用户ID
 	userID, exists := ctx.Get("userID")
 	if !exists {
@@ -404,278 +318,220 @@ func (c *ForumController) UnlikeTopic(ctx *gin.Context) {
 		return
 	}
 
-	// 获取主题ID
-	topicID := ctx.Param("id")
-
-	// Redis键名
-	likeKey := "topic_likes:" + topicID
-	userLikeKey := "user_likes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-
-	// 检查用户是否已点赞
-	isLiked, err := c.Redis.SIsMember(ctx, userLikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "检查点赞状态失败"})
+	var topic models.Topic
+	if err := c.DB.First(&topic, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
 		return
 	}
 
-	if !isLiked {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未点赞该主题"})
+	result, err := c.Votes.Vote(ctx.Request.Context(), topic.ID, userID.(uint), value, op)
+	if err != nil {
+		log.Printf("主题%d投票失败: %v", topic.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "投票失败"})
 		return
 	}
 
-	// 取消点赞
-	_, err = c.Redis.SRem(ctx, userLikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消点赞失败"})
+	// 取消投票时用户原来没有投这一票
+	if op == utils.TopicVoteClear && result.Previous != value {
+		if value > 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "未点赞该主题"})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "未点踩该主题"})
+		}
 		return
 	}
 
-	_, err = c.Redis.Decr(ctx, likeKey).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点赞数失败"})
-		return
+	var message string
+	switch {
+	case result.Current == 1:
+		message = "点赞成功"
+	case result.Current == -1:
+		message = "点踩成功"
+	case result.Previous == 1:
+		message = "取消点赞成功"
+	default:
+		message = "取消点踩成功"
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message":  "取消点赞成功",
-		"liked":    false,
-		"likes":    c.getLikeCount(topicID),
-		"dislikes": c.getDislikeCount(topicID),
+		"message":  message,
+		"liked":    result.Current == 1,
+		"disliked": result.Current == -1,
+		"likes":    result.Likes,
+		"dislikes": result.Dislikes,
 	})
 
-	// 立即同步点赞数据
-	c.syncAllLikes()
-}
-
-func (c *ForumController) UnDislikeTopic(ctx *gin.Context) {
-	// 从上下文获取用户ID
-	userID, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+	if result.Previous == result.Current {
 		return
 	}
+	c.publishTopicVotes(topic.ID, result.TopicVoteCounts)
 
-	// 获取主题ID
-	topicID := ctx.Param("id")
-
-	// Redis键名
-	dislikeKey := "topic_dislikes:" + topicID
-	userDislikeKey := "user_dislikes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-
-	// 检查用户是否已点踩
-	isDisliked, err := c.Redis.SIsMember(ctx, userDislikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "检查点踩状态失败"})
-		return
+	if result.Current == 1 {
+		c.Notifier.Notify(likeNotification(c.DB, userID.(uint), topic.UserID,
+			models.NotificationTargetTopic, topic.ID, "你的主题《"+topic.Title+"》"))
 	}
+}
 
-	if !isDisliked {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未点踩该主题"})
+// GetTopicLikes 获取主题点赞数
+func (c *ForumController) GetTopicLikes(ctx *gin.Context) {
+	var topic models.Topic
+	if err := c.DB.Select("id").First(&topic, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
 		return
 	}
 
-	// 取消点踩
-	_, err = c.Redis.SRem(ctx, userDislikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消点踩失败"})
-		return
+	// 登录时同时返回用户的投票状态
+	var viewerID uint
+	if userID, exists := ctx.Get("userID"); exists {
+		viewerID = userID.(uint)
 	}
 
-	_, err = c.Redis.Decr(ctx, dislikeKey).Result()
+	counts, vote, err := c.Votes.State(ctx.Request.Context(), topic.ID, viewerID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点踩数失败"})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取点赞数失败"})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message":  "取消点踩成功",
-		"disliked": false,
-		"likes":    c.getLikeCount(topicID),
-		"dislikes": c.getDislikeCount(topicID),
+		"likes":        counts.Likes,
+		"dislikes":     counts.Dislikes,
+		"userLiked":    vote == 1,
+		"userDisliked": vote == -1,
 	})
-
-	// 立即同步点踩数据
-	c.syncAllDislikes()
 }
 
-func (c *ForumController) LikeTopic(ctx *gin.Context) {
-	// 从上下文获取用户ID
-	userID, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
-		return
+// canViewTopic 隐藏和静默封禁期间发布的主题只有作者和管理员可以查看
+func canViewTopic(topic *models.Topic, viewer resourceViewer) bool {
+	if !topic.IsHidden && !topic.Shadowed {
+		return true
 	}
+	return viewer.IsAdmin || viewer.UserID == topic.UserID
+}
 
-	// 获取主题ID
-	topicID := ctx.Param("id")
-
-	// Redis键名
-	likeKey := "topic_likes:" + topicID
-	dislikeKey := "topic_dislikes:" + topicID
-	userLikeKey := "user_likes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-	userDislikeKey := "user_dislikes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
+// GetTopicById 获取主题详情
+func (c *ForumController) GetTopicById(ctx *gin.Context) {
+	id := ctx.Param("id")
 
-	// 检查用户是否已点踩
-	isDisliked, err := c.Redis.SIsMember(ctx, userDislikeKey, topicID).Result()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "检查点踩状态失败"})
+	var topic models.Topic
+	result := c.DB.Preload("User").Preload("Category").Preload("Tags").First(&topic, id)
+	if result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
 		return
 	}
 
-	// 如果已点踩，先取消点踩
-	if isDisliked {
-		// 调试日志：打印当前Redis键值
-		fmt.Printf("[DEBUG] Before remove dislike - userDislikeKey: %s, members: %v\n", userDislikeKey, c.Redis.SMembers(ctx, userDislikeKey).Val())
-		fmt.Printf("[DEBUG] Before remove dislike - dislikeKey: %s, value: %v\n", dislikeKey, c.Redis.Get(ctx, dislikeKey).Val())
-
-		_, err = c.Redis.SRem(ctx, userDislikeKey, topicID).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消点踩失败"})
-			return
-		}
-		_, err = c.Redis.Decr(ctx, dislikeKey).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点踩数失败"})
-			return
-		}
-
-		// 调试日志：打印操作后的Redis键值
-		fmt.Printf("[DEBUG] After remove dislike - userDislikeKey: %s, members: %v\n", userDislikeKey, c.Redis.SMembers(ctx, userDislikeKey).Val())
-		fmt.Printf("[DEBUG] After remove dislike - dislikeKey: %s, value: %v\n", dislikeKey, c.Redis.Get(ctx, dislikeKey).Val())
+	viewer := getResourceViewer(ctx, c.DB)
+	if !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return
 	}
 
-	// 检查用户是否已点赞
-	isLiked, err := c.Redis.SIsMember(ctx, userLikeKey, topicID).Result()
+	// 增加浏览次数，同一访客短时间内重复浏览只计一次
+	topic.ViewCount += recordView(ctx, c.Views, utils.ViewTargetTopic, topic.ID, viewer.UserID)
+
+	// 游标分页，树形视图按顶层回复分页
+	sort := ctx.Query("sort")
+	after, cursorSize, useCursor, err := cursorParams(ctx, replyKeyset(sort), 20)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "检查点赞状态失败"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if isLiked {
-		// 取消点赞
-		_, err = c.Redis.SRem(ctx, userLikeKey, topicID).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消点赞失败"})
+	response := gin.H{
+		"topic":          topic,
+		"watch":          viewerWatch(c.DB, viewer.UserID, models.WatchTargetTopic, topic.ID),
+		"poll":           topicPollResults(c.DB, topic.ID, viewer.UserID),
+		"resource_cards": resourceCards(c.DB, topic.Content, viewer), // 内容中引用的资源
+	}
+
+	// 树形视图：分页的顶层回复及展开的楼中楼
+	if ctx.Query("view") == "tree" {
+		response["view"] = "tree"
+		if useCursor {
+			replies, nextCursor := c.getTopicReplyTreeAfter(topic.ID, viewer, sort, after, cursorSize)
+			fillReplyVotes(c.DB, viewer.UserID, replies)
+			response["replies"] = replies
+			response["next_cursor"] = nextCursor
+			response["has_more"] = nextCursor != ""
+			response["pageSize"] = cursorSize
+			ctx.JSON(http.StatusOK, response)
 			return
 		}
 
-		_, err = c.Redis.Decr(ctx, likeKey).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点赞数失败"})
-			return
+		page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+		if page <= 0 {
+			page = 1
 		}
-
-		ctx.JSON(http.StatusOK, gin.H{
-			"message":  "取消点赞成功",
-			"liked":    false,
-			"disliked": false,
-			"likes":    c.getLikeCount(topicID),
-			"dislikes": c.getDislikeCount(topicID),
-		})
-	} else {
-		// 点赞
-		// 调试日志：打印当前Redis键值
-		fmt.Printf("[DEBUG] Before add like - userLikeKey: %s, members: %v\n", userLikeKey, c.Redis.SMembers(ctx, userLikeKey).Val())
-		fmt.Printf("[DEBUG] Before add like - likeKey: %s, value: %v\n", likeKey, c.Redis.Get(ctx, likeKey).Val())
-
-		_, err = c.Redis.SAdd(ctx, userLikeKey, topicID).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "点赞失败"})
-			return
+		if pageSize <= 0 || pageSize > 100 {
+			pageSize = 20
 		}
 
-		_, err = c.Redis.Incr(ctx, likeKey).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新点赞数失败"})
-			return
+		// 指定了回复时跳转到该回复所在的页，用于从搜索结果或通知直接定位到回复
+		if replyID := ctx.Query("reply"); replyID != "" {
+			if replyPage, focusID := c.replyTreePage(topic.ID, viewer, replyID, pageSize, sort); replyPage > 0 {
+				page = replyPage
+				response["focus_reply_id"] = focusID
+			}
 		}
 
-		// 调试日志：打印操作后的Redis键值
-		fmt.Printf("[DEBUG] After add like - userLikeKey: %s, members: %v\n", userLikeKey, c.Redis.SMembers(ctx, userLikeKey).Val())
-		fmt.Printf("[DEBUG] After add like - likeKey: %s, value: %v\n", likeKey, c.Redis.Get(ctx, likeKey).Val())
-
-		ctx.JSON(http.StatusOK, gin.H{
-			"message":  "点赞成功",
-			"liked":    true,
-			"disliked": false,
-			"likes":    c.getLikeCount(topicID),
-			"dislikes": c.getDislikeCount(topicID),
-		})
-	}
-
-	// 立即同步点赞数据
-	c.syncAllLikes()
-}
-
-// GetTopicLikes 获取主题点赞数
-func (c *ForumController) GetTopicLikes(ctx *gin.Context) {
-	topicID := ctx.Param("id")
-	likeKey := "topic_likes:" + topicID
-	dislikeKey := "topic_dislikes:" + topicID
-
-	// 获取点赞数
-	likeCount, err := c.Redis.Get(ctx, likeKey).Int64()
-	if err == redis.Nil {
-		likeCount = 0
-	} else if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取点赞数失败"})
-		return
-	}
-
-	// 获取点踩数
-	dislikeCount, err := c.Redis.Get(ctx, dislikeKey).Int64()
-	if err == redis.Nil {
-		dislikeCount = 0
-	} else if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取点踩数失败"})
+		replies, total := c.getTopicReplyTree(topic.ID, viewer, page, pageSize, sort)
+		fillReplyVotes(c.DB, viewer.UserID, replies)
+		response["replies"] = replies
+		response["total"] = total
+		response["page"] = page
+		response["pageSize"] = pageSize
+		ctx.JSON(http.StatusOK, response)
 		return
 	}
 
-	// 获取用户点赞状态
-	userLiked := false
-	userDisliked := false
-	if userID, exists := ctx.Get("userID"); exists {
-		userLikeKey := "user_likes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-		userDislikeKey := "user_dislikes:" + strconv.FormatUint(uint64(userID.(uint)), 10)
+	// 平铺视图，默认按时间顺序
+	response["view"] = "flat"
+	query := visiblePublished(c.DB.Model(&models.Reply{}).Where("topic_id = ?", topic.ID), "replies", viewer)
 
-		if isLiked, _ := c.Redis.SIsMember(ctx, userLikeKey, topicID).Result(); isLiked {
-			userLiked = true
+	// 带页码参数时按页码分页
+	_, hasPage := ctx.GetQuery("page")
+	_, hasPageSize := ctx.GetQuery("pageSize")
+	if !useCursor && (hasPage || hasPageSize) {
+		page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+		if page <= 0 {
+			page = 1
 		}
-		if isDisliked, _ := c.Redis.SIsMember(ctx, userDislikeKey, topicID).Result(); isDisliked {
-			userDisliked = true
+		if pageSize <= 0 || pageSize > 100 {
+			pageSize = 20
 		}
-	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"likes":        likeCount,
-		"dislikes":     dislikeCount,
-		"userLiked":    userLiked,
-		"userDisliked": userDisliked,
-	})
-}
-
-// GetTopicById 获取主题详情
-func (c *ForumController) GetTopicById(ctx *gin.Context) {
-	id := ctx.Param("id")
-
-	var topic models.Topic
-	result := c.DB.Preload("User").Preload("Category").First(&topic, id)
-	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		var total int64
+		query.Count(&total)
+
+		var replies []models.Reply
+		preloadReplyUsers(query).
+			Order(replyOrder(sort)).
+			Limit(pageSize).
+			Offset((page - 1) * pageSize).
+			Find(&replies)
+		fillReplyVotes(c.DB, viewer.UserID, replies)
+
+		response["replies"] = replies
+		response["total"] = total
+		response["page"] = page
+		response["pageSize"] = pageSize
+		ctx.JSON(http.StatusOK, response)
 		return
 	}
 
-	// 增加浏览次数
-	c.DB.Model(&topic).Update("view_count", gorm.Expr("view_count + 1"))
-
-	// 获取回复
-	var replies []models.Reply
-	c.DB.Where("topic_id = ?", id).Preload("User").Order("created_at ASC").Find(&replies)
+	// 不带分页参数的旧客户端最多返回maxFlatReplies条回复，其余通过游标继续加载
+	if !useCursor {
+		cursorSize = maxFlatReplies
+	}
+	replies, nextCursor := findRepliesAfter(query, sort, after, cursorSize)
+	fillReplyVotes(c.DB, viewer.UserID, replies)
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"topic":   topic,
-		"replies": replies,
-	})
+	response["replies"] = replies
+	response["next_cursor"] = nextCursor
+	response["has_more"] = nextCursor != ""
+	response["pageSize"] = cursorSize
+	ctx.JSON(http.StatusOK, response)
 }
 
 // CreateTopic 创建主题
@@ -689,9 +545,14 @@ func (c *ForumController) CreateTopic(ctx *gin.Context) {
 
 	// 绑定请求数据
 	var input struct {
-		Title      string `json:"title" binding:"required"`
-		Content    string `json:"content" binding:"required"`
-		CategoryID uint   `json:"category_id" binding:"required"`
+		Title        string     `json:"title" binding:"required"`
+		Content      string     `json:"content" binding:"required"`
+		CategoryID   uint       `json:"category_id" binding:"required"`
+		Type         string     `json:"type" binding:"omitempty,oneof=discussion question"`
+		BountyPoints int        `json:"bounty_points" binding:"min=0"`
+		BountyDays   int        `json:"bounty_days" binding:"min=0"`
+		Poll         *pollInput `json:"poll"` // 可选的投票
+		Tags         []string   `json:"tags"` // 可选的标签
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -706,40 +567,183 @@ func (c *ForumController) CreateTopic(ctx *gin.Context) {
 		return
 	}
 
+	// 检查悬赏设置，只有问答主题可以悬赏
+	if input.Type == "" {
+		input.Type = models.TopicTypeDiscussion
+	}
+	if input.BountyPoints > 0 && input.Type != models.TopicTypeQuestion {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "只有问答主题可以设置悬赏"})
+		return
+	}
+	if input.BountyPoints > maxBountyPoints {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("悬赏积分不能超过%d", maxBountyPoints)})
+		return
+	}
+	if input.BountyDays > maxBountyDays {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("悬赏期限不能超过%d天", maxBountyDays)})
+		return
+	}
+
+	// 检查敏感词，投票的问题和选项一并检查
+	fields := []textField{{"title", &input.Title}, {"content", &input.Content}}
+	if input.Poll != nil {
+		fields = append(fields, textField{"poll_question", &input.Poll.Question})
+		for i := range input.Poll.Options {
+			fields = append(fields, textField{"poll_option", &input.Poll.Options[i]})
+		}
+	}
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceTopic, fields...)
+	if !ok {
+		return
+	}
+
+	// 检查投票设置
+	var poll *models.Poll
+	if input.Poll != nil {
+		var msg string
+		if poll, msg = buildPoll(input.Poll); msg != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	// 检查标签，保留标签只有管理员能添加
+	tags, msg := pickTags(c.DB, c.Filter, getResourceViewer(ctx, c.DB), nil, input.Tags)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// 发帖频率和重复内容检查放在其他校验之后，校验失败的请求不占用发帖次数
+	posting, ok := guardPosting(ctx, c.DB, c.Spam, userID.(uint), utils.SpamActionTopic, input.Title+"\n"+input.Content)
+	if !ok {
+		return
+	}
+
 	// 创建主题
-	topic := models.Topic{
-		Title:      input.Title,
-		Content:    input.Content,
-		CategoryID: input.CategoryID,
-		UserID:     userID.(uint),
-		ViewCount:  0,
-		ReplyCount: 0,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	topic, mentionedUsers := c.newTopic(userID.(uint), input.Title, input.Content, input.CategoryID, input.Type, posting, moderation)
+
+	if input.BountyPoints > 0 {
+		if input.BountyDays == 0 {
+			input.BountyDays = defaultBountyDays
+		}
+		deadline := time.Now().AddDate(0, 0, input.BountyDays)
+		topic.BountyPoints = input.BountyPoints
+		topic.BountyStatus = models.BountyStatusOpen
+		topic.BountyDeadline = &deadline
 	}
 
-	if result := c.DB.Create(&topic); result.Error != nil {
+	// 创建主题、标签、投票和托管悬赏积分在同一个事务中完成
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := createTopic(tx, &topic, tags); err != nil {
+			return err
+		}
+		if poll != nil {
+			poll.TopicID = topic.ID
+			if err := tx.Create(poll).Error; err != nil {
+				return err
+			}
+		}
+		if topic.BountyPoints > 0 {
+			return escrowBounty(tx, &topic)
+		}
+		return nil
+	})
+	if errors.Is(err, errInsufficientPoints) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "积分不足，无法设置悬赏"})
+		return
+	}
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建主题失败"})
 		return
 	}
 
-	// 添加积分记录（发帖奖励积分）
-	pointRecord := models.PointRecord{
-		UserID:      userID.(uint),
-		Points:      5, // 发帖奖励5积分
-		Type:        "post",
-		Description: "发表主题奖励",
-		CreatedAt:   time.Now(),
+	c.publishTopic(ctx, &topic, posting, moderation, mentionedUsers)
+	ctx.JSON(http.StatusCreated, topic)
+}
+
+// newTopic 根据通过校验的内容构造新主题，命中审核词的主题先隐藏，审核通过后显示
+func (c *ForumController) newTopic(userID uint, title, content string, categoryID uint, topicType string,
+	posting *postingCheck, moderation *moderationCheck) (models.Topic, map[string]uint) {
+	contentHTML, mentionedUsers := renderWithMentions(c.DB, content)
+	topic := models.Topic{
+		Title:         title,
+		Content:       content,
+		ContentHTML:   contentHTML,
+		RenderVersion: utils.MarkdownRenderVersion,
+		CategoryID:    categoryID,
+		UserID:        userID,
+		Type:          topicType,
+		ViewCount:     0,
+		ReplyCount:    0,
+		Shadowed:      posting.Shadowed,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	if moderation.Review {
+		topic.IsHidden = true
+		topic.HiddenReason = moderationHiddenReason
+		topic.HiddenAt = &topic.CreatedAt
+	}
+	return topic, mentionedUsers
+}
+
+// createTopic 在事务中保存主题及其标签，不存在的标签一并创建
+func createTopic(tx *gorm.DB, topic *models.Topic, tags []models.Tag) error {
+	if err := saveTags(tx, tags); err != nil {
+		return err
 	}
+	topic.Tags = tags
+	return tx.Create(topic).Error
+}
 
-	c.DB.Create(&pointRecord)
+// publishTopic 主题创建成功后的处理：奖励积分、记录审核、关联资源和附件、通知被@的用户和分类订阅者
+// 发表主题和创建资源讨论主题共用，完成后topic为带有作者、分类和标签的最新数据
+func (c *ForumController) publishTopic(ctx *gin.Context, topic *models.Topic, posting *postingCheck,
+	moderation *moderationCheck, mentionedUsers map[string]uint) {
+	posting.done(ctx.Request.Context())
+
+	// 被静默封禁的用户发帖不奖励积分
+	if !topic.Shadowed {
+		// 添加积分记录（发帖奖励积分）
+		pointRecord := models.PointRecord{
+			UserID:      topic.UserID,
+			Points:      5, // 发帖奖励5积分
+			Type:        "post",
+			Description: "发表主题奖励",
+			CreatedAt:   time.Now(),
+		}
 
-	// 更新用户积分
-	c.DB.Model(&models.User{}).Where("id = ?", userID).Update("points", gorm.Expr("points + ?", 5))
+		c.DB.Create(&pointRecord)
 
-	// 返回创建的主题
-	c.DB.Preload("User").Preload("Category").First(&topic, topic.ID)
-	ctx.JSON(http.StatusCreated, topic)
+		// 更新用户积分
+		c.DB.Model(&models.User{}).Where("id = ?", topic.UserID).Update("points", gorm.Expr("points + ?", 5))
+	}
+
+	moderation.record(c.DB, topic.ID)
+	syncResourceReferences(c.DB, topic.ID, topic.Content)
+
+	// 通知被@的用户，待审核和静默封禁的主题不通知
+	mentioned := syncMentions(c.DB, models.MentionSourceTopic, topic.ID, topic.UserID, mentionedUsers)
+	if len(mentioned) > 0 && !topic.IsHidden && !topic.Shadowed {
+		c.Notifier.NotifyMany(mentioned, mentionNotification(c.DB, topic.UserID, "主题《"+topic.Title+"》",
+			topic.Content, models.NotificationTargetTopic, topic.ID, &topic.ID))
+	}
+
+	// 关联内容中引用的附件，发布后删除草稿
+	bindAttachments(c.DB, topic.UserID, models.AttachmentTargetTopic, topic.ID, topic.Content)
+	discardDraft(c.DB, topic.UserID, models.DraftContextTopic, 0)
+
+	// 作者自动订阅自己的主题，并通知订阅了该分类的用户
+	if setting := watchSetting(c.DB, topic.UserID); setting.AutoWatchOwn {
+		autoWatchTopic(c.DB, topic.UserID, topic.ID, setting.DefaultDelivery)
+	}
+
+	c.DB.Preload("User").Preload("Category").Preload("Tags").First(topic, topic.ID)
+	if !topic.IsHidden && !topic.Shadowed {
+		c.notifyCategoryWatchers(topic, mentioned)
+	}
 }
 
 // UpdateTopic 更新主题
@@ -770,9 +774,11 @@ func (c *ForumController) UpdateTopic(ctx *gin.Context) {
 
 	// 绑定请求数据
 	var input struct {
-		Title      string `json:"title"`
-		Content    string `json:"content"`
-		CategoryID uint   `json:"category_id"`
+		Title      string    `json:"title"`
+		Content    string    `json:"content"`
+		CategoryID uint      `json:"category_id"`
+		Reason     string    `json:"reason" binding:"max=200"` // 编辑原因，记录在编辑历史中
+		Tags       *[]string `json:"tags"`                     // 不传时保持原有标签
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -780,17 +786,33 @@ func (c *ForumController) UpdateTopic(ctx *gin.Context) {
 		return
 	}
 
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceTopic,
+		textField{"title", &input.Title}, textField{"content", &input.Content})
+	if !ok {
+		return
+	}
+
 	// 更新主题
 	updates := map[string]interface{}{
 		"updated_at": time.Now(),
 	}
 
+	// 编辑后命中审核词的主题隐藏，审核通过后恢复显示
+	if moderation.Review && !topic.IsHidden {
+		updates["is_hidden"] = true
+		updates["hidden_reason"] = moderationHiddenReason
+		updates["hidden_at"] = time.Now()
+	}
+
 	if input.Title != "" {
 		updates["title"] = input.Title
 	}
 
+	var mentionedUsers map[string]uint
 	if input.Content != "" {
 		updates["content"] = input.Content
+		updates["content_html"], mentionedUsers = renderWithMentions(c.DB, input.Content)
+		updates["render_version"] = utils.MarkdownRenderVersion
 	}
 
 	if input.CategoryID != 0 {
@@ -803,19 +825,125 @@ func (c *ForumController) UpdateTopic(ctx *gin.Context) {
 		updates["category_id"] = input.CategoryID
 	}
 
+	// 修改标签，作者不能移除管理员添加的保留标签
+	var tags []models.Tag
+	if input.Tags != nil {
+		var current []models.Tag
+		c.DB.Model(&topic).Association("Tags").Find(&current)
+		var msg string
+		if tags, msg = pickTags(c.DB, c.Filter, getResourceViewer(ctx, c.DB), current, *input.Tags); msg != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	// 标题或内容有变化时保存新版本，只修改分类和标签不算编辑
+	edit := models.Revision{
+		TargetType: models.RevisionTargetTopic,
+		TargetID:   topic.ID,
+		Title:      topic.Title,
+		Content:    topic.Content,
+		EditorID:   userID.(uint),
+		Reason:     input.Reason,
+		CreatedAt:  time.Now(),
+	}
+	if input.Title != "" {
+		edit.Title = input.Title
+	}
+	if input.Content != "" {
+		edit.Content = input.Content
+	}
+	edited := edit.Title != topic.Title || edit.Content != topic.Content
+	if edited {
+		updates["is_edited"] = true
+		updates["edited_at"] = edit.CreatedAt
+	}
+
 	// 保存更新
-	if result := c.DB.Model(&topic).Updates(updates); result.Error != nil {
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if edited {
+			if err := saveRevision(tx, topicRevision(&topic), &edit); err != nil {
+				return err
+			}
+		}
+		if input.Tags != nil {
+			if err := replaceTags(tx, &topic, tags); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&topic).Updates(updates).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新主题失败"})
 		return
 	}
 
 	// 重新查询主题以获取最新信息
-	c.DB.Preload("User").Preload("Category").First(&topic, id)
+	c.DB.Preload("User").Preload("Category").Preload("Tags").First(&topic, id)
+	bindAttachments(c.DB, topic.UserID, models.AttachmentTargetTopic, topic.ID, topic.Content)
+	moderation.record(c.DB, topic.ID)
+	if input.Content != "" {
+		syncResourceReferences(c.DB, topic.ID, topic.Content)
+	}
+
+	// 只通知本次编辑新@的用户，隐藏和静默的主题不通知
+	if input.Content != "" && !topic.IsHidden && !topic.Shadowed {
+		if mentioned := syncMentions(c.DB, models.MentionSourceTopic, topic.ID, topic.UserID, mentionedUsers); len(mentioned) > 0 {
+			c.Notifier.NotifyMany(mentioned, mentionNotification(c.DB, topic.UserID, "主题《"+topic.Title+"》",
+				topic.Content, models.NotificationTargetTopic, topic.ID, &topic.ID))
+		}
+	}
 
 	// 返回更新后的主题
 	ctx.JSON(http.StatusOK, topic)
 }
 
+// SetTopicTags 修改主题的标签，作者和管理员可以修改，不算作编辑
+// 管理员可以添加和移除保留标签，作者修改时原有的保留标签保持不变
+func (c *ForumController) SetTopicTags(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var topic models.Topic
+	if err := c.DB.Preload("Tags").First(&topic, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return
+	}
+
+	viewer := getResourceViewer(ctx, c.DB)
+	if topic.UserID != userID.(uint) && !viewer.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权修改此主题"})
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, msg := pickTags(c.DB, c.Filter, viewer, topic.Tags, input.Tags)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := c.DB.Transaction(func(tx *gorm.DB) error { return replaceTags(tx, &topic, tags) }); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "修改标签失败"})
+		return
+	}
+
+	result := []models.Tag{}
+	c.DB.Model(&topic).Association("Tags").Find(&result)
+	ctx.JSON(http.StatusOK, gin.H{"tags": result})
+}
+
 // DeleteTopic 删除主题
 func (c *ForumController) DeleteTopic(ctx *gin.Context) {
 	// 从上下文获取用户ID
@@ -845,15 +973,25 @@ func (c *ForumController) DeleteTopic(ctx *gin.Context) {
 		return
 	}
 
-	// 删除相关回复
-	c.DB.Where("topic_id = ?", id).Delete(&models.Reply{})
-
-	// 删除主题
-	if result := c.DB.Delete(&topic); result.Error != nil {
+	// 退回托管中的悬赏，然后删除相关回复和主题
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := refundBounty(tx, &topic); err != nil {
+			return err
+		}
+		if err := tx.Where("topic_id = ?", topic.ID).Delete(&models.Reply{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&topic).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除主题失败"})
 		return
 	}
 
+	removeTopicWatches(c.DB, topic.ID)
+	removeTopicAttachments(c.DB, c.Files, topic.ID)
+	unlinkDiscussionTopic(c.DB, topic.ID)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "主题已删除"})
 }
 
@@ -877,9 +1015,27 @@ func (c *ForumController) CreateReply(ctx *gin.Context) {
 		return
 	}
 
+	// 看不到的主题按不存在处理，锁定或隐藏的主题只有管理员可以回复
+	viewer := getResourceViewer(ctx, c.DB)
+	if !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return
+	}
+	if (topic.IsLocked || topic.IsHidden) && !viewer.IsAdmin {
+		if topic.IsHidden {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "主题已被隐藏，无法回复"})
+		} else {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "主题已锁定，无法回复"})
+		}
+		return
+	}
+
 	// 绑定请求数据
 	var input struct {
-		Content string `json:"content" binding:"required"`
+		Content       string `json:"content" binding:"required"`
+		ParentID      *uint  `json:"parent_id"`       // 回复某条回复
+		QuotedReplyID *uint  `json:"quoted_reply_id"` // 引用某条回复
+		QuoteContent  string `json:"quote_content"`   // 引用的片段，为空时引用整条回复
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -887,40 +1043,174 @@ func (c *ForumController) CreateReply(ctx *gin.Context) {
 		return
 	}
 
+	// 回复先发布，命中审核词时进入审核队列
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceReply,
+		textField{"content", &input.Content})
+	if !ok {
+		return
+	}
+
 	// 创建回复
+	contentHTML, mentionedUsers := renderWithMentions(c.DB, input.Content)
 	reply := models.Reply{
-		Content:   input.Content,
-		UserID:    userID.(uint),
-		TopicID:   topic.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Content:       input.Content,
+		ContentHTML:   contentHTML,
+		RenderVersion: utils.MarkdownRenderVersion,
+		UserID:        userID.(uint),
+		TopicID:       topic.ID,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	// 上级回复，访问者看不到的静默和待审核回复按不存在处理
+	var parent *models.Reply
+	if input.ParentID != nil {
+		var p models.Reply
+		if err := visiblePublished(c.DB.Where("topic_id = ?", topic.ID), "replies", viewer).First(&p, *input.ParentID).Error; err != nil || p.IsDeleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "回复的楼层不存在"})
+			return
+		}
+		replyToUserID := p.UserID
+		reply.ReplyToUserID = &replyToUserID
+
+		// 超过最大层数时挂到上级回复的同一层
+		if p.Depth+1 > models.MaxReplyDepth && p.ParentID != nil {
+			var gp models.Reply
+			if err := visiblePublished(c.DB, "replies", viewer).First(&gp, *p.ParentID).Error; err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "回复的楼层不存在"})
+				return
+			}
+			p = gp
+		}
+		parent = &p
+		reply.ParentID = &p.ID
+		reply.Depth = p.Depth + 1
 	}
 
-	if result := c.DB.Create(&reply); result.Error != nil {
+	// 引用
+	if input.QuotedReplyID != nil {
+		var quoted models.Reply
+		if err := visiblePublished(c.DB.Where("topic_id = ?", topic.ID), "replies", viewer).First(&quoted, *input.QuotedReplyID).Error; err != nil || quoted.IsDeleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "引用的回复不存在"})
+			return
+		}
+		quote, msg := resolveQuote(&quoted, input.QuoteContent)
+		if msg != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		reply.QuotedReplyID = &quoted.ID
+		reply.QuotedUserID = &quoted.UserID
+		reply.QuoteContent = quote
+	}
+
+	posting, ok := guardPosting(ctx, c.DB, c.Spam, userID.(uint), utils.SpamActionReply, reply.Content)
+	if !ok {
+		return
+	}
+	reply.Shadowed = posting.Shadowed
+	// 命中审核词的回复审核通过后才发布
+	reply.Pending = moderation.Review
+	reply.Unpublished = moderation.Review
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&reply).Error; err != nil {
+			return err
+		}
+
+		// 路径需要用到自身ID，创建后再写入
+		path := fmt.Sprintf("%d/", reply.ID)
+		if parent != nil {
+			path = parent.Path + path
+		}
+		if err := tx.Model(&reply).Update("path", path).Error; err != nil {
+			return err
+		}
+
+		if parent != nil {
+			if err := tx.Model(&models.Reply{}).Where("id = ?", parent.ID).
+				Update("child_count", gorm.Expr("child_count + 1")).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Reply{}).Where("id IN ?", replyAncestorIDs(path)).
+				Update("descendant_count", gorm.Expr("descendant_count + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		// 静默和待审核的回复不计入主题回复数，也不奖励积分
+		if !replyCounted(&reply) {
+			return nil
+		}
+		if err := payReplyPoints(tx, &reply); err != nil {
+			return err
+		}
+		return tx.Model(&topic).Update("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建回复失败"})
 		return
 	}
+	posting.done(ctx.Request.Context())
 
-	// 更新主题回复数
-	c.DB.Model(&topic).Update("reply_count", gorm.Expr("reply_count + 1"))
+	// 返回创建的回复
+	preloadReplyUsers(c.DB).First(&reply, reply.ID)
+	bindAttachments(c.DB, reply.UserID, models.AttachmentTargetReply, reply.ID, reply.Content)
+	discardDraft(c.DB, reply.UserID, models.DraftContextReply, topic.ID)
+	moderation.record(c.DB, reply.ID)
+
+	// 静默封禁期间和待审核的回复只有作者自己能看到，不通知其他用户
+	if !reply.Shadowed && !reply.Pending {
+		c.publishReply(&topic, &reply, mentionedUsers)
+	}
+	ctx.JSON(http.StatusCreated, reply)
+}
+
+// replyCounted 回复是否计入主题回复数，静默、待审核和已删除的回复不计入
+func replyCounted(reply *models.Reply) bool {
+	return !reply.Shadowed && !reply.Pending && !reply.IsDeleted
+}
 
-	// 添加积分记录（回复奖励积分）
-	pointRecord := models.PointRecord{
-		UserID:      userID.(uint),
-		Points:      2, // 回复奖励2积分
+// payReplyPoints 发放回复奖励积分并记录在回复上，删除回复时扣回
+// 回复自己的楼层不奖励积分，避免在楼中楼里刷积分
+func payReplyPoints(tx *gorm.DB, reply *models.Reply) error {
+	if reply.RewardPoints > 0 || (reply.ReplyToUserID != nil && *reply.ReplyToUserID == reply.UserID) {
+		return nil
+	}
+
+	points := 2 // 回复奖励2积分
+	if err := tx.Model(reply).UpdateColumn("reward_points", points).Error; err != nil {
+		return err
+	}
+	reply.RewardPoints = points
+	if err := tx.Create(&models.PointRecord{
+		UserID:      reply.UserID,
+		Points:      points,
 		Type:        "reply",
 		Description: "回复主题奖励",
 		CreatedAt:   time.Now(),
+	}).Error; err != nil {
+		return err
 	}
+	return tx.Model(&models.User{}).Where("id = ?", reply.UserID).Update("points", gorm.Expr("points + ?", points)).Error
+}
 
-	c.DB.Create(&pointRecord)
-
-	// 更新用户积分
-	c.DB.Model(&models.User{}).Where("id = ?", userID).Update("points", gorm.Expr("points + ?", 2))
+// publishReply 发布回复：通知被@、被回复和被引用的用户以及订阅者，并推送给正在浏览主题的用户
+// 回复需要预加载用户信息，待审核的回复在审核通过后调用
+func (c *ForumController) publishReply(topic *models.Topic, reply *models.Reply, mentionedUsers map[string]uint) {
+	// 被@的用户收到提及通知，不再重复收到回复通知
+	mentioned := syncMentions(c.DB, models.MentionSourceReply, reply.ID, reply.UserID, mentionedUsers)
+	if len(mentioned) > 0 {
+		c.Notifier.NotifyMany(mentioned, mentionNotification(c.DB, reply.UserID, "主题《"+topic.Title+"》的回复",
+			reply.Content, models.NotificationTargetReply, reply.ID, &topic.ID))
+	}
+	c.notifyNewReply(topic, reply, mentioned)
 
-	// 返回创建的回复
-	c.DB.Preload("User").First(&reply, reply.ID)
-	ctx.JSON(http.StatusCreated, reply)
+	// 回复者自动订阅主题，在发送通知之后订阅，不会收到自己回复的通知
+	if setting := watchSetting(c.DB, reply.UserID); setting.AutoWatchReplied {
+		autoWatchTopic(c.DB, reply.UserID, topic.ID, setting.DefaultDelivery)
+	}
+	c.Hub.Publish(utils.TopicChannel(topic.ID), utils.EventReplyCreated, reply)
 }
 
 // UpdateReply 更新回复
@@ -938,7 +1228,7 @@ func (c *ForumController) UpdateReply(ctx *gin.Context) {
 	// 查询回复
 	var reply models.Reply
 	result := c.DB.First(&reply, id)
-	if result.Error != nil {
+	if result.Error != nil || reply.IsDeleted {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
 		return
 	}
@@ -952,6 +1242,7 @@ func (c *ForumController) UpdateReply(ctx *gin.Context) {
 	// 绑定请求数据
 	var input struct {
 		Content string `json:"content" binding:"required"`
+		Reason  string `json:"reason" binding:"max=200"` // 编辑原因，记录在编辑历史中
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -959,20 +1250,85 @@ func (c *ForumController) UpdateReply(ctx *gin.Context) {
 		return
 	}
 
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceReply,
+		textField{"content", &input.Content})
+	if !ok {
+		return
+	}
+
 	// 更新回复
+	now := time.Now()
+	contentHTML, mentionedUsers := renderWithMentions(c.DB, input.Content)
 	updates := map[string]interface{}{
-		"content":    input.Content,
-		"updated_at": time.Now(),
+		"content":        input.Content,
+		"content_html":   contentHTML,
+		"render_version": utils.MarkdownRenderVersion,
+		"updated_at":     now,
+	}
+
+	// 内容有变化时保存新版本
+	edited := input.Content != reply.Content
+	if edited {
+		updates["is_edited"] = true
+		updates["edited_at"] = now
+	}
+
+	// 修改后命中审核词的回复重新进入待审核，审核通过前其他用户看不到
+	held := moderation.Review && !reply.Pending
+	wasPublic := !reply.Shadowed && !reply.Pending
+	if held {
+		updates["pending"] = true
 	}
 
 	// 保存更新
-	if result := c.DB.Model(&reply).Updates(updates); result.Error != nil {
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if held && replyCounted(&reply) {
+			if err := tx.Model(&models.Topic{}).Where("id = ?", reply.TopicID).
+				Update("reply_count", gorm.Expr("reply_count - 1")).Error; err != nil {
+				return err
+			}
+		}
+		if edited {
+			if err := saveRevision(tx, replyRevision(&reply), &models.Revision{
+				TargetType: models.RevisionTargetReply,
+				TargetID:   reply.ID,
+				Content:    input.Content,
+				EditorID:   reply.UserID,
+				Reason:     input.Reason,
+				CreatedAt:  now,
+			}); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&reply).Updates(updates).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新回复失败"})
 		return
 	}
 
 	// 重新查询回复以获取最新信息
-	c.DB.Preload("User").First(&reply, id)
+	preloadReplyUsers(c.DB).First(&reply, id)
+	bindAttachments(c.DB, reply.UserID, models.AttachmentTargetReply, reply.ID, reply.Content)
+	moderation.record(c.DB, reply.ID)
+
+	// 静默封禁期间和待审核的回复只有作者自己能看到，不推送也不通知
+	if reply.Shadowed || reply.Pending {
+		if held && wasPublic {
+			publishReplyDeleted(c.Hub, &reply, false)
+		}
+		ctx.JSON(http.StatusOK, reply)
+		return
+	}
+	c.Hub.Publish(utils.TopicChannel(reply.TopicID), utils.EventReplyUpdated, reply)
+
+	// 只通知本次编辑新@的用户
+	if mentioned := syncMentions(c.DB, models.MentionSourceReply, reply.ID, reply.UserID, mentionedUsers); len(mentioned) > 0 {
+		var topic models.Topic
+		c.DB.Select("id", "title").First(&topic, reply.TopicID)
+		c.Notifier.NotifyMany(mentioned, mentionNotification(c.DB, reply.UserID, "主题《"+topic.Title+"》的回复",
+			reply.Content, models.NotificationTargetReply, reply.ID, &topic.ID))
+	}
 
 	// 返回更新后的回复
 	ctx.JSON(http.StatusOK, reply)
@@ -993,7 +1349,7 @@ func (c *ForumController) DeleteReply(ctx *gin.Context) {
 	// 查询回复
 	var reply models.Reply
 	result := c.DB.First(&reply, id)
-	if result.Error != nil {
+	if result.Error != nil || reply.IsDeleted {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
 		return
 	}
@@ -1007,22 +1363,131 @@ func (c *ForumController) DeleteReply(ctx *gin.Context) {
 		return
 	}
 
-	// 获取主题信息，用于更新回复数
-	var topic models.Topic
-	c.DB.First(&topic, reply.TopicID)
+	// 被采纳的回答只有管理员可以删除
+	if reply.IsAccepted && user.Role != "admin" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "已被采纳的回答不能删除"})
+		return
+	}
 
-	// 删除回复
-	if result := c.DB.Delete(&reply); result.Error != nil {
+	// 删除回复，有子回复时保留占位
+	if err := c.DB.Transaction(func(tx *gorm.DB) error {
+		return removeReply(tx, &reply, "回复被删除扣除积分")
+	}); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除回复失败"})
 		return
 	}
 
-	// 更新主题回复数
-	c.DB.Model(&topic).Update("reply_count", gorm.Expr("reply_count - 1"))
+	removeReplyAttachments(c.DB, c.Files, reply.ID)
+	publishReplyDeleted(c.Hub, &reply, reply.ChildCount > 0)
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "回复已删除"})
 }
 
+// removeReply 删除回复，有子回复时保留占位
+// 同时更新主题回复数，删除的是已采纳的答案时问题恢复为未解决，并扣回发表回复时获得的积分，子回复作者的积分不受影响
+func removeReply(tx *gorm.DB, reply *models.Reply, description string) error {
+	counted := replyCounted(reply)
+	if err := deleteReplyNode(tx, reply); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	if counted {
+		updates["reply_count"] = gorm.Expr("reply_count - 1")
+	}
+	if reply.IsAccepted {
+		updates["is_solved"] = false
+		updates["accepted_reply_id"] = nil
+		updates["solved_at"] = nil
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&models.Topic{}).Where("id = ?", reply.TopicID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if reply.RewardPoints <= 0 {
+		return nil
+	}
+	if err := tx.Create(&models.PointRecord{
+		UserID:      reply.UserID,
+		Points:      -reply.RewardPoints,
+		Type:        "reply",
+		Description: description,
+		CreatedAt:   time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", reply.UserID).
+		Update("points", gorm.Expr("points - ?", reply.RewardPoints)).Error
+}
+
+// publishReplyDeleted 通知正在浏览主题的用户回复已被删除或不再可见
+// tombstone为true时回复只清空内容，保留占位
+func publishReplyDeleted(hub *utils.RealtimeHub, reply *models.Reply, tombstone bool) {
+	hub.Publish(utils.TopicChannel(reply.TopicID), utils.EventReplyDeleted, gin.H{
+		"id":        reply.ID,
+		"parent_id": reply.ParentID,
+		"tombstone": tombstone,
+	})
+}
+
+// approveReply 审核通过待审核的回复：计入主题回复数并发放积分，发布由调用方在事务提交后进行
+func approveReply(tx *gorm.DB, reply *models.Reply) error {
+	if err := tx.Model(reply).UpdateColumn("pending", false).Error; err != nil {
+		return err
+	}
+	reply.Pending = false
+	if !replyCounted(reply) {
+		return nil
+	}
+	if err := payReplyPoints(tx, reply); err != nil {
+		return err
+	}
+	return tx.Model(&models.Topic{}).Where("id = ?", reply.TopicID).
+		Update("reply_count", gorm.Expr("reply_count + 1")).Error
+}
+
+// publishApprovedReply 发布审核通过的回复
+// 创建时就待审核的回复按新回复发送通知；修改后重新审核的回复只通知新@的用户
+func (c *ForumController) publishApprovedReply(replyID uint) {
+	var reply models.Reply
+	if err := preloadReplyUsers(c.DB).First(&reply, replyID).Error; err != nil ||
+		reply.Pending || reply.Shadowed || reply.IsDeleted {
+		return
+	}
+	var topic models.Topic
+	if err := c.DB.First(&topic, reply.TopicID).Error; err != nil {
+		return
+	}
+
+	mentionedUsers := utils.ResolveMentions(c.DB, reply.Content)
+	if reply.Unpublished {
+		c.DB.Model(&reply).UpdateColumn("unpublished", false)
+		c.publishReply(&topic, &reply, mentionedUsers)
+		return
+	}
+
+	if mentioned := syncMentions(c.DB, models.MentionSourceReply, reply.ID, reply.UserID, mentionedUsers); len(mentioned) > 0 {
+		c.Notifier.NotifyMany(mentioned, mentionNotification(c.DB, reply.UserID, "主题《"+topic.Title+"》的回复",
+			reply.Content, models.NotificationTargetReply, reply.ID, &topic.ID))
+	}
+	// 重新审核时已经通知过浏览主题的用户回复不可见，这里按新回复推送
+	c.Hub.Publish(utils.TopicChannel(topic.ID), utils.EventReplyCreated, reply)
+}
+
+// favoriteOrder 收藏列表排序的ORDER BY子句，只接受固定的排序方式，其余按收藏时间倒序
+func favoriteOrder(sort string) string {
+	switch strings.ToLower(strings.TrimSpace(sort)) {
+	case "created_at asc", "oldest":
+		return "created_at ASC, id ASC"
+	case "sort_order", "sort_order asc":
+		return "sort_order ASC, id ASC"
+	default:
+		return "created_at DESC, id DESC"
+	}
+}
+
 // GetUserFavorites 获取用户收藏的帖子列表
 func (c *ForumController) GetUserFavorites(ctx *gin.Context) {
 	// 获取用户ID
@@ -1051,9 +1516,8 @@ func (c *ForumController) GetUserFavorites(ctx *gin.Context) {
 	offset := (page - 1) * limit
 
 	// 查询用户收藏的帖子
-	var favorites []models.UserTopicFavorite
-	query := c.DB.Preload("Topic").Preload("Topic.User").Preload("Topic.Category")
-	query = query.Where("user_id = ?", userID).Order(sort).Offset(offset).Limit(limit)
+	var favorites []models.FavoriteItem
+	query := c.DB.Where("user_id = ? AND item_type = ?", userID, models.FavoriteItemTopic).Order(favoriteOrder(sort)).Offset(offset).Limit(limit)
 
 	if err := query.Find(&favorites).Error; err != nil {
 		log.Printf("获取用户收藏失败: %v, 用户ID: %v, 查询参数: page=%d, limit=%d, sort=%s", err, userID, page, limit, sort)
@@ -1063,15 +1527,17 @@ func (c *ForumController) GetUserFavorites(ctx *gin.Context) {
 
 	// 获取总数
 	var total int64
-	c.DB.Model(&models.UserTopicFavorite{}).Where("user_id = ?", userID).Count(&total)
+	c.DB.Model(&models.FavoriteItem{}).Where("user_id = ? AND item_type = ?", userID, models.FavoriteItemTopic).Count(&total)
 
 	// 格式化返回数据
+	viewer := getResourceViewer(ctx, c.DB)
 	var topics []gin.H
 	for _, favorite := range favorites {
 		// 获取主题信息
 		var topic models.Topic
-		if err := c.DB.Preload("User").Preload("Category").First(&topic, favorite.TopicID).Error; err != nil {
-			continue // 跳过已删除的主题
+		if err := c.DB.Preload("User").Preload("Category").First(&topic, favorite.ItemID).Error; err != nil ||
+			!canViewTopic(&topic, viewer) {
+			continue // 跳过已删除或被隐藏的主题
 		}
 
 		topics = append(topics, gin.H{
@@ -1082,6 +1548,8 @@ func (c *ForumController) GetUserFavorites(ctx *gin.Context) {
 			"user":        topic.User,
 			"view_count":  topic.ViewCount,
 			"reply_count": topic.ReplyCount,
+			"folder_id":   favorite.FolderID,
+			"note":        favorite.Note,
 			"created_at":  favorite.CreatedAt,
 		})
 	}
@@ -1096,3 +1564,17 @@ func (c *ForumController) GetUserFavorites(ctx *gin.Context) {
 		},
 	})
 }
+
+// PreviewMarkdown 预览Markdown渲染结果，与保存后的content_html一致
+func (c *ForumController) PreviewMarkdown(ctx *gin.Context) {
+	var input struct {
+		Content string `json:"content" binding:"max=65535"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"content_html": utils.RenderMarkdown(input.Content)})
+}
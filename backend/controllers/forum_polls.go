@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// maxPollOptions 投票最多的选项数
+const maxPollOptions = 20
+
+var (
+	errPollVoted  = errors.New("已经投过票")
+	errPollClosed = errors.New("投票已截止")
+)
+
+// pollInput 发布主题时附带的投票
+type pollInput struct {
+	Question   string     `json:"question" binding:"max=200"`
+	Options    []string   `json:"options" binding:"required,min=2,dive,max=100"`
+	Multiple   bool       `json:"multiple"`
+	MaxChoices int        `json:"max_choices" binding:"min=0"`
+	Anonymous  bool       `json:"anonymous"`
+	ClosesAt   *time.Time `json:"closes_at"`
+}
+
+// buildPoll 校验投票设置并生成投票，设置有误时返回错误信息
+func buildPoll(input *pollInput) (*models.Poll, string) {
+	seen := make(map[string]bool)
+	var options []models.PollOption
+	for _, content := range input.Options {
+		content = strings.TrimSpace(content)
+		if content == "" {
+			continue
+		}
+		if seen[content] {
+			return nil, "投票选项不能重复"
+		}
+		seen[content] = true
+		options = append(options, models.PollOption{Content: content, SortOrder: len(options)})
+	}
+
+	if len(options) < 2 {
+		return nil, "投票至少需要2个选项"
+	}
+	if len(options) > maxPollOptions {
+		return nil, fmt.Sprintf("投票最多%d个选项", maxPollOptions)
+	}
+	if input.Multiple && input.MaxChoices > len(options) {
+		return nil, "最多选择数不能超过选项数"
+	}
+	if input.ClosesAt != nil && !input.ClosesAt.After(time.Now()) {
+		return nil, "截止时间必须晚于当前时间"
+	}
+
+	poll := &models.Poll{
+		Question:  strings.TrimSpace(input.Question),
+		Multiple:  input.Multiple,
+		Anonymous: input.Anonymous,
+		ClosesAt:  input.ClosesAt,
+		Options:   options,
+	}
+	// 单选投票不需要最多选择数
+	if input.Multiple {
+		poll.MaxChoices = input.MaxChoices
+	}
+	return poll, ""
+}
+
+// loadTopicPoll 查询主题的投票及其选项，没有投票时返回nil
+func loadTopicPoll(db *gorm.DB, topicID uint) *models.Poll {
+	var poll models.Poll
+	if db.Preload("Options", func(tx *gorm.DB) *gorm.DB { return tx.Order("sort_order ASC") }).
+		Where("topic_id = ?", topicID).
+		Limit(1).
+		Find(&poll).RowsAffected == 0 {
+		return nil
+	}
+	return &poll
+}
+
+// pollResults 投票的结果，viewerID不为0时包含该用户的选择
+// 非匿名投票列出每个选项的投票人
+func pollResults(db *gorm.DB, poll *models.Poll, viewerID uint) gin.H {
+	myChoices := []uint{}
+	if viewerID != 0 {
+		db.Model(&models.PollChoice{}).Where("poll_id = ? AND user_id = ?", poll.ID, viewerID).Pluck("option_id", &myChoices)
+	}
+
+	voters := make(map[uint][]gin.H)
+	if !poll.Anonymous {
+		var choices []models.PollChoice
+		db.Preload("User").Where("poll_id = ?", poll.ID).Order("id ASC").Find(&choices)
+		for _, choice := range choices {
+			if choice.User == nil {
+				continue
+			}
+			voters[choice.OptionID] = append(voters[choice.OptionID], gin.H{
+				"id":       choice.User.ID,
+				"username": choice.User.Username,
+				"avatar":   choice.User.Avatar,
+			})
+		}
+	}
+
+	options := make([]gin.H, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		// 百分比按投票人数计算，多选时各项之和可能超过100
+		percent := 0.0
+		if poll.VoterCount > 0 {
+			percent = math.Round(float64(option.VoteCount)*1000/float64(poll.VoterCount)) / 10
+		}
+
+		item := gin.H{
+			"id":         option.ID,
+			"content":    option.Content,
+			"vote_count": option.VoteCount,
+			"percent":    percent,
+		}
+		if !poll.Anonymous {
+			list := voters[option.ID]
+			if list == nil {
+				list = []gin.H{}
+			}
+			item["voters"] = list
+		}
+		options = append(options, item)
+	}
+
+	return gin.H{
+		"id":          poll.ID,
+		"topic_id":    poll.TopicID,
+		"question":    poll.Question,
+		"multiple":    poll.Multiple,
+		"max_choices": poll.MaxChoices,
+		"anonymous":   poll.Anonymous,
+		"closes_at":   poll.ClosesAt,
+		"is_closed":   poll.IsClosed(time.Now()),
+		"voter_count": poll.VoterCount,
+		"options":     options,
+		"has_voted":   len(myChoices) > 0,
+		"my_choices":  myChoices,
+	}
+}
+
+// topicPollResults 主题的投票结果，没有投票时返回nil
+func topicPollResults(db *gorm.DB, topicID, viewerID uint) gin.H {
+	poll := loadTopicPoll(db, topicID)
+	if poll == nil {
+		return nil
+	}
+	return pollResults(db, poll, viewerID)
+}
+
+// loadPollTopic 查询投票所在的主题，隐藏和静默的主题只有作者和管理员可以访问，失败时直接写入错误响应
+func (c *ForumController) loadPollTopic(ctx *gin.Context, viewer resourceViewer) (*models.Topic, *models.Poll, bool) {
+	var topic models.Topic
+	if err := c.DB.First(&topic, ctx.Param("id")).Error; err != nil || !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return nil, nil, false
+	}
+
+	poll := loadTopicPoll(c.DB, topic.ID)
+	if poll == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "该主题没有投票"})
+		return nil, nil, false
+	}
+	return &topic, poll, true
+}
+
+// GetPoll 获取主题的投票结果
+func (c *ForumController) GetPoll(ctx *gin.Context) {
+	viewer := getResourceViewer(ctx, c.DB)
+	_, poll, ok := c.loadPollTopic(ctx, viewer)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pollResults(c.DB, poll, viewer.UserID))
+}
+
+// VotePoll 在主题的投票中投票，每人只能投一次，截止后不能再投
+func (c *ForumController) VotePoll(ctx *gin.Context) {
+	viewer := getResourceViewer(ctx, c.DB)
+	if viewer.UserID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	topic, poll, ok := c.loadPollTopic(ctx, viewer)
+	if !ok {
+		return
+	}
+	if topic.IsLocked && !viewer.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "主题已锁定，无法投票"})
+		return
+	}
+	if poll.IsClosed(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "投票已截止"})
+		return
+	}
+
+	var input struct {
+		OptionIDs []uint `json:"option_ids" binding:"required,min=1"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	valid := make(map[uint]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+	chosen := make(map[uint]bool)
+	var optionIDs []uint
+	for _, id := range input.OptionIDs {
+		if !valid[id] {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "选项不存在"})
+			return
+		}
+		if !chosen[id] {
+			chosen[id] = true
+			optionIDs = append(optionIDs, id)
+		}
+	}
+
+	if !poll.Multiple && len(optionIDs) > 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "单选投票只能选择一项"})
+		return
+	}
+	if poll.MaxChoices > 0 && len(optionIDs) > poll.MaxChoices {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多只能选择%d项", poll.MaxChoices)})
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		// 同时截止的检查和计数在同一条语句中完成，截止后的并发请求不会被计入
+		result := tx.Model(&models.Poll{}).
+			Where("id = ? AND (closes_at IS NULL OR closes_at > ?)", poll.ID, time.Now()).
+			UpdateColumn("voter_count", gorm.Expr("voter_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPollClosed
+		}
+
+		// 唯一索引保证每人只有一张选票
+		ballot := models.PollBallot{PollID: poll.ID, UserID: viewer.UserID, CreatedAt: time.Now()}
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ballot)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPollVoted
+		}
+
+		choices := make([]models.PollChoice, 0, len(optionIDs))
+		for _, id := range optionIDs {
+			choices = append(choices, models.PollChoice{BallotID: ballot.ID, PollID: poll.ID, OptionID: id, UserID: viewer.UserID})
+		}
+		if err := tx.Create(&choices).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PollOption{}).Where("id IN ?", optionIDs).
+			UpdateColumn("vote_count", gorm.Expr("vote_count + 1")).Error
+	})
+	switch {
+	case errors.Is(err, errPollVoted):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "你已经投过票了"})
+		return
+	case errors.Is(err, errPollClosed):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "投票已截止"})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "投票失败"})
+		return
+	}
+
+	poll = loadTopicPoll(c.DB, topic.ID)
+	c.Hub.Publish(utils.TopicChannel(topic.ID), utils.EventPollResults, pollResults(c.DB, poll, 0))
+	ctx.JSON(http.StatusOK, pollResults(c.DB, poll, viewer.UserID))
+}
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+const (
+	// maxBountyPoints 单个问题的最高悬赏积分
+	maxBountyPoints = 1000
+	// defaultBountyDays 未指定期限时悬赏的有效天数
+	defaultBountyDays = 7
+	// maxBountyDays 悬赏的最长有效天数
+	maxBountyDays = 30
+	// bountySettleInterval 检查到期悬赏的间隔
+	bountySettleInterval = time.Minute
+)
+
+var (
+	errInsufficientPoints = errors.New("积分不足")
+	// errBountySettled 悬赏已经结算，并发的采纳或到期结算只有一个生效
+	errBountySettled = errors.New("悬赏已结算")
+)
+
+// escrowBounty 从提问者积分中扣除悬赏积分托管，积分不足时返回errInsufficientPoints
+func escrowBounty(tx *gorm.DB, topic *models.Topic) error {
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND points >= ?", topic.UserID, topic.BountyPoints).
+		Update("points", gorm.Expr("points - ?", topic.BountyPoints))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInsufficientPoints
+	}
+
+	return tx.Create(&models.PointRecord{
+		UserID:      topic.UserID,
+		Points:      -topic.BountyPoints,
+		Type:        "bounty",
+		Description: fmt.Sprintf("问题《%s》悬赏托管", topic.Title),
+		CreatedAt:   time.Now(),
+	}).Error
+}
+
+// transferBounty 将托管的悬赏积分发放给userID并设置悬赏状态
+// 悬赏不是托管状态时返回errBountySettled
+func transferBounty(tx *gorm.DB, topic *models.Topic, userID uint, status, description string) error {
+	result := tx.Model(&models.Topic{}).
+		Where("id = ? AND bounty_status = ?", topic.ID, models.BountyStatusOpen).
+		Update("bounty_status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errBountySettled
+	}
+	topic.BountyStatus = status
+
+	if err := tx.Create(&models.PointRecord{
+		UserID:      userID,
+		Points:      topic.BountyPoints,
+		Type:        "bounty",
+		Description: description,
+		CreatedAt:   time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).
+		Update("points", gorm.Expr("points + ?", topic.BountyPoints)).Error
+}
+
+// refundBounty 主题被删除时退回托管的悬赏积分，没有托管中的悬赏时不做处理
+// 需要在删除主题的事务中、软删除主题之前调用，否则更新悬赏状态时匹配不到主题
+func refundBounty(tx *gorm.DB, topic *models.Topic) error {
+	if topic.BountyStatus != models.BountyStatusOpen {
+		return nil
+	}
+	err := transferBounty(tx, topic, topic.UserID, models.BountyStatusRefunded,
+		fmt.Sprintf("问题《%s》被删除，退回悬赏", topic.Title))
+	if errors.Is(err, errBountySettled) {
+		return nil
+	}
+	return err
+}
+
+// markAnswerAccepted 将回复标记为问题的答案，问题已经解决时返回errBountySettled
+func markAnswerAccepted(tx *gorm.DB, topic *models.Topic, reply *models.Reply) error {
+	now := time.Now()
+	result := tx.Model(&models.Topic{}).
+		Where("id = ? AND is_solved = ?", topic.ID, false).
+		Updates(map[string]interface{}{
+			"is_solved":         true,
+			"accepted_reply_id": reply.ID,
+			"solved_at":         now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errBountySettled
+	}
+
+	return tx.Model(reply).Update("is_accepted", true).Error
+}
+
+// AcceptAnswer 提问者采纳回复为答案，有托管中的悬赏时发放给回答者
+func (c *ForumController) AcceptAnswer(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var topic models.Topic
+	if err := c.DB.First(&topic, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return
+	}
+
+	if topic.Type != models.TopicTypeQuestion {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "只有问答主题可以采纳答案"})
+		return
+	}
+	if topic.UserID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "只有提问者可以采纳答案"})
+		return
+	}
+	if topic.IsSolved {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "问题已采纳答案"})
+		return
+	}
+
+	var input struct {
+		ReplyID uint `json:"reply_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reply models.Reply
+	if err := c.DB.Where("topic_id = ?", topic.ID).First(&reply, input.ReplyID).Error; err != nil ||
+		reply.IsDeleted || reply.Shadowed || reply.Pending {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+		return
+	}
+	if reply.UserID == topic.UserID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不能采纳自己的回复"})
+		return
+	}
+
+	paid := false
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := markAnswerAccepted(tx, &topic, &reply); err != nil {
+			return err
+		}
+
+		// 悬赏已经到期退回时仍然可以采纳，只是不再发放积分
+		if topic.BountyStatus != models.BountyStatusOpen {
+			return nil
+		}
+		err := transferBounty(tx, &topic, reply.UserID, models.BountyStatusPaid,
+			fmt.Sprintf("回答被问题《%s》采纳，获得悬赏", topic.Title))
+		if errors.Is(err, errBountySettled) {
+			return nil
+		}
+		paid = err == nil
+		return err
+	})
+	if errors.Is(err, errBountySettled) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "问题已采纳答案"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "采纳答案失败"})
+		return
+	}
+
+	title := fmt.Sprintf("你在《%s》中的回答被采纳", topic.Title)
+	if paid {
+		title += fmt.Sprintf("，获得%d积分悬赏", topic.BountyPoints)
+	}
+	c.Notifier.Notify(&models.Notification{
+		UserID:     reply.UserID,
+		Type:       models.NotificationAnswer,
+		Title:      title,
+		Content:    reply.Content,
+		ActorID:    &topic.UserID,
+		TargetType: models.NotificationTargetReply,
+		TargetID:   reply.ID,
+		TopicID:    &topic.ID,
+	})
+
+	c.DB.Preload("User").Preload("Category").First(&topic, topic.ID)
+	ctx.JSON(http.StatusOK, gin.H{"topic": topic, "reply_id": reply.ID, "bounty_paid": paid})
+}
+
+// settleBounties 定时结算到期的悬赏
+func (c *ForumController) settleBounties() {
+	ticker := time.NewTicker(bountySettleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.settleExpiredBounties()
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// settleExpiredBounties 结算所有到期仍未采纳的悬赏
+func (c *ForumController) settleExpiredBounties() {
+	var topics []models.Topic
+	c.DB.Where("bounty_status = ? AND bounty_deadline <= ?", models.BountyStatusOpen, time.Now()).
+		Limit(100).
+		Find(&topics)
+
+	for i := range topics {
+		c.settleBounty(&topics[i])
+	}
+}
+
+// settleBounty 结算到期的悬赏：有得分为正的回答时发放给得分最高的回答并标记为答案，否则退回提问者
+func (c *ForumController) settleBounty(topic *models.Topic) {
+	var answer models.Reply
+	found := c.DB.Where("topic_id = ? AND user_id <> ? AND is_deleted = ? AND shadowed = ? AND pending = ? AND vote_score > 0",
+		topic.ID, topic.UserID, false, false, false).
+		Order("vote_score DESC").
+		Order("created_at ASC").
+		Limit(1).
+		Find(&answer).RowsAffected > 0
+
+	accepted := found
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if accepted {
+			err := markAnswerAccepted(tx, topic, &answer)
+			if errors.Is(err, errBountySettled) {
+				// 问题已经采纳了答案但悬赏仍在托管中，退回提问者，避免每次检查都重试
+				accepted = false
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if !accepted {
+			return transferBounty(tx, topic, topic.UserID, models.BountyStatusRefunded,
+				fmt.Sprintf("问题《%s》悬赏到期，退回悬赏", topic.Title))
+		}
+		return transferBounty(tx, topic, answer.UserID, models.BountyStatusAwarded,
+			fmt.Sprintf("回答在问题《%s》中得分最高，获得悬赏", topic.Title))
+	})
+	if errors.Is(err, errBountySettled) {
+		return
+	}
+	if err != nil {
+		log.Printf("结算主题%d的悬赏失败: %v", topic.ID, err)
+		return
+	}
+
+	if !accepted {
+		c.Notifier.Notify(&models.Notification{
+			UserID:     topic.UserID,
+			Type:       models.NotificationAnswer,
+			Title:      fmt.Sprintf("你的问题《%s》悬赏到期，%d积分已退回", topic.Title, topic.BountyPoints),
+			TargetType: models.NotificationTargetTopic,
+			TargetID:   topic.ID,
+			TopicID:    &topic.ID,
+		})
+		return
+	}
+
+	c.Notifier.Notify(&models.Notification{
+		UserID:     answer.UserID,
+		Type:       models.NotificationAnswer,
+		Title:      fmt.Sprintf("你在《%s》中的回答得分最高，获得%d积分悬赏", topic.Title, topic.BountyPoints),
+		Content:    answer.Content,
+		TargetType: models.NotificationTargetReply,
+		TargetID:   answer.ID,
+		TopicID:    &topic.ID,
+	})
+	c.Notifier.Notify(&models.Notification{
+		UserID:     topic.UserID,
+		Type:       models.NotificationAnswer,
+		Title:      fmt.Sprintf("你的问题《%s》悬赏到期，已自动采纳得分最高的回答", topic.Title),
+		TargetType: models.NotificationTargetReply,
+		TargetID:   answer.ID,
+		TopicID:    &topic.ID,
+	})
+}
@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// saveRevision 保存一次编辑产生的新版本，还没有任何版本时先把original保存为版本1
+func saveRevision(tx *gorm.DB, original, edit *models.Revision) error {
+	var latest models.Revision
+	if tx.Where("target_type = ? AND target_id = ?", edit.TargetType, edit.TargetID).
+		Order("version DESC").
+		Limit(1).
+		Find(&latest).RowsAffected == 0 {
+		original.Version = 1
+		if err := tx.Create(original).Error; err != nil {
+			return err
+		}
+		latest = *original
+	}
+
+	edit.Version = latest.Version + 1
+	return tx.Create(edit).Error
+}
+
+// topicRevision 主题当前内容的快照，作为第一次编辑前的原始版本
+func topicRevision(topic *models.Topic) *models.Revision {
+	return &models.Revision{
+		TargetType: models.RevisionTargetTopic,
+		TargetID:   topic.ID,
+		Title:      topic.Title,
+		Content:    topic.Content,
+		EditorID:   topic.UserID,
+		CreatedAt:  topic.CreatedAt,
+	}
+}
+
+// replyRevision 回复当前内容的快照，作为第一次编辑前的原始版本
+func replyRevision(reply *models.Reply) *models.Revision {
+	return &models.Revision{
+		TargetType: models.RevisionTargetReply,
+		TargetID:   reply.ID,
+		Content:    reply.Content,
+		EditorID:   reply.UserID,
+		CreatedAt:  reply.CreatedAt,
+	}
+}
+
+// GetTopicRevisions 获取主题的编辑历史
+func (c *ForumController) GetTopicRevisions(ctx *gin.Context) {
+	if topicID, ok := c.loadRevisionTarget(ctx, models.RevisionTargetTopic); ok {
+		c.listRevisions(ctx, models.RevisionTargetTopic, topicID)
+	}
+}
+
+// GetReplyRevisions 获取回复的编辑历史
+func (c *ForumController) GetReplyRevisions(ctx *gin.Context) {
+	if replyID, ok := c.loadRevisionTarget(ctx, models.RevisionTargetReply); ok {
+		c.listRevisions(ctx, models.RevisionTargetReply, replyID)
+	}
+}
+
+// GetTopicRevisionDiff 比较主题的两个版本
+func (c *ForumController) GetTopicRevisionDiff(ctx *gin.Context) {
+	if topicID, ok := c.loadRevisionTarget(ctx, models.RevisionTargetTopic); ok {
+		c.diffRevisions(ctx, models.RevisionTargetTopic, topicID)
+	}
+}
+
+// GetReplyRevisionDiff 比较回复的两个版本
+func (c *ForumController) GetReplyRevisionDiff(ctx *gin.Context) {
+	if replyID, ok := c.loadRevisionTarget(ctx, models.RevisionTargetReply); ok {
+		c.diffRevisions(ctx, models.RevisionTargetReply, replyID)
+	}
+}
+
+// loadRevisionTarget 检查当前用户能否查看主题或回复，失败时直接写入错误响应
+// 隐藏主题的历史只有作者和管理员可以查看，已删除回复的历史只有管理员可以查看
+func (c *ForumController) loadRevisionTarget(ctx *gin.Context, targetType string) (uint, bool) {
+	viewer := getResourceViewer(ctx, c.DB)
+
+	topicID := ctx.Param("id")
+	var targetID uint
+	if targetType == models.RevisionTargetReply {
+		var reply models.Reply
+		if err := visiblePublished(c.DB, "replies", viewer).First(&reply, ctx.Param("id")).Error; err != nil || (reply.IsDeleted && !viewer.IsAdmin) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+			return 0, false
+		}
+		topicID = strconv.FormatUint(uint64(reply.TopicID), 10)
+		targetID = reply.ID
+	}
+
+	var topic models.Topic
+	if err := c.DB.First(&topic, topicID).Error; err != nil || !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return 0, false
+	}
+	if targetType == models.RevisionTargetTopic {
+		targetID = topic.ID
+	}
+
+	return targetID, true
+}
+
+// listRevisions 返回全部版本，按版本号从新到旧排列，不包含内容
+func (c *ForumController) listRevisions(ctx *gin.Context, targetType string, targetID uint) {
+	var revisions []models.Revision
+	c.DB.Omit("content").
+		Preload("Editor").
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("version DESC").
+		Find(&revisions)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"total":     len(revisions),
+	})
+}
+
+// diffRevisions 比较两个版本的内容，默认比较最新版本和上一个版本
+func (c *ForumController) diffRevisions(ctx *gin.Context, targetType string, targetID uint) {
+	var latest int
+	c.DB.Model(&models.Revision{}).
+		Select("COALESCE(MAX(version), 0)").
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Scan(&latest)
+	if latest < 2 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "没有编辑记录"})
+		return
+	}
+
+	to, err := strconv.Atoi(ctx.DefaultQuery("to", strconv.Itoa(latest)))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "版本号必须是整数"})
+		return
+	}
+	from, err := strconv.Atoi(ctx.DefaultQuery("from", strconv.Itoa(to-1)))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "版本号必须是整数"})
+		return
+	}
+	if from < 1 || to < 1 || from > latest || to > latest || from == to {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("版本号应为1到%d之间两个不同的数", latest)})
+		return
+	}
+
+	var revisions []models.Revision
+	c.DB.Preload("Editor").
+		Where("target_type = ? AND target_id = ? AND version IN ?", targetType, targetID, []int{from, to}).
+		Find(&revisions)
+	if len(revisions) != 2 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "版本不存在"})
+		return
+	}
+	older, newer := revisions[0], revisions[1]
+	if older.Version != from {
+		older, newer = newer, older
+	}
+
+	lines := utils.DiffLines(older.Content, newer.Content)
+	insertions, deletions := 0, 0
+	for _, line := range lines {
+		switch line.Type {
+		case utils.DiffInsert:
+			insertions++
+		case utils.DiffDelete:
+			deletions++
+		}
+	}
+
+	response := gin.H{
+		"from":       revisionSummary(&older),
+		"to":         revisionSummary(&newer),
+		"diff":       lines,
+		"insertions": insertions,
+		"deletions":  deletions,
+	}
+	if targetType == models.RevisionTargetTopic {
+		response["title"] = gin.H{
+			"from":    older.Title,
+			"to":      newer.Title,
+			"changed": older.Title != newer.Title,
+		}
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// revisionSummary 比较结果中版本的基本信息
+func revisionSummary(revision *models.Revision) gin.H {
+	summary := gin.H{
+		"version":    revision.Version,
+		"reason":     revision.Reason,
+		"revert_of":  revision.RevertOf,
+		"created_at": revision.CreatedAt,
+		"editor":     nil,
+	}
+	if revision.Editor != nil {
+		summary["editor"] = gin.H{"id": revision.Editor.ID, "username": revision.Editor.Username, "avatar": revision.Editor.Avatar}
+	}
+	return summary
+}
+
+// revertInput 回滚请求，reason可以为空
+type revertInput struct {
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// loadRevertRevision 读取回滚请求和要恢复的版本，失败时直接写入错误响应
+func (c *AdminController) loadRevertRevision(ctx *gin.Context, targetType string, targetID uint) (*models.Revision, string, bool) {
+	var input revertInput
+	// 请求体可以为空
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	var revision models.Revision
+	if err := c.DB.Where("target_type = ? AND target_id = ? AND version = ?", targetType, targetID, ctx.Param("version")).
+		First(&revision).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "版本不存在"})
+		return nil, "", false
+	}
+
+	reason := fmt.Sprintf("管理员回滚到版本%d", revision.Version)
+	if input.Reason != "" {
+		reason += "：" + input.Reason
+	}
+	return &revision, reason, true
+}
+
+// RevertTopic 将主题的标题和内容恢复到之前的版本，回滚本身作为一个新版本保存
+func (c *AdminController) RevertTopic(ctx *gin.Context) {
+	topic, ok := c.loadModerationTopic(ctx)
+	if !ok {
+		return
+	}
+	adminID, _ := ctx.Get("userID")
+
+	revision, reason, ok := c.loadRevertRevision(ctx, models.RevisionTargetTopic, topic.ID)
+	if !ok {
+		return
+	}
+	if revision.Title == topic.Title && revision.Content == topic.Content {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "当前内容与该版本相同"})
+		return
+	}
+
+	now := time.Now()
+	contentHTML, mentionedUsers := renderWithMentions(c.DB, revision.Content)
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveRevision(tx, topicRevision(topic), &models.Revision{
+			TargetType: models.RevisionTargetTopic,
+			TargetID:   topic.ID,
+			Title:      revision.Title,
+			Content:    revision.Content,
+			EditorID:   adminID.(uint),
+			Reason:     reason,
+			RevertOf:   &revision.Version,
+			CreatedAt:  now,
+		}); err != nil {
+			return err
+		}
+		return tx.Model(topic).Updates(map[string]interface{}{
+			"title":          revision.Title,
+			"content":        revision.Content,
+			"content_html":   contentHTML,
+			"render_version": utils.MarkdownRenderVersion,
+			"is_edited":      true,
+			"edited_at":      now,
+			"updated_at":     now,
+		}).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "回滚失败"})
+		return
+	}
+
+	// 回滚恢复的@提及只更新记录，不重新通知
+	syncMentions(c.DB, models.MentionSourceTopic, topic.ID, topic.UserID, mentionedUsers)
+	syncResourceReferences(c.DB, topic.ID, revision.Content)
+
+	c.notifyModeration(topic, fmt.Sprintf("你的主题《%s》已被管理员恢复到版本%d", revision.Title, revision.Version), reason)
+
+	c.DB.Preload("User").Preload("Category").First(topic, topic.ID)
+	ctx.JSON(http.StatusOK, topic)
+}
+
+// RevertReply 将回复的内容恢复到之前的版本，回滚本身作为一个新版本保存
+func (c *AdminController) RevertReply(ctx *gin.Context) {
+	admin, ok := c.requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var reply models.Reply
+	if err := c.DB.First(&reply, ctx.Param("id")).Error; err != nil || reply.IsDeleted {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+		return
+	}
+
+	revision, reason, ok := c.loadRevertRevision(ctx, models.RevisionTargetReply, reply.ID)
+	if !ok {
+		return
+	}
+	if revision.Content == reply.Content {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "当前内容与该版本相同"})
+		return
+	}
+
+	now := time.Now()
+	contentHTML, mentionedUsers := renderWithMentions(c.DB, revision.Content)
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveRevision(tx, replyRevision(&reply), &models.Revision{
+			TargetType: models.RevisionTargetReply,
+			TargetID:   reply.ID,
+			Content:    revision.Content,
+			EditorID:   admin.ID,
+			Reason:     reason,
+			RevertOf:   &revision.Version,
+			CreatedAt:  now,
+		}); err != nil {
+			return err
+		}
+		return tx.Model(&reply).Updates(map[string]interface{}{
+			"content":        revision.Content,
+			"content_html":   contentHTML,
+			"render_version": utils.MarkdownRenderVersion,
+			"is_edited":      true,
+			"edited_at":      now,
+			"updated_at":     now,
+		}).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "回滚失败"})
+		return
+	}
+
+	// 回滚恢复的@提及只更新记录，不重新通知
+	syncMentions(c.DB, models.MentionSourceReply, reply.ID, reply.UserID, mentionedUsers)
+
+	var topic models.Topic
+	c.DB.Select("id", "title").First(&topic, reply.TopicID)
+	c.Notifier.Notify(&models.Notification{
+		UserID:     reply.UserID,
+		Type:       models.NotificationModeration,
+		Title:      fmt.Sprintf("你在《%s》中的回复已被管理员恢复到版本%d", topic.Title, revision.Version),
+		Content:    reason,
+		TargetType: models.NotificationTargetReply,
+		TargetID:   reply.ID,
+		TopicID:    &topic.ID,
+	})
+
+	preloadReplyUsers(c.DB).First(&reply, reply.ID)
+	ctx.JSON(http.StatusOK, reply)
+}
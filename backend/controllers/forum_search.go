@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+const (
+	// searchSnippetLength 搜索结果摘要的最大字符数
+	searchSnippetLength = 160
+	// fulltextMatchTopic 主题的全文匹配条件，列必须与全文索引一致
+	fulltextMatchTopic = "MATCH(topics.title, topics.content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	// fulltextMatchReply 回复的全文匹配条件
+	fulltextMatchReply = "MATCH(replies.content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+)
+
+// forumSearchHit 搜索命中的主题或回复
+type forumSearchHit struct {
+	Type      string
+	TopicID   uint
+	ReplyID   uint
+	Score     float64
+	CreatedAt time.Time
+}
+
+// SearchForum 全文搜索主题和回复，按相关度排序
+// 支持按分类、作者、时间范围、解决状态和最低得分筛选，回复的结果链接到主题中的该回复
+func (c *ForumController) SearchForum(ctx *gin.Context) {
+	keyword := strings.TrimSpace(ctx.Query("q"))
+	if len([]rune(keyword)) < 2 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词至少需要2个字符"})
+		return
+	}
+
+	// 分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+
+	searchType := ctx.DefaultQuery("type", "all")
+	if searchType != "all" && searchType != "topic" && searchType != "reply" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "type只能是all、topic或reply"})
+		return
+	}
+
+	topicQuery := c.DB.Table("topics").
+		Select("'topic' AS type, topics.id AS topic_id, 0 AS reply_id, "+fulltextMatchTopic+" AS score, topics.created_at", keyword).
+		Where("topics.deleted_at IS NULL").
+		Where(fulltextMatchTopic, keyword)
+	replyQuery := c.DB.Table("replies").
+		Select("'reply' AS type, replies.topic_id, replies.id AS reply_id, "+fulltextMatchReply+" AS score, replies.created_at", keyword).
+		Joins("JOIN topics ON topics.id = replies.topic_id AND topics.deleted_at IS NULL").
+		Where("replies.deleted_at IS NULL AND replies.is_deleted = ?", false).
+		Where(fulltextMatchReply, keyword)
+
+	// both 给主题和回复两部分加上相同的主题条件
+	both := func(query string, args ...interface{}) {
+		topicQuery = topicQuery.Where(query, args...)
+		replyQuery = replyQuery.Where(query, args...)
+	}
+
+	// 隐藏的主题只有管理员能搜索到，静默封禁期间发布的内容只有作者和管理员能搜索到
+	viewer := getResourceViewer(ctx, c.DB)
+	if !viewer.IsAdmin {
+		both("topics.is_hidden = ?", false)
+	}
+	topicQuery = visibleShadowed(topicQuery, "topics", viewer)
+	replyQuery = visiblePublished(visibleShadowed(replyQuery, "topics", viewer), "replies", viewer)
+
+	if categoryID := ctx.Query("category"); categoryID != "" {
+		both("topics.category_id = ?", categoryID)
+	}
+
+	switch ctx.Query("solved") {
+	case "true":
+		both("topics.type = ? AND topics.is_solved = ?", models.TopicTypeQuestion, true)
+	case "false":
+		both("topics.type = ? AND topics.is_solved = ?", models.TopicTypeQuestion, false)
+	}
+
+	// 作者按用户名筛选，筛选的是主题或回复本身的作者
+	if author := strings.TrimSpace(ctx.Query("author")); author != "" {
+		var user models.User
+		if err := c.DB.Select("id").Where("username = ?", author).First(&user).Error; err != nil {
+			ctx.JSON(http.StatusOK, gin.H{"results": []gin.H{}, "total": 0, "page": page, "pageSize": pageSize, "q": keyword})
+			return
+		}
+		topicQuery = topicQuery.Where("topics.user_id = ?", user.ID)
+		replyQuery = replyQuery.Where("replies.user_id = ?", user.ID)
+	}
+
+	// 时间范围，格式为YYYY-MM-DD，包含结束日期当天
+	if from := ctx.Query("from"); from != "" {
+		date, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "日期格式错误，应为YYYY-MM-DD"})
+			return
+		}
+		topicQuery = topicQuery.Where("topics.created_at >= ?", date)
+		replyQuery = replyQuery.Where("replies.created_at >= ?", date)
+	}
+	if to := ctx.Query("to"); to != "" {
+		date, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "日期格式错误，应为YYYY-MM-DD"})
+			return
+		}
+		topicQuery = topicQuery.Where("topics.created_at < ?", date.AddDate(0, 0, 1))
+		replyQuery = replyQuery.Where("replies.created_at < ?", date.AddDate(0, 0, 1))
+	}
+
+	// 最低得分：主题为点赞数减点踩数，回复为赞同数减反对数
+	if minVotes := ctx.Query("min_votes"); minVotes != "" {
+		value, err := strconv.Atoi(minVotes)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "min_votes必须是整数"})
+			return
+		}
+		topicQuery = topicQuery.Where("topics.like_count - topics.dislike_count >= ?", value)
+		replyQuery = replyQuery.Where("replies.vote_score >= ?", value)
+	}
+
+	var hits *gorm.DB
+	switch searchType {
+	case "topic":
+		hits = c.DB.Table("(?) AS hits", topicQuery)
+	case "reply":
+		hits = c.DB.Table("(?) AS hits", replyQuery)
+	default:
+		hits = c.DB.Table("(? UNION ALL ?) AS hits", topicQuery, replyQuery)
+	}
+	// 计数和查询分别使用独立的语句
+	hits = hits.Session(&gorm.Session{})
+
+	order := "score DESC, created_at DESC"
+	if ctx.Query("sort") == "newest" {
+		order = "created_at DESC"
+	}
+
+	var total int64
+	if err := hits.Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败"})
+		return
+	}
+
+	var results []forumSearchHit
+	if err := hits.Select("*").Order(order).Limit(pageSize).Offset((page - 1) * pageSize).Find(&results).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"results":  c.buildSearchResults(results, utils.SearchTerms(keyword)),
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"q":        keyword,
+	})
+}
+
+// buildSearchResults 加载命中的主题和回复，生成带高亮的搜索结果
+func (c *ForumController) buildSearchResults(hits []forumSearchHit, terms []string) []gin.H {
+	var topicIDs, replyIDs []uint
+	for _, hit := range hits {
+		topicIDs = append(topicIDs, hit.TopicID)
+		if hit.ReplyID != 0 {
+			replyIDs = append(replyIDs, hit.ReplyID)
+		}
+	}
+
+	topics := make(map[uint]models.Topic)
+	if len(topicIDs) > 0 {
+		var list []models.Topic
+		c.DB.Preload("User").Preload("Category").Where("id IN ?", topicIDs).Find(&list)
+		for _, topic := range list {
+			topics[topic.ID] = topic
+		}
+	}
+
+	replies := make(map[uint]models.Reply)
+	if len(replyIDs) > 0 {
+		var list []models.Reply
+		c.DB.Preload("User").Where("id IN ?", replyIDs).Find(&list)
+		for _, reply := range list {
+			replies[reply.ID] = reply
+		}
+	}
+
+	results := make([]gin.H, 0, len(hits))
+	for _, hit := range hits {
+		topic, ok := topics[hit.TopicID]
+		if !ok {
+			continue
+		}
+
+		topicInfo := gin.H{
+			"id":                topic.ID,
+			"title":             topic.Title,
+			"title_highlight":   utils.HighlightSnippet(topic.Title, terms, 0),
+			"user":              gin.H{"id": topic.User.ID, "username": topic.User.Username, "avatar": topic.User.Avatar},
+			"category":          gin.H{"id": topic.Category.ID, "name": topic.Category.Name},
+			"type":              topic.Type,
+			"is_solved":         topic.IsSolved,
+			"reply_count":       topic.ReplyCount,
+			"like_count":        topic.LikeCount,
+			"dislike_count":     topic.DislikeCount,
+			"created_at":        topic.CreatedAt,
+			"accepted_reply_id": topic.AcceptedReplyID,
+		}
+
+		result := gin.H{
+			"type":  hit.Type,
+			"score": hit.Score,
+			"topic": topicInfo,
+		}
+
+		if hit.ReplyID == 0 {
+			result["snippet"] = utils.HighlightSnippet(utils.PlainText(topic.ContentHTML), terms, searchSnippetLength)
+			result["link"] = fmt.Sprintf("/forum/topic/%d", topic.ID)
+		} else {
+			reply, ok := replies[hit.ReplyID]
+			if !ok {
+				continue
+			}
+			result["reply"] = gin.H{
+				"id":          reply.ID,
+				"parent_id":   reply.ParentID,
+				"user":        gin.H{"id": reply.User.ID, "username": reply.User.Username, "avatar": reply.User.Avatar},
+				"vote_score":  reply.VoteScore,
+				"is_accepted": reply.IsAccepted,
+				"created_at":  reply.CreatedAt,
+			}
+			result["snippet"] = utils.HighlightSnippet(utils.PlainText(reply.ContentHTML), terms, searchSnippetLength)
+			result["link"] = fmt.Sprintf("/forum/topic/%d?reply=%d#reply-%d", topic.ID, reply.ID, reply.ID)
+		}
+
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+const (
+	// replyTreeDepth 树形视图默认展开的子回复层数
+	replyTreeDepth = 3
+	// replyTreeChildLimit 树形视图中每条回复最多展开的子回复数，其余通过子回复接口分页加载
+	replyTreeChildLimit = 5
+	// maxQuoteLength 引用片段的最大长度（字符数）
+	maxQuoteLength = 500
+	// maxFlatReplies 平铺视图不分页时最多返回的回复数
+	maxFlatReplies = 1000
+)
+
+// GetReplyChildren 分页获取某条回复的子回复，用于加载较深或较长的楼中楼
+func (c *ForumController) GetReplyChildren(ctx *gin.Context) {
+	viewer := getResourceViewer(ctx, c.DB)
+
+	// 上级回复和所在主题都需要对访问者可见
+	var parent models.Reply
+	if err := visiblePublished(c.DB, "replies", viewer).First(&parent, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+		return
+	}
+	var topic models.Topic
+	if err := c.DB.First(&topic, parent.TopicID).Error; err != nil || !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "回复不存在"})
+		return
+	}
+
+	// 分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	depth, _ := strconv.Atoi(ctx.DefaultQuery("depth", "2"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+	if depth < 0 || depth > 5 {
+		depth = 2
+	}
+
+	viewerID := viewer.UserID
+	query := visiblePublished(c.DB.Model(&models.Reply{}).Where("parent_id = ?", parent.ID), "replies", viewer)
+
+	// 游标分页
+	sort := ctx.Query("sort")
+	after, cursorSize, useCursor, err := cursorParams(ctx, replyKeyset(sort), 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if useCursor {
+		replies, nextCursor := findRepliesAfter(query, sort, after, cursorSize)
+		c.loadReplySubtrees(replies, viewer, depth, replyTreeChildLimit)
+		fillReplyVotes(c.DB, viewerID, replies)
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"parent_id":   parent.ID,
+			"replies":     replies,
+			"next_cursor": nextCursor,
+			"has_more":    nextCursor != "",
+			"pageSize":    cursorSize,
+		})
+		return
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var replies []models.Reply
+	preloadReplyUsers(query).
+		Order(replyOrder(sort)).
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&replies)
+
+	c.loadReplySubtrees(replies, viewer, depth, replyTreeChildLimit)
+	fillReplyVotes(c.DB, viewerID, replies)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"parent_id": parent.ID,
+		"replies":   replies,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+	})
+}
+
+// topicRootReplies 访问者可见的主题顶层回复的查询
+func (c *ForumController) topicRootReplies(topicID uint, viewer resourceViewer) *gorm.DB {
+	return visiblePublished(c.DB.Model(&models.Reply{}).Where("topic_id = ? AND parent_id IS NULL", topicID), "replies", viewer)
+}
+
+// getTopicReplyTree 获取主题的树形回复：分页的顶层回复及其展开的子回复
+// 排序只影响顶层回复，子回复总是按时间顺序
+func (c *ForumController) getTopicReplyTree(topicID uint, viewer resourceViewer, page, pageSize int, sort string) ([]models.Reply, int64) {
+	query := c.topicRootReplies(topicID, viewer)
+
+	var total int64
+	query.Count(&total)
+
+	var replies []models.Reply
+	preloadReplyUsers(query).
+		Order(replyOrder(sort)).
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&replies)
+
+	c.loadReplySubtrees(replies, viewer, replyTreeDepth, replyTreeChildLimit)
+	return replies, total
+}
+
+// getTopicReplyTreeAfter 按游标获取树形回复，返回下一页的游标
+func (c *ForumController) getTopicReplyTreeAfter(topicID uint, viewer resourceViewer, sort string, after []interface{}, pageSize int) ([]models.Reply, string) {
+	replies, nextCursor := findRepliesAfter(c.topicRootReplies(topicID, viewer), sort, after, pageSize)
+	c.loadReplySubtrees(replies, viewer, replyTreeDepth, replyTreeChildLimit)
+	return replies, nextCursor
+}
+
+// replyTreePage 计算回复在树形视图中所在的页码，即其顶层回复按当前排序所在的页
+// 返回页码和回复ID，回复不存在时返回0
+func (c *ForumController) replyTreePage(topicID uint, viewer resourceViewer, replyID string, pageSize int, sort string) (int, uint) {
+	// 访问者看不到的静默回复按不存在处理
+	var reply models.Reply
+	if err := visiblePublished(c.DB.Where("topic_id = ?", topicID), "replies", viewer).First(&reply, replyID).Error; err != nil {
+		return 0, 0
+	}
+
+	root := reply
+	if ancestors := replyAncestorIDs(reply.Path); len(ancestors) > 0 {
+		var top models.Reply
+		if err := visiblePublished(c.DB.Where("topic_id = ?", topicID), "replies", viewer).First(&top, ancestors[0]).Error; err != nil {
+			return 0, 0
+		}
+		root = top
+	}
+
+	// 统计排在顶层回复之前的顶层回复数
+	var before int64
+	replyKeyset(sort).before(c.topicRootReplies(topicID, viewer), replyKeyValues(sort, &root)).Count(&before)
+	return int(before)/pageSize + 1, reply.ID
+}
+
+// loadReplySubtrees 逐层加载访问者可见的子回复，最多展开depth层，每条回复最多展开childLimit条子回复
+// 没有完全展开的回复会标记HasMoreChildren。ChildCount包含访问者看不到的回复，是否还有更多按可见的子回复判断
+func (c *ForumController) loadReplySubtrees(replies []models.Reply, viewer resourceViewer, depth, childLimit int) {
+	level := make([]*models.Reply, 0, len(replies))
+	for i := range replies {
+		level = append(level, &replies[i])
+	}
+
+	for d := 0; d < depth && len(level) > 0; d++ {
+		parentIDs, parents := replyParents(level)
+		if len(parentIDs) == 0 {
+			return
+		}
+
+		// 每条回复最多多取一条子回复，用于判断是否还有更多
+		ranked := visiblePublished(c.DB.Model(&models.Reply{}).Where("replies.parent_id IN ?", parentIDs), "replies", viewer).
+			Select("replies.id, ROW_NUMBER() OVER (PARTITION BY replies.parent_id ORDER BY replies.created_at ASC, replies.id ASC) AS child_rank")
+		var children []models.Reply
+		preloadReplyUsers(c.DB.Where("id IN (?)", c.DB.Table("(?) AS ranked", ranked).Select("id").Where("child_rank <= ?", childLimit+1))).
+			Order("created_at ASC, id ASC").
+			Find(&children)
+
+		for _, child := range children {
+			parent := parents[*child.ParentID]
+			if len(parent.Children) < childLimit {
+				parent.Children = append(parent.Children, child)
+			} else {
+				parent.HasMoreChildren = true
+			}
+		}
+
+		level = level[:0]
+		for _, parent := range parents {
+			for i := range parent.Children {
+				level = append(level, &parent.Children[i])
+			}
+		}
+	}
+
+	// 超出展开层数的回复只标记是否还有可见的子回复
+	parentIDs, parents := replyParents(level)
+	if len(parentIDs) == 0 {
+		return
+	}
+	var withChildren []uint
+	visiblePublished(c.DB.Model(&models.Reply{}).Where("replies.parent_id IN ?", parentIDs), "replies", viewer).
+		Distinct("replies.parent_id").
+		Pluck("replies.parent_id", &withChildren)
+	for _, id := range withChildren {
+		parents[id].HasMoreChildren = true
+	}
+}
+
+// replyParents 选出有子回复的回复，ChildCount为0的回复不需要再查询
+func replyParents(level []*models.Reply) ([]uint, map[uint]*models.Reply) {
+	var parentIDs []uint
+	parents := make(map[uint]*models.Reply)
+	for _, reply := range level {
+		if reply.ChildCount > 0 {
+			parentIDs = append(parentIDs, reply.ID)
+			parents[reply.ID] = reply
+		}
+	}
+	return parentIDs, parents
+}
+
+// deleteReplyNode 删除回复并维护楼中楼结构
+// 有子回复的回复只清空内容并标记删除，保留占位；没有子回复时直接删除，
+// 并顺带清理因此变为空的已删除占位
+func deleteReplyNode(tx *gorm.DB, reply *models.Reply) error {
+	// 祖先的子树回复数只统计未删除的回复
+	if ancestors := replyAncestorIDs(reply.Path); len(ancestors) > 0 {
+		if err := tx.Model(&models.Reply{}).Where("id IN ?", ancestors).
+			Update("descendant_count", gorm.Expr("descendant_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+
+	if reply.ChildCount > 0 {
+		return tx.Model(reply).Updates(map[string]interface{}{
+			"is_deleted":      true,
+			"content":         "",
+			"content_html":    "",
+			"quote_content":   "",
+			"quoted_reply_id": nil,
+			"quoted_user_id":  nil,
+		}).Error
+	}
+
+	current := reply
+	for {
+		if err := tx.Delete(current).Error; err != nil {
+			return err
+		}
+		if current.ParentID == nil {
+			return nil
+		}
+
+		var parent models.Reply
+		if err := tx.First(&parent, *current.ParentID).Error; err != nil {
+			return nil
+		}
+		if err := tx.Model(&parent).Update("child_count", gorm.Expr("child_count - 1")).Error; err != nil {
+			return err
+		}
+
+		// 上级是已删除的占位且已经没有子回复时一并清理
+		if !parent.IsDeleted || parent.ChildCount > 1 {
+			return nil
+		}
+		current = &parent
+	}
+}
+
+// notifyNewReply 通知新回复的相关用户：被回复的楼层作者、被引用的用户和主题的订阅者（包括主题作者）
+// 同一个人只收到一条通知，优先级依次降低；已经收到@提及通知的用户不再通知
+func (c *ForumController) notifyNewReply(topic *models.Topic, reply *models.Reply, mentioned []uint) {
+	actorID := reply.UserID
+	notified := make(map[uint]bool)
+	for _, userID := range mentioned {
+		notified[userID] = true
+	}
+
+	send := func(userID uint, title string) {
+		if notified[userID] {
+			return
+		}
+		notified[userID] = true
+
+		c.Notifier.Notify(&models.Notification{
+			UserID:     userID,
+			Type:       models.NotificationReply,
+			Title:      title,
+			Content:    reply.Content,
+			ActorID:    &actorID,
+			TargetType: models.NotificationTargetReply,
+			TargetID:   reply.ID,
+			TopicID:    &topic.ID,
+		})
+	}
+
+	username := reply.User.Username
+	if reply.ReplyToUserID != nil {
+		send(*reply.ReplyToUserID, fmt.Sprintf("%s 回复了你在《%s》中的楼层", username, topic.Title))
+	}
+	if reply.QuotedUserID != nil {
+		send(*reply.QuotedUserID, fmt.Sprintf("%s 引用了你在《%s》中的回复", username, topic.Title))
+	}
+	c.notifyTopicWatchers(topic, reply, notified)
+}
+
+// preloadReplyUsers 预加载回复相关的用户信息
+func preloadReplyUsers(query *gorm.DB) *gorm.DB {
+	return query.Preload("User").Preload("ReplyToUser").Preload("QuotedUser")
+}
+
+// replyAncestorIDs 解析回复路径中的祖先回复ID，不包含回复自身
+func replyAncestorIDs(path string) []uint {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(segments) <= 1 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(segments)-1)
+	for _, segment := range segments[:len(segments)-1] {
+		if id, err := strconv.ParseUint(segment, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// resolveQuote 校验引用内容，引用片段必须出自被引用的回复
+// 未指定片段时引用整条回复（超长时截断）
+func resolveQuote(quoted *models.Reply, fragment string) (string, string) {
+	fragment = strings.TrimSpace(fragment)
+	if fragment == "" {
+		runes := []rune(quoted.Content)
+		if len(runes) > maxQuoteLength {
+			return string(runes[:maxQuoteLength]) + "…", ""
+		}
+		return quoted.Content, ""
+	}
+
+	if len([]rune(fragment)) > maxQuoteLength {
+		return "", "引用内容过长"
+	}
+
+	if !strings.Contains(quoted.Content, fragment) {
+		return "", "引用内容与原回复不符"
+	}
+
+	return fragment, ""
+}
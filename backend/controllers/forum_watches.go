@@ -0,0 +1,587 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+)
+
+const (
+	// watchDigestHour 每天几点之后发送订阅摘要
+	watchDigestHour = 8
+	// watchDigestInterval 检查是否需要发送摘要的间隔
+	watchDigestInterval = 10 * time.Minute
+)
+
+// watchInput 订阅请求，delivery为空时使用用户设置中的默认送达方式
+type watchInput struct {
+	Delivery string `json:"delivery" binding:"omitempty,oneof=instant daily weekly"`
+}
+
+// watchSetting 查询用户的订阅设置，没有保存过时返回默认设置
+func watchSetting(db *gorm.DB, userID uint) models.WatchSetting {
+	var setting models.WatchSetting
+	if err := db.Where("user_id = ?", userID).First(&setting).Error; err != nil {
+		return models.DefaultWatchSetting(userID)
+	}
+	return setting
+}
+
+// autoWatchTopic 自动订阅主题，已经订阅过（包括修改过送达方式）时不做修改
+func autoWatchTopic(db *gorm.DB, userID, topicID uint, delivery string) {
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Watch{
+		UserID:     userID,
+		TargetType: models.WatchTargetTopic,
+		TargetID:   topicID,
+		Delivery:   delivery,
+	}).Error; err != nil {
+		log.Printf("自动订阅主题%d失败: %v", topicID, err)
+	}
+}
+
+// removeTopicWatches 主题被删除时移除对它的订阅和待发送的摘要动态
+func removeTopicWatches(db *gorm.DB, topicID uint) {
+	db.Where("topic_id = ?", topicID).Delete(&models.WatchDigestItem{})
+	db.Where("target_type = ? AND target_id = ?", models.WatchTargetTopic, topicID).Delete(&models.Watch{})
+}
+
+// viewerWatch 查询用户对某个对象的订阅，未登录或未订阅时返回nil
+func viewerWatch(db *gorm.DB, userID uint, targetType string, targetID uint) *models.Watch {
+	if userID == 0 {
+		return nil
+	}
+
+	var watch models.Watch
+	if db.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+		Limit(1).Find(&watch).RowsAffected == 0 {
+		return nil
+	}
+	return &watch
+}
+
+// notifyTopicWatchers 通知订阅了主题的用户有新回复，已经收到其他通知的用户不再通知
+// 主题作者的订阅发送的是回复通知，其他订阅者收到订阅动态通知；选择摘要的订阅者记入摘要
+func (c *ForumController) notifyTopicWatchers(topic *models.Topic, reply *models.Reply, notified map[uint]bool) {
+	var watches []models.Watch
+	c.DB.Where("target_type = ? AND target_id = ? AND user_id <> ?", models.WatchTargetTopic, topic.ID, reply.UserID).
+		Find(&watches)
+
+	actorID := reply.UserID
+	for i := range watches {
+		watch := &watches[i]
+		if notified[watch.UserID] {
+			continue
+		}
+		// 隐藏和静默的主题只有作者还能看到
+		if !canViewTopic(topic, resourceViewer{UserID: watch.UserID}) {
+			continue
+		}
+		notified[watch.UserID] = true
+
+		if watch.Delivery != models.WatchDeliveryInstant {
+			c.queueWatchDigest(watch, topic.ID, &reply.ID, actorID)
+			continue
+		}
+
+		notificationType := models.NotificationWatch
+		title := fmt.Sprintf("%s 回复了你订阅的主题《%s》", reply.User.Username, topic.Title)
+		if watch.UserID == topic.UserID {
+			notificationType = models.NotificationReply
+			title = fmt.Sprintf("%s 回复了你的主题《%s》", reply.User.Username, topic.Title)
+		}
+		c.Notifier.Notify(&models.Notification{
+			UserID:     watch.UserID,
+			Type:       notificationType,
+			Title:      title,
+			Content:    reply.Content,
+			ActorID:    &actorID,
+			TargetType: models.NotificationTargetReply,
+			TargetID:   reply.ID,
+			TopicID:    &topic.ID,
+		})
+	}
+}
+
+// notifyCategoryWatchers 通知订阅了分类的用户有新主题，已经收到@提及通知的用户不再通知
+// topic需要预加载User和Category
+func (c *ForumController) notifyCategoryWatchers(topic *models.Topic, mentioned []uint) {
+	notified := make(map[uint]bool)
+	for _, userID := range mentioned {
+		notified[userID] = true
+	}
+
+	var watches []models.Watch
+	c.DB.Where("target_type = ? AND target_id = ? AND user_id <> ?", models.WatchTargetCategory, topic.CategoryID, topic.UserID).
+		Find(&watches)
+
+	actorID := topic.UserID
+	for i := range watches {
+		watch := &watches[i]
+		if notified[watch.UserID] {
+			continue
+		}
+
+		if watch.Delivery != models.WatchDeliveryInstant {
+			c.queueWatchDigest(watch, topic.ID, nil, actorID)
+			continue
+		}
+
+		c.Notifier.Notify(&models.Notification{
+			UserID:     watch.UserID,
+			Type:       models.NotificationWatch,
+			Title:      fmt.Sprintf("%s 在你订阅的分类「%s」发布了新主题《%s》", topic.User.Username, topic.Category.Name, topic.Title),
+			Content:    topic.Content,
+			ActorID:    &actorID,
+			TargetType: models.NotificationTargetTopic,
+			TargetID:   topic.ID,
+			TopicID:    &topic.ID,
+		})
+	}
+}
+
+// queueWatchDigest 记录一条等待汇总到摘要中的动态，屏蔽了触发者时不记录
+func (c *ForumController) queueWatchDigest(watch *models.Watch, topicID uint, replyID *uint, actorID uint) {
+	if c.Notifier.Blocked(watch.UserID, actorID, models.NotificationWatch) {
+		return
+	}
+
+	if err := c.DB.Create(&models.WatchDigestItem{
+		UserID:    watch.UserID,
+		WatchID:   watch.ID,
+		Delivery:  watch.Delivery,
+		TopicID:   topicID,
+		ReplyID:   replyID,
+		ActorID:   actorID,
+		CreatedAt: time.Now(),
+	}).Error; err != nil {
+		log.Printf("记录订阅摘要失败: %v", err)
+	}
+}
+
+// GetWatches 获取当前用户的订阅列表，可按type筛选主题或分类订阅
+func (c *ForumController) GetWatches(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := c.DB.Model(&models.Watch{}).Where("user_id = ?", userID)
+	if targetType := ctx.Query("type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var watches []models.Watch
+	query.Order("created_at DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&watches)
+
+	var topicIDs, categoryIDs []uint
+	for _, watch := range watches {
+		if watch.TargetType == models.WatchTargetTopic {
+			topicIDs = append(topicIDs, watch.TargetID)
+		} else {
+			categoryIDs = append(categoryIDs, watch.TargetID)
+		}
+	}
+
+	topics := make(map[uint]models.Topic)
+	if len(topicIDs) > 0 {
+		var list []models.Topic
+		c.DB.Preload("User").Preload("Category").Where("id IN ?", topicIDs).Find(&list)
+		for _, topic := range list {
+			topics[topic.ID] = topic
+		}
+	}
+
+	categories := make(map[uint]models.Category)
+	if len(categoryIDs) > 0 {
+		var list []models.Category
+		c.DB.Where("id IN ?", categoryIDs).Find(&list)
+		for _, category := range list {
+			categories[category.ID] = category
+		}
+	}
+
+	results := make([]gin.H, 0, len(watches))
+	for _, watch := range watches {
+		item := gin.H{
+			"id":          watch.ID,
+			"target_type": watch.TargetType,
+			"target_id":   watch.TargetID,
+			"delivery":    watch.Delivery,
+			"created_at":  watch.CreatedAt,
+		}
+
+		if watch.TargetType == models.WatchTargetTopic {
+			topic, ok := topics[watch.TargetID]
+			if !ok {
+				continue
+			}
+			item["topic"] = gin.H{
+				"id":          topic.ID,
+				"title":       topic.Title,
+				"user":        gin.H{"id": topic.User.ID, "username": topic.User.Username, "avatar": topic.User.Avatar},
+				"category":    gin.H{"id": topic.Category.ID, "name": topic.Category.Name},
+				"reply_count": topic.ReplyCount,
+				"updated_at":  topic.UpdatedAt,
+			}
+		} else {
+			category, ok := categories[watch.TargetID]
+			if !ok {
+				continue
+			}
+			item["category"] = gin.H{"id": category.ID, "name": category.Name}
+		}
+
+		results = append(results, item)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"watches":  results,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// WatchTopic 订阅主题或修改订阅的送达方式
+func (c *ForumController) WatchTopic(ctx *gin.Context) {
+	viewer := getResourceViewer(ctx, c.DB)
+	if viewer.UserID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var topic models.Topic
+	if err := c.DB.First(&topic, ctx.Param("id")).Error; err != nil || !canViewTopic(&topic, viewer) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return
+	}
+
+	c.saveWatch(ctx, viewer.UserID, models.WatchTargetTopic, topic.ID)
+}
+
+// WatchCategory 订阅分类或修改订阅的送达方式
+func (c *ForumController) WatchCategory(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var category models.Category
+	if err := c.DB.First(&category, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+		return
+	}
+
+	c.saveWatch(ctx, userID.(uint), models.WatchTargetCategory, category.ID)
+}
+
+// saveWatch 创建订阅，已订阅时修改送达方式
+// 待发送的摘要动态随送达方式转到新的摘要中，改为即时通知时丢弃
+func (c *ForumController) saveWatch(ctx *gin.Context, userID uint, targetType string, targetID uint) {
+	var input watchInput
+	// 请求体可以为空，此时使用默认送达方式
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Delivery == "" {
+		input.Delivery = watchSetting(c.DB, userID).DefaultDelivery
+	}
+
+	watch := models.Watch{UserID: userID, TargetType: targetType, TargetID: targetID}
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(watch).Attrs(models.Watch{Delivery: input.Delivery}).FirstOrCreate(&watch).Error; err != nil {
+			return err
+		}
+		if watch.Delivery == input.Delivery {
+			return nil
+		}
+
+		if err := tx.Model(&watch).Update("delivery", input.Delivery).Error; err != nil {
+			return err
+		}
+		pending := tx.Where("watch_id = ?", watch.ID)
+		if input.Delivery == models.WatchDeliveryInstant {
+			return pending.Delete(&models.WatchDigestItem{}).Error
+		}
+		return pending.Model(&models.WatchDigestItem{}).Update("delivery", input.Delivery).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "订阅失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "订阅成功", "watch": watch})
+}
+
+// UnwatchTopic 取消订阅主题
+func (c *ForumController) UnwatchTopic(ctx *gin.Context) {
+	c.removeWatch(ctx, models.WatchTargetTopic)
+}
+
+// UnwatchCategory 取消订阅分类
+func (c *ForumController) UnwatchCategory(ctx *gin.Context) {
+	c.removeWatch(ctx, models.WatchTargetCategory)
+}
+
+// removeWatch 删除订阅及其待发送的摘要动态
+func (c *ForumController) removeWatch(ctx *gin.Context, targetType string) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var watch models.Watch
+	if err := c.DB.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, ctx.Param("id")).
+		First(&watch).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "未订阅"})
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("watch_id = ?", watch.ID).Delete(&models.WatchDigestItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&watch).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消订阅失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已取消订阅"})
+}
+
+// GetWatchSettings 获取自动订阅设置
+func (c *ForumController) GetWatchSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"settings": watchSetting(c.DB, userID.(uint))})
+}
+
+// UpdateWatchSettings 修改自动订阅设置，只修改请求中提供的字段
+func (c *ForumController) UpdateWatchSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input struct {
+		AutoWatchOwn     *bool  `json:"auto_watch_own"`
+		AutoWatchReplied *bool  `json:"auto_watch_replied"`
+		DefaultDelivery  string `json:"default_delivery" binding:"omitempty,oneof=instant daily weekly"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if input.AutoWatchOwn != nil {
+		updates["auto_watch_own"] = *input.AutoWatchOwn
+	}
+	if input.AutoWatchReplied != nil {
+		updates["auto_watch_replied"] = *input.AutoWatchReplied
+	}
+	if input.DefaultDelivery != "" {
+		updates["default_delivery"] = input.DefaultDelivery
+	}
+
+	setting := models.WatchSetting{UserID: userID.(uint)}
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(setting).FirstOrCreate(&setting).Error; err != nil {
+			return err
+		}
+		return tx.Model(&setting).Updates(updates).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存订阅设置失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "订阅设置已保存", "settings": watchSetting(c.DB, userID.(uint))})
+}
+
+// sendWatchDigests 定时发送订阅摘要
+func (c *ForumController) sendWatchDigests() {
+	ticker := time.NewTicker(watchDigestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.deliverWatchDigests(time.Now())
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// deliverWatchDigests 每天watchDigestHour点后发送每日摘要，每周一发送每周摘要
+func (c *ForumController) deliverWatchDigests(now time.Time) {
+	if now.Hour() < watchDigestHour {
+		return
+	}
+
+	if c.claimDigestRun("daily:"+now.Format("2006-01-02"), 48*time.Hour) {
+		c.sendDigests(models.WatchDeliveryDaily, now)
+	}
+
+	if now.Weekday() == time.Monday {
+		year, week := now.ISOWeek()
+		if c.claimDigestRun(fmt.Sprintf("weekly:%d-%d", year, week), 8*24*time.Hour) {
+			c.sendDigests(models.WatchDeliveryWeekly, now)
+		}
+	}
+}
+
+// claimDigestRun 在Redis中记录本期摘要已开始发送，多个实例中只有一个能领取到
+func (c *ForumController) claimDigestRun(period string, ttl time.Duration) bool {
+	claimed, err := c.Redis.SetNX(context.Background(), "watch_digest:"+period, 1, ttl).Result()
+	if err != nil {
+		log.Printf("领取订阅摘要任务失败: %v", err)
+		return false
+	}
+	return claimed
+}
+
+// sendDigests 向所有有待发送动态的用户发送摘要
+func (c *ForumController) sendDigests(delivery string, before time.Time) {
+	var userIDs []uint
+	c.DB.Model(&models.WatchDigestItem{}).
+		Where("delivery = ? AND created_at < ?", delivery, before).
+		Distinct().
+		Pluck("user_id", &userIDs)
+
+	for _, userID := range userIDs {
+		c.sendUserDigest(userID, delivery, before)
+	}
+}
+
+// digestEntry 摘要中一个主题的动态
+type digestEntry struct {
+	topic   models.Topic
+	isNew   bool // 是订阅的分类下的新主题
+	replies int  // 新回复数
+}
+
+// sendUserDigest 将用户的待发送动态按主题汇总为一条通知，发送后删除这些动态
+// 已删除、隐藏或被静默封禁的主题不再出现在摘要中
+func (c *ForumController) sendUserDigest(userID uint, delivery string, before time.Time) {
+	var items []models.WatchDigestItem
+	c.DB.Where("user_id = ? AND delivery = ? AND created_at < ?", userID, delivery, before).
+		Order("id ASC").
+		Find(&items)
+	if len(items) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(items))
+	topicIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+		topicIDs = append(topicIDs, item.TopicID)
+	}
+
+	topics := make(map[uint]models.Topic)
+	var list []models.Topic
+	c.DB.Preload("Category").Where("id IN ? AND is_hidden = ? AND shadowed = ?", topicIDs, false, false).Find(&list)
+	for _, topic := range list {
+		topics[topic.ID] = topic
+	}
+
+	// 按动态出现的先后顺序排列主题
+	var entries []*digestEntry
+	byTopic := make(map[uint]*digestEntry)
+	for _, item := range items {
+		topic, ok := topics[item.TopicID]
+		if !ok {
+			continue
+		}
+		entry, ok := byTopic[topic.ID]
+		if !ok {
+			entry = &digestEntry{topic: topic}
+			byTopic[topic.ID] = entry
+			entries = append(entries, entry)
+		}
+		if item.ReplyID == nil {
+			entry.isNew = true
+		} else {
+			entry.replies++
+		}
+	}
+
+	if len(entries) > 0 {
+		lines := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			switch {
+			case entry.isNew && entry.replies > 0:
+				lines = append(lines, fmt.Sprintf("「%s」新主题《%s》，%d条新回复", entry.topic.Category.Name, entry.topic.Title, entry.replies))
+			case entry.isNew:
+				lines = append(lines, fmt.Sprintf("「%s」新主题《%s》", entry.topic.Category.Name, entry.topic.Title))
+			default:
+				lines = append(lines, fmt.Sprintf("《%s》%d条新回复", entry.topic.Title, entry.replies))
+			}
+		}
+
+		period := "每日"
+		if delivery == models.WatchDeliveryWeekly {
+			period = "每周"
+		}
+		notification := &models.Notification{
+			UserID:     userID,
+			Type:       models.NotificationWatch,
+			Title:      fmt.Sprintf("%s订阅摘要：%d个主题有新动态", period, len(entries)),
+			Content:    strings.Join(lines, "；"),
+			TargetType: models.NotificationTargetDigest,
+		}
+		// 只有一个主题时直接跳转到该主题
+		if len(entries) == 1 {
+			notification.TargetType = models.NotificationTargetTopic
+			notification.TargetID = entries[0].topic.ID
+			notification.TopicID = &entries[0].topic.ID
+		}
+		c.Notifier.Notify(notification)
+	}
+
+	if err := c.DB.Where("id IN ?", ids).Delete(&models.WatchDigestItem{}).Error; err != nil {
+		log.Printf("删除用户%d已发送的订阅摘要失败: %v", userID, err)
+	}
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// GroupController 用户组控制器
+type GroupController struct {
+	DB *gorm.DB
+}
+
+// NewGroupController 创建用户组控制器实例
+func NewGroupController(db *gorm.DB) *GroupController {
+	return &GroupController{DB: db}
+}
+
+// GetMyGroups 获取当前用户所在的用户组
+func (c *GroupController) GetMyGroups(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var groups []models.UserGroup
+	c.DB.Where("id IN (SELECT group_id FROM user_group_members WHERE user_id = ?)", userID).
+		Preload("Owner").
+		Order("created_at DESC").
+		Find(&groups)
+
+	for i := range groups {
+		c.DB.Model(&models.UserGroupMember{}).Where("group_id = ?", groups[i].ID).Count(&groups[i].MemberCount)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// CreateGroup 创建用户组，创建者自动成为组长
+func (c *GroupController) CreateGroup(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input struct {
+		Name        string `json:"name" binding:"required,max=50"`
+		Description string `json:"description" binding:"max=255"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group := models.UserGroup{
+		Name:        input.Name,
+		Description: input.Description,
+		OwnerID:     userID.(uint),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserGroupMember{
+			GroupID:   group.ID,
+			UserID:    userID.(uint),
+			Role:      models.GroupRoleOwner,
+			CreatedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户组失败"})
+		return
+	}
+
+	group.MemberCount = 1
+	ctx.JSON(http.StatusCreated, group)
+}
+
+// GetGroup 获取用户组详情及成员列表，仅组内成员和管理员可见
+func (c *GroupController) GetGroup(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var group models.UserGroup
+	if err := c.DB.Preload("Owner").First(&group, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+		return
+	}
+
+	if !isGroupMember(c.DB, group.ID, userID.(uint)) && !c.isAdmin(userID) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权查看此用户组"})
+		return
+	}
+
+	var members []models.UserGroupMember
+	c.DB.Where("group_id = ?", group.ID).Preload("User").Order("created_at ASC").Find(&members)
+	group.MemberCount = int64(len(members))
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"group":   group,
+		"members": members,
+	})
+}
+
+// UpdateGroup 修改用户组信息
+func (c *GroupController) UpdateGroup(ctx *gin.Context) {
+	group, ok := c.loadManagedGroup(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name        string  `json:"name" binding:"max=50"`
+		Description *string `json:"description" binding:"omitempty,max=255"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"updated_at": time.Now(),
+	}
+	if input.Name != "" {
+		updates["name"] = input.Name
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+
+	if err := c.DB.Model(group).Updates(updates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户组失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup 删除用户组
+// 仅该组可见的资源改为仅上传者可见，避免删除后被意外公开
+func (c *GroupController) DeleteGroup(ctx *gin.Context) {
+	group, ok := c.loadManagedGroup(ctx)
+	if !ok {
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Resource{}).
+			Where("visibility = ? AND group_id = ?", models.VisibilityGroup, group.ID).
+			Updates(map[string]interface{}{
+				"visibility": models.VisibilityPrivate,
+				"group_id":   nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.UserGroupMember{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(group).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除用户组失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "用户组已删除"})
+}
+
+// AddMember 添加用户组成员，可以按用户ID或用户名添加
+func (c *GroupController) AddMember(ctx *gin.Context) {
+	group, ok := c.loadManagedGroup(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID   uint   `json:"user_id"`
+		Username string `json:"username"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	var err error
+	switch {
+	case input.UserID != 0:
+		err = c.DB.First(&user, input.UserID).Error
+	case input.Username != "":
+		err = c.DB.Where("username = ?", input.Username).First(&user).Error
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请指定用户ID或用户名"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	if isGroupMember(c.DB, group.ID, user.ID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "该用户已在用户组中"})
+		return
+	}
+
+	member := models.UserGroupMember{
+		GroupID:   group.ID,
+		UserID:    user.ID,
+		Role:      models.GroupRoleMember,
+		CreatedAt: time.Now(),
+	}
+	if err := c.DB.Create(&member).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "添加成员失败"})
+		return
+	}
+
+	member.User = &user
+	ctx.JSON(http.StatusCreated, member)
+}
+
+// RemoveMember 移除用户组成员，成员也可以自己退出
+func (c *GroupController) RemoveMember(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var group models.UserGroup
+	if err := c.DB.First(&group, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+		return
+	}
+
+	var member models.UserGroupMember
+	if err := c.DB.Where("group_id = ? AND user_id = ?", group.ID, ctx.Param("userId")).First(&member).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "该用户不在用户组中"})
+		return
+	}
+
+	isSelf := member.UserID == userID.(uint)
+	if !isSelf && group.OwnerID != userID.(uint) && !c.isAdmin(userID) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权管理此用户组"})
+		return
+	}
+
+	if member.Role == models.GroupRoleOwner {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "组长不能退出用户组"})
+		return
+	}
+
+	if err := c.DB.Delete(&member).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已移出用户组"})
+}
+
+// loadManagedGroup 查询当前用户可以管理的用户组（组长或管理员），失败时直接写入错误响应
+func (c *GroupController) loadManagedGroup(ctx *gin.Context) (*models.UserGroup, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return nil, false
+	}
+
+	var group models.UserGroup
+	if err := c.DB.First(&group, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户组不存在"})
+		return nil, false
+	}
+
+	if group.OwnerID != userID.(uint) && !c.isAdmin(userID) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权管理此用户组"})
+		return nil, false
+	}
+
+	return &group, true
+}
+
+// isAdmin 检查用户是否为管理员
+func (c *GroupController) isAdmin(userID interface{}) bool {
+	var user models.User
+	c.DB.First(&user, userID)
+	return user.Role == "admin"
+}
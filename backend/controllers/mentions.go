@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// renderWithMentions 渲染内容并解析其中@提及的用户
+func renderWithMentions(db *gorm.DB, content string) (string, map[string]uint) {
+	users := utils.ResolveMentions(db, content)
+	return utils.RenderMarkdownWithMentions(content, users), users
+}
+
+// syncMentions 保存内容中@提及的用户，返回本次新提及的用户ID
+// 编辑内容时只有新增的提及需要通知，不再提及的用户记录会被删除
+func syncMentions(db *gorm.DB, sourceType string, sourceID, authorID uint, users map[string]uint) []uint {
+	var existing []models.Mention
+	db.Where("source_type = ? AND source_id = ?", sourceType, sourceID).Find(&existing)
+
+	mentioned := make(map[uint]bool, len(users))
+	for _, userID := range users {
+		mentioned[userID] = true
+	}
+
+	previous := make(map[uint]bool, len(existing))
+	var removed []uint
+	for _, mention := range existing {
+		previous[mention.UserID] = true
+		if !mentioned[mention.UserID] {
+			removed = append(removed, mention.ID)
+		}
+	}
+	if len(removed) > 0 {
+		db.Delete(&models.Mention{}, removed)
+	}
+
+	var added []uint
+	for userID := range mentioned {
+		if previous[userID] {
+			continue
+		}
+		mention := models.Mention{
+			SourceType: sourceType,
+			SourceID:   sourceID,
+			UserID:     userID,
+			AuthorID:   authorID,
+			CreatedAt:  time.Now(),
+		}
+		if err := db.Create(&mention).Error; err == nil {
+			added = append(added, userID)
+		}
+	}
+	return added
+}
+
+// mentionNotification 构造@提及通知，where为提及所在位置的描述
+func mentionNotification(db *gorm.DB, actorID uint, where, content, targetType string, targetID uint, topicID *uint) models.Notification {
+	var actor models.User
+	db.Select("id", "username").First(&actor, actorID)
+
+	return models.Notification{
+		Type:       models.NotificationMention,
+		Title:      actor.Username + " 在" + where + "中提到了你",
+		Content:    content,
+		ActorID:    &actorID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		TopicID:    topicID,
+	}
+}
+
+// AutocompleteUsers 按用户名前缀搜索用户，用于编辑器中的@提及补全
+func (c *UserController) AutocompleteUsers(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	keyword := strings.TrimSpace(strings.TrimPrefix(ctx.Query("q"), "@"))
+	if keyword == "" {
+		ctx.JSON(http.StatusOK, gin.H{"users": []gin.H{}})
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 20 {
+		limit = 10
+	}
+
+	// 转义LIKE通配符
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(keyword)
+
+	var users []models.User
+	c.DB.Select("id", "username", "avatar").
+		Where("username LIKE ? AND id <> ?", escaped+"%", userID).
+		// 按用户名长度排序，完全匹配的用户名最短，排在最前
+		Order("CHAR_LENGTH(username) ASC, username ASC").
+		Limit(limit).
+		Find(&users)
+
+	result := make([]gin.H, 0, len(users))
+	for _, user := range users {
+		result = append(result, gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"avatar":   user.Avatar,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"users": result})
+}
+
+// GetBlockedUsers 获取自己屏蔽的用户
+func (c *UserController) GetBlockedUsers(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query := c.DB.Where("user_id = ?", userID)
+	if kind := ctx.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var blocks []models.UserBlock
+	query.Preload("BlockedUser").Order("created_at DESC").Find(&blocks)
+
+	ctx.JSON(http.StatusOK, gin.H{"blocks": blocks})
+}
+
+// BlockUser 屏蔽用户，已屏蔽时修改屏蔽类型
+// mute 免打扰：不接收对方的@提及通知；block 拉黑：不接收对方触发的任何通知
+func (c *UserController) BlockUser(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input struct {
+		UserID uint   `json:"user_id" binding:"required"`
+		Kind   string `json:"kind" binding:"required,oneof=mute block"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.UserID == userID.(uint) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不能屏蔽自己"})
+		return
+	}
+
+	var target models.User
+	if err := c.DB.First(&target, input.UserID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	var block models.UserBlock
+	if err := c.DB.Where(models.UserBlock{UserID: userID.(uint), BlockedUserID: target.ID}).
+		Attrs(models.UserBlock{CreatedAt: time.Now()}).
+		Assign(models.UserBlock{Kind: input.Kind}).
+		FirstOrCreate(&block).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "屏蔽用户失败"})
+		return
+	}
+
+	block.BlockedUser = &target
+	ctx.JSON(http.StatusOK, block)
+}
+
+// UnblockUser 取消屏蔽用户
+func (c *UserController) UnblockUser(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	result := c.DB.Where("user_id = ? AND blocked_user_id = ?", userID, ctx.Param("userId")).Delete(&models.UserBlock{})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消屏蔽失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "未屏蔽该用户"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已取消屏蔽"})
+}
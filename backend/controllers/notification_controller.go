@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// NotificationController 站内通知控制器
+type NotificationController struct {
+	DB *gorm.DB
+}
+
+// NewNotificationController 创建通知控制器实例
+func NewNotificationController(db *gorm.DB) *NotificationController {
+	return &NotificationController{DB: db}
+}
+
+// GetNotifications 获取当前用户的通知列表
+func (c *NotificationController) GetNotifications(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := c.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+
+	// 按类型过滤
+	if notificationType := ctx.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	// 只看未读
+	if ctx.Query("unread") == "true" {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var notifications []models.Notification
+	query.Preload("Actor").
+		Order("created_at DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&notifications)
+
+	var unreadCount int64
+	c.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userID, false).Count(&unreadCount)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread_count":  unreadCount,
+		"total":         total,
+		"page":          page,
+		"pageSize":      pageSize,
+	})
+}
+
+// GetUnreadCount 获取未读通知数，同时返回各类型的未读数
+func (c *NotificationController) GetUnreadCount(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var rows []struct {
+		Type  string
+		Count int64
+	}
+	c.DB.Model(&models.Notification{}).
+		Select("type, COUNT(*) AS count").
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Group("type").
+		Scan(&rows)
+
+	var total int64
+	byType := make(map[string]int64)
+	for _, row := range rows {
+		byType[row.Type] = row.Count
+		total += row.Count
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"unread_count": total,
+		"by_type":      byType,
+	})
+}
+
+// MarkRead 将一条通知标记为已读
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var notification models.Notification
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&notification).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return
+	}
+
+	if !notification.IsRead {
+		now := time.Now()
+		if err := c.DB.Model(&notification).Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": now,
+		}).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已标记为已读"})
+}
+
+// MarkAllRead 将全部通知（或某一类型的通知）标记为已读
+func (c *NotificationController) MarkAllRead(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query := c.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userID, false)
+	if notificationType := ctx.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"is_read": true,
+		"read_at": time.Now(),
+	})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "count": result.RowsAffected})
+}
+
+// DeleteNotification 删除一条通知
+func (c *NotificationController) DeleteNotification(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	result := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除通知失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "通知已删除"})
+}
+
+// GetPreferences 获取各类通知的开关
+func (c *NotificationController) GetPreferences(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var saved []models.NotificationPreference
+	c.DB.Where("user_id = ?", userID).Find(&saved)
+
+	enabled := make(map[string]bool)
+	for _, preference := range saved {
+		enabled[preference.Type] = preference.Enabled
+	}
+
+	type preferenceItem struct {
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+	}
+
+	preferences := make([]preferenceItem, 0, len(models.NotificationTypes))
+	for notificationType, name := range models.NotificationTypes {
+		value, ok := enabled[notificationType]
+		preferences = append(preferences, preferenceItem{
+			Type:    notificationType,
+			Name:    name,
+			Enabled: !ok || value,
+		})
+	}
+	sort.Slice(preferences, func(i, j int) bool { return preferences[i].Type < preferences[j].Type })
+
+	ctx.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// UpdatePreferences 修改通知开关
+func (c *NotificationController) UpdatePreferences(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var input struct {
+		Preferences map[string]bool `json:"preferences" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for notificationType := range input.Preferences {
+		if _, ok := models.NotificationTypes[notificationType]; !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "未知的通知类型: " + notificationType})
+			return
+		}
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		for notificationType, enabled := range input.Preferences {
+			preference := models.NotificationPreference{UserID: userID.(uint), Type: notificationType}
+			if err := tx.Where(preference).FirstOrCreate(&preference).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&preference).Updates(map[string]interface{}{
+				"enabled":    enabled,
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存通知设置失败"})
+		return
+	}
+
+	c.GetPreferences(ctx)
+}
+
+// likeNotification 构造点赞通知，what为被点赞对象的描述
+func likeNotification(db *gorm.DB, actorID, ownerID uint, targetType string, targetID uint, what string) *models.Notification {
+	var actor models.User
+	db.Select("id", "username").First(&actor, actorID)
+
+	notification := &models.Notification{
+		UserID:     ownerID,
+		Type:       models.NotificationLike,
+		Title:      fmt.Sprintf("%s 赞了%s", actor.Username, what),
+		ActorID:    &actorID,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if targetType == models.NotificationTargetTopic {
+		notification.TopicID = &targetID
+	}
+	return notification
+}
+
+// pointsAdjustedNotification 构造管理员调整积分的通知
+func pointsAdjustedNotification(userID uint, points int, description string) *models.Notification {
+	title := fmt.Sprintf("管理员为你增加了%d积分", points)
+	if points < 0 {
+		title = fmt.Sprintf("管理员扣除了你%d积分", -points)
+	}
+
+	return &models.Notification{
+		UserID:     userID,
+		Type:       models.NotificationPoints,
+		Title:      title,
+		Content:    description,
+		TargetType: models.NotificationTargetPoints,
+	}
+}
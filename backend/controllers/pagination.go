@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCursorPageSize 游标分页每页的最大条数
+const maxCursorPageSize = 100
+
+var errInvalidCursor = errors.New("无效的游标")
+
+// sortKey 游标分页的一个排序键
+type sortKey struct {
+	Expr string // 排序的列或表达式
+	Desc bool
+	Time bool // 时间类型的值，游标中以RFC3339格式保存
+}
+
+// keyset 游标分页的排序规则
+// 最后一个排序键必须唯一（通常是ID），同值的记录才有确定的先后顺序
+type keyset []sortKey
+
+// order 按排序规则生成ORDER BY子句，页码分页也使用同样的顺序
+func (k keyset) order() string {
+	parts := make([]string, len(k))
+	for i, key := range k {
+		if key.Desc {
+			parts[i] = key.Expr + " DESC"
+		} else {
+			parts[i] = key.Expr + " ASC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// after 只保留排在游标位置之后的记录，values为空时表示第一页
+func (k keyset) after(query *gorm.DB, values []interface{}) *gorm.DB {
+	return k.compare(query, values, false)
+}
+
+// before 只保留排在某条记录之前的记录，用于计算记录所在的页码
+func (k keyset) before(query *gorm.DB, values []interface{}) *gorm.DB {
+	return k.compare(query, values, true)
+}
+
+// compare 按排序键逐列比较，各排序键方向不同，因此展开为 (a > ?) OR (a = ? AND b < ?) OR ...
+func (k keyset) compare(query *gorm.DB, values []interface{}, reverse bool) *gorm.DB {
+	if len(values) == 0 {
+		return query
+	}
+
+	var clauses []string
+	var args []interface{}
+	for i, key := range k {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, k[j].Expr+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.Desc != reverse {
+			op = " < ?"
+		}
+		conds = append(conds, key.Expr+op)
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(conds, " AND ")+")")
+	}
+	return query.Where("("+strings.Join(clauses, " OR ")+")", args...)
+}
+
+// encode 将一条记录的排序键编码为不透明的游标
+func (k keyset) encode(values ...interface{}) string {
+	encoded := make([]interface{}, len(values))
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		encoded[i] = value
+	}
+	data, _ := json.Marshal(encoded)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode 解析游标，排序键的个数和类型必须与排序规则一致
+func (k keyset) decode(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var raw []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil || len(raw) != len(k) {
+		return nil, errInvalidCursor
+	}
+
+	values := make([]interface{}, len(k))
+	for i, key := range k {
+		switch value := raw[i].(type) {
+		case string:
+			if !key.Time {
+				return nil, errInvalidCursor
+			}
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, errInvalidCursor
+			}
+			values[i] = t
+		case json.Number:
+			if key.Time {
+				return nil, errInvalidCursor
+			}
+			n, err := value.Int64()
+			if err != nil {
+				return nil, errInvalidCursor
+			}
+			values[i] = n
+		default:
+			return nil, errInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+// cursorParams 读取游标分页参数，请求中带有cursor参数时使用游标分页
+// cursor为空表示第一页；返回的排序键为空时从头开始
+func cursorParams(ctx *gin.Context, k keyset, defaultPageSize int) (values []interface{}, pageSize int, enabled bool, err error) {
+	cursor, enabled := ctx.GetQuery("cursor")
+	if !enabled {
+		return nil, 0, false, nil
+	}
+
+	pageSize, _ = strconv.Atoi(ctx.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if pageSize <= 0 || pageSize > maxCursorPageSize {
+		pageSize = defaultPageSize
+	}
+	if cursor == "" {
+		return nil, pageSize, true, nil
+	}
+
+	values, err = k.decode(cursor)
+	return values, pageSize, true, err
+}
+
+// boolKey 布尔类型的排序键在游标中保存为0或1
+func boolKey(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// dryRunDB 只生成SQL、不连接数据库的GORM实例
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:1)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestKeysetOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		keys keyset
+		want string
+	}{
+		{"单列", keyset{{Expr: "id"}}, "id ASC"},
+		{"时间倒序", keyset{{Expr: "created_at", Desc: true, Time: true}, {Expr: "id", Desc: true}}, "created_at DESC, id DESC"},
+		{"方向不同", replyKeyset("best"), "is_accepted DESC, vote_score DESC, created_at ASC, id ASC"},
+		{"默认回复排序", replyKeyset(""), "created_at ASC, id ASC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.keys.order(); got != tt.want {
+				t.Errorf("order() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetEncodeDecode(t *testing.T) {
+	at := time.Date(2024, 3, 1, 8, 30, 15, 123456789, time.FixedZone("CST", 8*3600))
+	tests := []struct {
+		name   string
+		keys   keyset
+		values []interface{}
+		want   []interface{}
+	}{
+		{"整数", keyset{{Expr: "id"}}, []interface{}{uint(42)}, []interface{}{int64(42)}},
+		{"负数", keyset{{Expr: "vote_score", Desc: true}, {Expr: "id"}}, []interface{}{-3, uint(7)}, []interface{}{int64(-3), int64(7)}},
+		{"时间转为UTC", keyset{{Expr: "created_at", Time: true}, {Expr: "id"}}, []interface{}{at, uint(1)}, []interface{}{at.UTC(), int64(1)}},
+		{"布尔键", replyKeyset("best"),
+			replyKeyValues("best", &models.Reply{ID: 9, IsAccepted: true, VoteScore: 5, CreatedAt: at}),
+			[]interface{}{int64(1), int64(5), at.UTC(), int64(9)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keys.decode(tt.keys.encode(tt.values...))
+			if err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode(encode()) = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetDecodeInvalid(t *testing.T) {
+	keys := keyset{{Expr: "created_at", Time: true}, {Expr: "id"}}
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"不是base64", "!!!"},
+		{"不是JSON", raw("not json")},
+		{"不是数组", raw(`{"id":1}`)},
+		{"键数不足", raw(`["2024-03-01T00:00:00Z"]`)},
+		{"键数过多", raw(`["2024-03-01T00:00:00Z",1,2]`)},
+		{"时间键为数字", raw(`[1,1]`)},
+		{"时间格式错误", raw(`["yesterday",1]`)},
+		{"数字键为字符串", raw(`["2024-03-01T00:00:00Z","1"]`)},
+		{"数字键为小数", raw(`["2024-03-01T00:00:00Z",1.5]`)},
+		{"空值", raw(`[null,1]`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keys.decode(tt.cursor); err != errInvalidCursor {
+				t.Errorf("decode(%q) error = %v, want errInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestKeysetCompare(t *testing.T) {
+	db := dryRunDB(t)
+	keys := keyset{{Expr: "score", Desc: true}, {Expr: "id"}}
+	tests := []struct {
+		name     string
+		build    func(*gorm.DB) *gorm.DB
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			"第一页不加条件",
+			func(q *gorm.DB) *gorm.DB { return keys.after(q, nil) },
+			"SELECT * FROM `replies` WHERE `replies`.`deleted_at` IS NULL",
+			nil,
+		},
+		{
+			"游标之后",
+			func(q *gorm.DB) *gorm.DB { return keys.after(q, []interface{}{5, 10}) },
+			"SELECT * FROM `replies` WHERE (((score < ?) OR (score = ? AND id > ?))) AND `replies`.`deleted_at` IS NULL",
+			[]interface{}{5, 5, 10},
+		},
+		{
+			"记录之前",
+			func(q *gorm.DB) *gorm.DB { return keys.before(q, []interface{}{5, 10}) },
+			"SELECT * FROM `replies` WHERE (((score > ?) OR (score = ? AND id < ?))) AND `replies`.`deleted_at` IS NULL",
+			[]interface{}{5, 5, 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var replies []models.Reply
+			stmt := tt.build(db.Model(&models.Reply{})).Find(&replies).Statement
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL = %q, want %q", got, tt.wantSQL)
+			}
+			if len(stmt.Vars) != len(tt.wantVars) || (len(tt.wantVars) > 0 && !reflect.DeepEqual(stmt.Vars, tt.wantVars)) {
+				t.Errorf("Vars = %#v, want %#v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestCursorParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := keyset{{Expr: "id"}}
+	valid := keys.encode(uint(3))
+	tests := []struct {
+		name         string
+		query        string
+		wantValues   []interface{}
+		wantPageSize int
+		wantEnabled  bool
+		wantErr      bool
+	}{
+		{"没有cursor参数时不使用游标", "?pageSize=5", nil, 0, false, false},
+		{"空cursor为第一页", "?cursor=", nil, 10, true, false},
+		{"自定义每页条数", "?cursor=&pageSize=30", nil, 30, true, false},
+		{"每页条数超过上限", "?cursor=&pageSize=1000", nil, 10, true, false},
+		{"每页条数无效", "?cursor=&pageSize=-1", nil, 10, true, false},
+		{"有效游标", "?cursor=" + valid, []interface{}{int64(3)}, 10, true, false},
+		{"无效游标", "?cursor=abc", nil, 10, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/"+tt.query, nil)
+
+			values, pageSize, enabled, err := cursorParams(ctx, keys, 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if enabled != tt.wantEnabled || pageSize != tt.wantPageSize {
+				t.Errorf("enabled, pageSize = %v, %d, want %v, %d", enabled, pageSize, tt.wantEnabled, tt.wantPageSize)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("values = %#v, want %#v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestFavoriteOrder(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"", "created_at DESC, id DESC"},
+		{"created_at desc", "created_at DESC, id DESC"},
+		{"CREATED_AT ASC", "created_at ASC, id ASC"},
+		{"oldest", "created_at ASC, id ASC"},
+		{"sort_order", "sort_order ASC, id ASC"},
+		{"id; DROP TABLE users", "created_at DESC, id DESC"},
+		{"(SELECT password FROM users LIMIT 1)", "created_at DESC, id DESC"},
+	}
+	for _, tt := range tests {
+		if got := favoriteOrder(tt.sort); got != tt.want {
+			t.Errorf("favoriteOrder(%q) = %q, want %q", tt.sort, got, tt.want)
+		}
+	}
+}
@@ -10,16 +10,18 @@ import (
 	"gorm.io/gorm"
 
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
 // PointsController 积分控制器
 type PointsController struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Notifier *utils.Notifier
 }
 
 // NewPointsController 创建积分控制器实例
-func NewPointsController(db *gorm.DB) *PointsController {
-	return &PointsController{DB: db}
+func NewPointsController(db *gorm.DB, notifier *utils.Notifier) *PointsController {
+	return &PointsController{DB: db, Notifier: notifier}
 }
 
 // GetUserPoints 获取用户积分信息
@@ -58,6 +60,37 @@ func (c *PointsController) GetPointsHistory(ctx *gin.Context) {
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
 
+	// 游标分页
+	keys := keyset{{Expr: "created_at", Desc: true, Time: true}, {Expr: "id", Desc: true}}
+	after, cursorSize, useCursor, err := cursorParams(ctx, keys, 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if useCursor {
+		var records []models.PointRecord
+		keys.after(c.DB.Where("user_id = ?", userID), after).
+			Preload("Resource").
+			Order(keys.order()).
+			Limit(cursorSize + 1).
+			Find(&records)
+
+		nextCursor := ""
+		hasMore := len(records) > cursorSize
+		if hasMore {
+			records = records[:cursorSize]
+			last := &records[cursorSize-1]
+			nextCursor = keys.encode(last.CreatedAt, last.ID)
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"records":     records,
+			"next_cursor": nextCursor,
+			"has_more":    hasMore,
+			"pageSize":    cursorSize,
+		})
+		return
+	}
+
 	// 查询积分记录
 	var records []models.PointRecord
 	var total int64
@@ -65,7 +98,7 @@ func (c *PointsController) GetPointsHistory(ctx *gin.Context) {
 	c.DB.Model(&models.PointRecord{}).Where("user_id = ?", userID).Count(&total)
 	c.DB.Where("user_id = ?", userID).
 		Preload("Resource").
-		Order("created_at DESC").
+		Order(keys.order()).
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
 		Find(&records)
@@ -144,6 +177,8 @@ func (c *PointsController) AddPoints(ctx *gin.Context) {
 	// 提交事务
 	tx.Commit()
 
+	c.Notifier.Notify(pointsAdjustedNotification(input.UserID, input.Points, input.Description))
+
 	// 返回结果
 	ctx.JSON(http.StatusOK, gin.H{
 		"message":     "积分添加成功",
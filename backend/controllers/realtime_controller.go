@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+const (
+	// realtimeWriteWait 单次写入的超时时间
+	realtimeWriteWait = 10 * time.Second
+	// realtimePongWait 等待客户端响应心跳的时间
+	realtimePongWait = 60 * time.Second
+	// realtimePingPeriod 服务端发送心跳的间隔，必须小于realtimePongWait
+	realtimePingPeriod = 50 * time.Second
+	// realtimeMaxChannels 单个连接最多订阅的频道数
+	realtimeMaxChannels = 50
+)
+
+var realtimeUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// 与CORS配置一致，允许任意来源；连接本身需要携带有效令牌
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// RealtimeController 实时推送控制器，支持WebSocket和SSE两种连接方式
+type RealtimeController struct {
+	DB  *gorm.DB
+	Hub *utils.RealtimeHub
+}
+
+// NewRealtimeController 创建实时推送控制器实例
+func NewRealtimeController(db *gorm.DB, hub *utils.RealtimeHub) *RealtimeController {
+	return &RealtimeController{DB: db, Hub: hub}
+}
+
+// realtimeCommand 客户端通过WebSocket发送的指令
+type realtimeCommand struct {
+	Action  string `json:"action"` // subscribe, unsubscribe, ping
+	Channel string `json:"channel"`
+}
+
+// ServeWebSocket 建立WebSocket连接
+// 连接后自动订阅自己的通知频道，其余频道通过subscribe指令订阅
+func (c *RealtimeController) ServeWebSocket(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	conn, err := realtimeUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// Upgrade失败时已经写入了错误响应
+		return
+	}
+
+	subscriber := c.Hub.NewSubscriber()
+	subscriber.Subscribe(utils.UserChannel(userID.(uint)))
+	done := make(chan struct{})
+
+	go c.writeWebSocket(conn, subscriber, done)
+
+	defer func() {
+		close(done)
+		subscriber.Close()
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(1024)
+	conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var command realtimeCommand
+		if err := json.Unmarshal(message, &command); err != nil {
+			sendRealtimeEvent(subscriber, "error", "", "指令格式无效")
+			continue
+		}
+
+		switch command.Action {
+		case "subscribe":
+			if len(subscriber.Channels()) >= realtimeMaxChannels {
+				sendRealtimeEvent(subscriber, "error", command.Channel, "订阅的频道过多")
+				continue
+			}
+			if msg := c.authorizeChannel(userID.(uint), command.Channel); msg != "" {
+				sendRealtimeEvent(subscriber, "error", command.Channel, msg)
+				continue
+			}
+			subscriber.Subscribe(command.Channel)
+			sendRealtimeEvent(subscriber, "subscribed", command.Channel, nil)
+		case "unsubscribe":
+			subscriber.Unsubscribe(command.Channel)
+			sendRealtimeEvent(subscriber, "unsubscribed", command.Channel, nil)
+		case "ping":
+			sendRealtimeEvent(subscriber, "pong", "", nil)
+		default:
+			sendRealtimeEvent(subscriber, "error", "", "未知的指令")
+		}
+	}
+}
+
+// writeWebSocket 将订阅到的事件写入WebSocket，并定时发送心跳
+func (c *RealtimeController) writeWebSocket(conn *websocket.Conn, subscriber *utils.RealtimeSubscriber, done chan struct{}) {
+	ticker := time.NewTicker(realtimePingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case payload := <-subscriber.Events:
+			conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				conn.Close()
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				conn.Close()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// ServeSSE 建立SSE连接，适用于只需要接收推送的客户端
+// 频道在连接时通过channels参数指定，多个频道用逗号分隔，自己的通知频道自动订阅
+func (c *RealtimeController) ServeSSE(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var channels []string
+	for _, channel := range strings.Split(ctx.Query("channels"), ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) > realtimeMaxChannels {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "订阅的频道过多"})
+		return
+	}
+	for _, channel := range channels {
+		if msg := c.authorizeChannel(userID.(uint), channel); msg != "" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": msg, "channel": channel})
+			return
+		}
+	}
+
+	subscriber := c.Hub.NewSubscriber()
+	defer subscriber.Close()
+
+	subscriber.Subscribe(utils.UserChannel(userID.(uint)))
+	for _, channel := range channels {
+		subscriber.Subscribe(channel)
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// 关闭Nginx的响应缓冲
+	ctx.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(realtimePingPeriod)
+	defer ticker.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case payload := <-subscriber.Events:
+			ctx.SSEvent("message", string(payload))
+			return true
+		case <-ticker.C:
+			// 注释行作为心跳，防止代理关闭空闲连接
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// authorizeChannel 校验用户能否订阅频道，返回错误信息，可以订阅时返回空字符串
+// topic:<id> 能查看主题的登录用户都可以订阅；user:<id> 只能订阅自己的；chat:<id> 只能订阅自己的会话
+func (c *RealtimeController) authorizeChannel(userID uint, channel string) string {
+	kind, rawID, found := strings.Cut(channel, ":")
+	if !found {
+		return "频道格式无效"
+	}
+
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || id == 0 {
+		return "频道格式无效"
+	}
+
+	switch kind {
+	case "topic":
+		var topic models.Topic
+		if err := c.DB.Select("id", "user_id", "is_hidden", "shadowed").First(&topic, id).Error; err != nil {
+			return "主题不存在"
+		}
+
+		// 隐藏和静默的主题按不存在处理
+		var user models.User
+		c.DB.Select("id", "role").First(&user, userID)
+		if !canViewTopic(&topic, resourceViewer{UserID: userID, IsAdmin: user.Role == "admin"}) {
+			return "主题不存在"
+		}
+	case "user":
+		if uint(id) != userID {
+			return "无权订阅此频道"
+		}
+	case "chat":
+		var session models.ChatSession
+		if err := c.DB.First(&session, id).Error; err != nil {
+			return "会话不存在"
+		}
+		if session.UserID != userID {
+			return "无权订阅此频道"
+		}
+	default:
+		return "未知的频道类型"
+	}
+
+	return ""
+}
+
+// sendRealtimeEvent 向单个连接发送控制消息，缓冲已满时丢弃
+func sendRealtimeEvent(subscriber *utils.RealtimeSubscriber, eventType, channel string, data interface{}) {
+	payload, err := json.Marshal(utils.RealtimeEvent{Type: eventType, Channel: channel, Data: data})
+	if err != nil {
+		return
+	}
+
+	select {
+	case subscriber.Events <- payload:
+	default:
+	}
+}
@@ -17,12 +17,20 @@ import (
 
 	"g/front/backend/config"
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
+// resourceURLExpires 资源临时下载URL的有效期
+const resourceURLExpires = 10 * time.Minute
+
 // ResourceController 资源控制器
 type ResourceController struct {
 	DB          *gorm.DB
 	MinioClient *minio.Client
+	Notifier    *utils.Notifier
+	Views       *utils.ViewCounter
+	Filter      *utils.ContentFilter // 敏感词过滤
+	Spam        *utils.SpamGuard     // 发帖反垃圾
 }
 
 // AddFavorite 添加资源收藏
@@ -44,21 +52,18 @@ func (c *ResourceController) AddFavorite(ctx *gin.Context) {
 		return
 	}
 
-	// 检查是否已收藏
-	var existingFavorite models.UserFavorite
-	if err := c.DB.Where("user_id = ? AND resource_id = ?", userID, resource.ID).First(&existingFavorite).Error; err == nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "已收藏该资源", "isFavorited": true})
+	// 收藏到默认收藏夹
+	folder, err := models.GetDefaultFavoriteFolder(c.DB, userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
 		return
 	}
 
-	// 创建收藏记录
-	favorite := models.UserFavorite{
-		UserID:     userID.(uint),
-		ResourceID: resource.ID,
-		CreatedAt:  time.Now(),
-	}
-
-	if err := c.DB.Create(&favorite).Error; err != nil {
+	if _, err := addFavoriteItem(c.DB, userID.(uint), folder.ID, models.FavoriteItemResource, resource.ID, ""); err != nil {
+		if err == errFavoriteExists {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "已收藏该资源", "isFavorited": true})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
 		return
 	}
@@ -86,7 +91,7 @@ func (c *ResourceController) RemoveFavorite(ctx *gin.Context) {
 	}
 
 	// 删除收藏记录
-	if err := c.DB.Where("user_id = ? AND resource_id = ?", userID, resource.ID).Delete(&models.UserFavorite{}).Error; err != nil {
+	if err := c.DB.Where("user_id = ? AND item_type = ? AND item_id = ?", userID, models.FavoriteItemResource, resource.ID).Delete(&models.FavoriteItem{}).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "取消收藏失败"})
 		return
 	}
@@ -114,8 +119,7 @@ func (c *ResourceController) GetFavoriteStatus(ctx *gin.Context) {
 	}
 
 	// 检查是否已收藏
-	var existingFavorite models.UserFavorite
-	if err := c.DB.Where("user_id = ? AND resource_id = ?", userID, resource.ID).First(&existingFavorite).Error; err != nil {
+	if !isFavorited(c.DB, userID, models.FavoriteItemResource, resource.ID) {
 		ctx.JSON(http.StatusOK, gin.H{"is_favorite": false, "isFavorited": false})
 		return
 	}
@@ -133,21 +137,30 @@ func (c *ResourceController) GetUserFavorites(ctx *gin.Context) {
 	}
 
 	// 查询用户收藏的资源
-	var favorites []models.UserFavorite
-	if err := c.DB.Preload("Resource").Preload("Resource.User").Where("user_id = ?", userID).Find(&favorites).Error; err != nil {
+	var favorites []models.FavoriteItem
+	if err := c.DB.Where("user_id = ? AND item_type = ?", userID, models.FavoriteItemResource).Order("created_at DESC").Find(&favorites).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏列表失败"})
 		return
 	}
 
 	// 格式化返回数据
+	viewer := getResourceViewer(ctx, c.DB)
 	var resources []gin.H
 	for _, favorite := range favorites {
+		var resource models.Resource
+		if err := c.DB.Preload("User").Preload("Category").First(&resource, favorite.ItemID).Error; err != nil ||
+			!canViewResource(c.DB, &resource, viewer) {
+			continue // 跳过已删除或不能再查看的资源
+		}
+
 		resources = append(resources, gin.H{
-			"id":          favorite.Resource.ID,
-			"title":       favorite.Resource.Title,
-			"description": favorite.Resource.Description,
-			"category":    favorite.Resource.Category,
-			"user":        favorite.Resource.User,
+			"id":          resource.ID,
+			"title":       resource.Title,
+			"description": resource.Description,
+			"category":    resource.Category,
+			"user":        resource.User,
+			"folder_id":   favorite.FolderID,
+			"note":        favorite.Note,
 			"created_at":  favorite.CreatedAt,
 		})
 	}
@@ -156,8 +169,8 @@ func (c *ResourceController) GetUserFavorites(ctx *gin.Context) {
 }
 
 // NewResourceController 创建资源控制器实例
-func NewResourceController(db *gorm.DB, minioClient *minio.Client) *ResourceController {
-	return &ResourceController{DB: db, MinioClient: minioClient}
+func NewResourceController(db *gorm.DB, minioClient *minio.Client, notifier *utils.Notifier, views *utils.ViewCounter, filter *utils.ContentFilter, spam *utils.SpamGuard) *ResourceController {
+	return &ResourceController{DB: db, MinioClient: minioClient, Notifier: notifier, Views: views, Filter: filter, Spam: spam}
 }
 
 // DeleteUserResource 删除用户资源
@@ -206,8 +219,9 @@ func (c *ResourceController) GetResources(ctx *gin.Context) {
 	// 获取搜索关键词
 	query := ctx.Query("query")
 
-	// 构建查询
+	// 构建查询，只返回访问者可见的资源
 	dbQuery := c.DB.Model(&models.Resource{}).Where("status = ?", "approved")
+	dbQuery = visibleResources(dbQuery, getResourceViewer(ctx, c.DB))
 
 	// 关键词搜索
 	if query != "" {
@@ -224,14 +238,53 @@ func (c *ResourceController) GetResources(ctx *gin.Context) {
 		dbQuery = dbQuery.Where("category_id = ?", categoryID)
 	}
 
+	// 标签过滤，多个标签以逗号分隔，只返回带有全部标签的资源
+	if tags := ctx.Query("tag"); tags != "" {
+		dbQuery = filterTagged(dbQuery, "resources", "resource_tags", "resource_id", tags)
+	}
+
 	// 排序
-	switch sort {
-	case "newest":
-		dbQuery = dbQuery.Order("created_at DESC")
-	case "popular":
-		dbQuery = dbQuery.Order("download_count DESC")
-	default:
-		dbQuery = dbQuery.Order("created_at DESC")
+	keys := keyset{{Expr: "resources.created_at", Desc: true, Time: true}, {Expr: "resources.id", Desc: true}}
+	keyValues := func(resource *models.Resource) []interface{} {
+		return []interface{}{resource.CreatedAt, resource.ID}
+	}
+	if sort == "popular" {
+		keys = keyset{{Expr: "resources.download_count", Desc: true}, {Expr: "resources.id", Desc: true}}
+		keyValues = func(resource *models.Resource) []interface{} {
+			return []interface{}{resource.DownloadCount, resource.ID}
+		}
+	}
+
+	// 游标分页：不统计总数，数据变化时不会重复或遗漏
+	after, cursorSize, useCursor, err := cursorParams(ctx, keys, 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if useCursor {
+		var resources []models.Resource
+		keys.after(dbQuery, after).Preload("User").Preload("Category").Preload("Tags").
+			Order(keys.order()).
+			Limit(cursorSize + 1).
+			Find(&resources)
+
+		nextCursor := ""
+		hasMore := len(resources) > cursorSize
+		if hasMore {
+			resources = resources[:cursorSize]
+			nextCursor = keys.encode(keyValues(&resources[cursorSize-1])...)
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"resources":   resources,
+			"next_cursor": nextCursor,
+			"has_more":    hasMore,
+			"pageSize":    cursorSize,
+			"query":       ctx.Query("query"),
+			"category":    ctx.Query("category"),
+			"tag":         ctx.Query("tag"),
+			"sort":        ctx.DefaultQuery("sort", "newest"),
+		})
+		return
 	}
 
 	// 执行查询
@@ -239,7 +292,8 @@ func (c *ResourceController) GetResources(ctx *gin.Context) {
 	var total int64
 
 	dbQuery.Count(&total)
-	dbQuery.Preload("User").Preload("Category").
+	dbQuery.Preload("User").Preload("Category").Preload("Tags").
+		Order(keys.order()).
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
 		Find(&resources)
@@ -252,6 +306,7 @@ func (c *ResourceController) GetResources(ctx *gin.Context) {
 		"pageSize":  pageSize,
 		"query":     ctx.Query("query"),                 // 返回原始查询关键词
 		"category":  ctx.Query("category"),              // 返回分类ID
+		"tag":       ctx.Query("tag"),                   // 返回标签过滤
 		"sort":      ctx.DefaultQuery("sort", "newest"), // 返回排序方式
 	})
 }
@@ -261,17 +316,89 @@ func (c *ResourceController) GetResourceById(ctx *gin.Context) {
 	id := ctx.Param("id")
 
 	var resource models.Resource
-	result := c.DB.Preload("User").Preload("Category").First(&resource, id)
+	result := c.DB.Preload("User").Preload("Category").Preload("Tags").First(&resource, id)
 	if result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
 		return
 	}
 
-	// 增加浏览次数逻辑可以在这里添加
+	// 检查可见范围
+	viewer := getResourceViewer(ctx, c.DB)
+	if !checkResourceAccess(ctx, c.DB, &resource, viewer) {
+		return
+	}
+
+	// 增加浏览次数，同一访客短时间内重复浏览只计一次
+	resource.ViewCount += recordView(ctx, c.Views, utils.ViewTargetResource, resource.ID, viewer.UserID)
+
+	// 资源存储桶是私有的，图片资源返回短期有效的预览URL
+	if isImageResource(resource.FileType, resource.FilePath) {
+		files := &utils.MinioUtils{Client: c.MinioClient, BucketName: config.GetEnv("resources", "resources")}
+		if previewURL, err := files.GetFileURL(resource.FilePath, resourceURLExpires); err == nil {
+			resource.PreviewURL = previewURL
+		}
+	}
 
 	ctx.JSON(http.StatusOK, resource)
 }
 
+// isImageResource 资源是否为可以直接预览的图片
+func isImageResource(fileType, filePath string) bool {
+	if strings.HasPrefix(strings.ToLower(fileType), "image/") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp":
+		return true
+	}
+	return false
+}
+
+// GetRecommendations 获取相关资源推荐
+// 优先推荐同分类下载量高的资源，不足时用其他热门资源补齐，只推荐访问者可见的资源
+func (c *ResourceController) GetRecommendations(ctx *gin.Context) {
+	var resource models.Resource
+	if err := c.DB.First(&resource, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+
+	viewer := getResourceViewer(ctx, c.DB)
+	if !checkResourceAccess(ctx, c.DB, &resource, viewer) {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "6"))
+	if limit <= 0 || limit > 20 {
+		limit = 6
+	}
+
+	// 同分类资源
+	var recommendations []models.Resource
+	sameCategory := c.DB.Model(&models.Resource{}).
+		Where("status = ? AND id <> ? AND category_id = ?", "approved", resource.ID, resource.CategoryID)
+	visibleResources(sameCategory, viewer).
+		Preload("User").Preload("Category").
+		Order("download_count DESC").
+		Limit(limit).
+		Find(&recommendations)
+
+	// 其他热门资源补齐
+	if len(recommendations) < limit {
+		var others []models.Resource
+		otherCategory := c.DB.Model(&models.Resource{}).
+			Where("status = ? AND id <> ? AND category_id <> ?", "approved", resource.ID, resource.CategoryID)
+		visibleResources(otherCategory, viewer).
+			Preload("User").Preload("Category").
+			Order("download_count DESC").
+			Limit(limit - len(recommendations)).
+			Find(&others)
+		recommendations = append(recommendations, others...)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"resources": recommendations})
+}
+
 // GetResourceDownloadUrl 获取资源下载URL
 func (c *ResourceController) GetResourceDownloadUrl(ctx *gin.Context) {
 	id := ctx.Param("id")
@@ -283,28 +410,51 @@ func (c *ResourceController) GetResourceDownloadUrl(ctx *gin.Context) {
 		return
 	}
 
+	// 检查可见范围
+	if !checkResourceAccess(ctx, c.DB, &resource, getResourceViewer(ctx, c.DB)) {
+		return
+	}
+
 	// 检查MinIO文件是否存在
-	ctxBg := context.Background()
-	_, err := c.MinioClient.StatObject(ctxBg, config.GetEnv("resources", "resources"), resource.FilePath, minio.StatObjectOptions{})
+	bucketName := config.GetEnv("resources", "resources")
+	_, err := c.MinioClient.StatObject(context.Background(), bucketName, resource.FilePath, minio.StatObjectOptions{})
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"error": "文件不存在",
-			"details": gin.H{
-				"bucket": config.GetEnv("resources", "resources"),
-				"key":    resource.FilePath,
-			},
-		})
+		log.Printf("获取文件信息失败: %v, 资源ID: %d", err, resource.ID)
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
 		return
 	}
 
-	// 生成MinIO下载URL
-	endpoint := config.GetEnv("MINIO_ENDPOINT", "47.121.210.209:9001")
-	bucketName := config.GetEnv("resources", "resources")
-	fileURL := fmt.Sprintf("http://%s/%s/%s", endpoint, bucketName, resource.FilePath)
+	// 需要水印的PDF资源，下载用户专属的带水印副本
+	objectKey := resource.FilePath
+	if resource.WatermarkOnDownload && isPDFResource(resource.FileType, resource.FilePath) {
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		objectKey, err = watermarkedObjectKey(c.DB, c.MinioClient, bucketName, &resource, userID.(uint), 0)
+		if err != nil {
+			log.Printf("生成水印文件失败: %v, 资源ID: %d", err, resource.ID)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成水印文件失败"})
+			return
+		}
+	}
+
+	// 资源存储桶是私有的，只返回短期有效的临时下载URL
+	fileName := resourceFileName(&resource)
+	files := &utils.MinioUtils{Client: c.MinioClient, BucketName: bucketName}
+	fileURL, err := files.GetDownloadURL(objectKey, fileName, resourceURLExpires)
+	if err != nil {
+		log.Printf("生成下载URL失败: %v, 资源ID: %d", err, resource.ID)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成下载URL失败"})
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"url":      fileURL,
-		"filename": resource.FilePath,
+		"url":        fileURL,
+		"filename":   fileName,
+		"expires_in": int(resourceURLExpires.Seconds()),
 	})
 }
 
@@ -349,8 +499,9 @@ func (c *ResourceController) Search(ctx *gin.Context) {
 		pageSize = 12
 	}
 
-	// 构建查询条件
-	query := c.DB.Model(&models.Resource{}).Where("status = ?", "approved")
+	// 构建查询条件，只返回访问者可见的资源
+	query := c.DB.Model(&models.Resource{}).Where("resources.status = ?", "approved")
+	query = visibleResources(query, getResourceViewer(ctx, c.DB))
 
 	// 关键词搜索
 	if q != "" {
@@ -429,21 +580,76 @@ func (c *ResourceController) GetComments(ctx *gin.Context) {
 		return
 	}
 
+	// 不可见资源的评论同样不可见
+	var resource models.Resource
+	if err := c.DB.First(&resource, resourceID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+	viewer := getResourceViewer(ctx, c.DB)
+	if !checkResourceAccess(ctx, c.DB, &resource, viewer) {
+		return
+	}
+
 	// 分页参数
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
 
+	// 排序方式：best 按得分从高到低，默认最新的在前
+	keys := keyset{{Expr: "time", Desc: true, Time: true}, {Expr: "id", Desc: true}}
+	keyValues := func(comment *models.Comment) []interface{} {
+		return []interface{}{comment.Time, comment.ID}
+	}
+	if ctx.Query("sort") == "best" {
+		keys = append(keyset{{Expr: "vote_score", Desc: true}}, keys...)
+		keyValues = func(comment *models.Comment) []interface{} {
+			return []interface{}{comment.VoteScore, comment.Time, comment.ID}
+		}
+	}
+
+	// 游标分页
+	after, cursorSize, useCursor, err := cursorParams(ctx, keys, 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if useCursor {
+		var comments []models.Comment
+		keys.after(visiblePublished(c.DB.Where("resource_id = ?", resourceID), "comments", viewer), after).
+			Preload("User").
+			Order(keys.order()).
+			Limit(cursorSize + 1).
+			Find(&comments)
+
+		nextCursor := ""
+		hasMore := len(comments) > cursorSize
+		if hasMore {
+			comments = comments[:cursorSize]
+			nextCursor = keys.encode(keyValues(&comments[cursorSize-1])...)
+		}
+		fillCommentVotes(c.DB, viewer.UserID, comments)
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"comments":    comments,
+			"next_cursor": nextCursor,
+			"has_more":    hasMore,
+			"pageSize":    cursorSize,
+		})
+		return
+	}
+
 	// 查询评论
 	var comments []models.Comment
 	var total int64
 
-	c.DB.Model(&models.Comment{}).Where("resource_id = ?", resourceID).Count(&total)
-	c.DB.Where("resource_id = ?", resourceID).
-		Preload("User").
-		Order("time DESC").
+	query := visiblePublished(c.DB.Model(&models.Comment{}).Where("resource_id = ?", resourceID), "comments", viewer)
+	query.Count(&total)
+	query.Preload("User").
+		Order(keys.order()).
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
 		Find(&comments)
+	fillCommentVotes(c.DB, viewer.UserID, comments)
 
 	// 返回评论列表
 	ctx.JSON(http.StatusOK, gin.H{
@@ -515,6 +721,15 @@ func (c *ResourceController) PostComment(ctx *gin.Context) {
 		return
 	}
 
+	var resource models.Resource
+	if err := c.DB.First(&resource, resourceID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+	if !checkResourceAccess(ctx, c.DB, &resource, getResourceViewer(ctx, c.DB)) {
+		return
+	}
+
 	// 解析请求数据
 	var request struct {
 		Rating  int    `json:"rating" binding:"required,min=1,max=5"`
@@ -525,13 +740,30 @@ func (c *ResourceController) PostComment(ctx *gin.Context) {
 		return
 	}
 
+	// 命中审核词的评论进入审核队列，审核通过前只有作者自己能看到
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID, models.ModerationSourceComment,
+		textField{"content", &request.Content})
+	if !ok {
+		return
+	}
+
+	posting, ok := guardPosting(ctx, c.DB, c.Spam, userID, utils.SpamActionComment, request.Content)
+	if !ok {
+		return
+	}
+
 	// 创建评论
+	contentHTML, mentionedUsers := renderWithMentions(c.DB, request.Content)
 	comment := models.Comment{
-		ResourceID: resourceID,
-		UserID:     userID,
-		Rating:     request.Rating,
-		Content:    request.Content,
-		Time:       time.Now(),
+		ResourceID:    resourceID,
+		UserID:        userID,
+		Rating:        request.Rating,
+		Content:       request.Content,
+		ContentHTML:   contentHTML,
+		RenderVersion: utils.MarkdownRenderVersion,
+		Time:          time.Now(),
+		Shadowed:      posting.Shadowed,
+		Pending:       moderation.Review,
 	}
 
 	// 保存到数据库
@@ -541,12 +773,30 @@ func (c *ResourceController) PostComment(ctx *gin.Context) {
 		return
 	}
 
+	moderation.record(c.DB, comment.ID)
+	posting.done(ctx.Request.Context())
+
+	// 待审核的评论在审核通过后再通知被@的用户
+	if !comment.Pending {
+		notifyCommentMentions(c.DB, c.Notifier, &comment, &resource, mentionedUsers)
+	}
+
 	// 返回新创建的评论
 	ctx.JSON(http.StatusCreated, gin.H{
 		"comment": comment,
 	})
 }
 
+// notifyCommentMentions 保存评论中的@提及并通知被@的用户，静默封禁期间的评论不通知
+func notifyCommentMentions(db *gorm.DB, notifier *utils.Notifier, comment *models.Comment, resource *models.Resource, users map[string]uint) {
+	mentioned := syncMentions(db, models.MentionSourceComment, comment.ID, comment.UserID, users)
+	if len(mentioned) == 0 || comment.Shadowed {
+		return
+	}
+	notifier.NotifyMany(mentioned, mentionNotification(db, comment.UserID, "资源《"+resource.Title+"》的评论",
+		comment.Content, models.NotificationTargetResource, resource.ID, nil))
+}
+
 // UploadResource 上传资源文件
 func (c *ResourceController) UploadResource(ctx *gin.Context) {
 	// 获取上传的文件
@@ -651,18 +901,92 @@ func (c *ResourceController) UploadResource(ctx *gin.Context) {
 		return
 	}
 
+	// 资源本身需要审核，命中审核词时只记录，供审核时参考
+	moderation, blocked := checkContent(c.DB, c.Filter, userID, models.ModerationSourceResource,
+		textField{"title", &title}, textField{"description", &description})
+	if len(blocked) > 0 {
+		tx.Rollback()
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": blockedMessage(blocked),
+		})
+		return
+	}
+
+	posting, rejection := checkPosting(ctx.Request.Context(), c.DB, c.Spam, userID, utils.SpamActionResource, title+"\n"+description)
+	if rejection != nil {
+		tx.Rollback()
+		writeSpamRejection(ctx, rejection, gin.H{
+			"success": false,
+			"message": rejection.Message,
+		})
+		return
+	}
+
+	// 下载时添加水印，仅支持PDF
+	watermarkOnDownload := ctx.PostForm("watermark_on_download") == "true"
+	if watermarkOnDownload && !isPDFResource(header.Header.Get("Content-Type"), fileName) {
+		tx.Rollback()
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "只有PDF资源支持下载水印",
+		})
+		return
+	}
+
+	// 可见范围，默认公开
+	visibility := ctx.DefaultPostForm("visibility", models.VisibilityPublic)
+	var groupID *uint
+	if groupIDStr := ctx.PostForm("group_id"); groupIDStr != "" && visibility == models.VisibilityGroup {
+		if id, err := strconv.ParseUint(groupIDStr, 10, 64); err == nil {
+			value := uint(id)
+			groupID = &value
+		}
+	}
+	if msg := validateResourceVisibility(c.DB, userID, visibility, groupID); msg != "" {
+		tx.Rollback()
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	// 标签，多个标签以逗号分隔
+	tags, msg := pickTags(c.DB, c.Filter, getResourceViewer(ctx, c.DB), nil, splitTagNames(ctx.PostForm("tags")))
+	if msg != "" {
+		tx.Rollback()
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+	if err := saveTags(tx, tags); err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "保存标签失败",
+		})
+		return
+	}
+
 	// 创建资源记录
 	resource := models.Resource{
-		Title:       title,
-		Description: description,
-		CategoryID:  uint(categoryID),
-		FilePath:    fileName,
-		FileSize:    header.Size,
-		FileType:    header.Header.Get("Content-Type"),
-		Status:      "pending",
-		UserID:      userID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Title:               title,
+		Description:         description,
+		CategoryID:          uint(categoryID),
+		FilePath:            fileName,
+		FileSize:            header.Size,
+		FileType:            header.Header.Get("Content-Type"),
+		Status:              "pending",
+		WatermarkOnDownload: watermarkOnDownload,
+		Visibility:          visibility,
+		GroupID:             groupID,
+		UserID:              userID,
+		Tags:                tags,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
 	}
 
 	log.Printf("准备插入资源记录: %+v\n", resource)
@@ -744,6 +1068,11 @@ func (c *ResourceController) UploadResource(ctx *gin.Context) {
 		return
 	}
 
+	// 发布后删除草稿
+	discardDraft(c.DB, userID, models.DraftContextResource, 0)
+	moderation.record(c.DB, resource.ID)
+	posting.done(ctx.Request.Context())
+
 	// 返回文件URL和资源ID
 	endpoint := config.GetEnv("MINIO_ENDPOINT", "47.121.210.209:9000")
 	fileURL := fmt.Sprintf("%s/%s/%s", endpoint, bucketName, fileName)
@@ -795,6 +1124,9 @@ func (c *ResourceController) LikeResource(ctx *gin.Context) {
 		var likeCount int64
 		c.DB.Model(&models.UserLike{}).Where("resource_id = ?", resourceID).Count(&likeCount)
 		ctx.JSON(http.StatusOK, gin.H{"isLiked": true, "likes": likeCount})
+
+		c.Notifier.Notify(likeNotification(c.DB, userID.(uint), resource.UserID,
+			models.NotificationTargetResource, resource.ID, "你的资源《"+resource.Title+"》"))
 	}
 }
 
@@ -867,27 +1199,25 @@ func (c *ResourceController) FavoriteResource(ctx *gin.Context) {
 		return
 	}
 
-	var userFavorite models.UserFavorite
-	result := c.DB.Where("user_id = ? AND resource_id = ?", userID, resourceID).First(&userFavorite)
+	var favorite models.FavoriteItem
+	result := c.DB.Where("user_id = ? AND item_type = ? AND item_id = ?", userID, models.FavoriteItemResource, resourceID).First(&favorite)
 
 	if result.Error == nil {
 		// 取消收藏
-		c.DB.Delete(&userFavorite)
+		c.DB.Delete(&favorite)
 		ctx.JSON(http.StatusOK, gin.H{"isFavorited": false})
 	} else {
-		// 收藏
-		userIDStr, ok := userID.(string)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID格式"})
+		// 收藏到默认收藏夹
+		resourceIDUint, _ := strconv.ParseUint(resourceID, 10, 32)
+		folder, err := models.GetDefaultFavoriteFolder(c.DB, userID.(uint))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
 			return
 		}
-		userIDUint, _ := strconv.ParseUint(userIDStr, 10, 32)
-		resourceIDUint, _ := strconv.ParseUint(resourceID, 10, 32)
-		userFavorite = models.UserFavorite{
-			UserID:     uint(userIDUint),
-			ResourceID: uint(resourceIDUint),
+		if _, err := addFavoriteItem(c.DB, userID.(uint), folder.ID, models.FavoriteItemResource, uint(resourceIDUint), ""); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+			return
 		}
-		c.DB.Create(&userFavorite)
 		ctx.JSON(http.StatusOK, gin.H{"isFavorited": true})
 	}
 }
@@ -975,6 +1305,7 @@ func (c *ResourceController) SearchResources(ctx *gin.Context) {
 	// 构建查询
 	dbQuery := c.DB.Model(&models.Resource{}).
 		Where("status = ?", "approved")
+	dbQuery = visibleResources(dbQuery, getResourceViewer(ctx, c.DB))
 
 	// 关键词搜索
 	if query != "" {
@@ -1025,13 +1356,17 @@ func (c *ResourceController) CreateResource(ctx *gin.Context) {
 
 	// 绑定请求数据
 	var input struct {
-		Title          string `json:"title" binding:"required"`
-		Description    string `json:"description" binding:"required"`
-		CategoryID     uint   `json:"category_id" binding:"required"`
-		FilePath       string `json:"file_path" binding:"required"`
-		FileSize       int64  `json:"file_size" binding:"required"`
-		FileType       string `json:"file_type" binding:"required"`
-		PointsRequired int    `json:"points_required"`
+		Title               string   `json:"title" binding:"required"`
+		Description         string   `json:"description" binding:"required"`
+		CategoryID          uint     `json:"category_id" binding:"required"`
+		FilePath            string   `json:"file_path" binding:"required"`
+		FileSize            int64    `json:"file_size" binding:"required"`
+		FileType            string   `json:"file_type" binding:"required"`
+		PointsRequired      int      `json:"points_required"`
+		WatermarkOnDownload bool     `json:"watermark_on_download"` // 下载时添加水印，仅支持PDF
+		Visibility          string   `json:"visibility"`            // public, logged_in, group, private
+		GroupID             *uint    `json:"group_id"`
+		Tags                []string `json:"tags"` // 可选的标签
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -1039,6 +1374,30 @@ func (c *ResourceController) CreateResource(ctx *gin.Context) {
 		return
 	}
 
+	// 资源本身需要审核，命中审核词时只记录，供审核时参考
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceResource,
+		textField{"title", &input.Title}, textField{"description", &input.Description})
+	if !ok {
+		return
+	}
+
+	// 校验可见范围，默认公开
+	if input.Visibility == "" {
+		input.Visibility = models.VisibilityPublic
+	}
+	if msg := validateResourceVisibility(c.DB, userID.(uint), input.Visibility, input.GroupID); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if input.Visibility != models.VisibilityGroup {
+		input.GroupID = nil
+	}
+
+	if input.WatermarkOnDownload && !isPDFResource(input.FileType, input.FilePath) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "只有PDF资源支持下载水印"})
+		return
+	}
+
 	// 检查分类是否存在
 	var category models.Category
 	if result := c.DB.First(&category, input.CategoryID); result.Error != nil {
@@ -1046,40 +1405,71 @@ func (c *ResourceController) CreateResource(ctx *gin.Context) {
 		return
 	}
 
+	// 检查标签，保留标签只有管理员能添加
+	tags, msg := pickTags(c.DB, c.Filter, getResourceViewer(ctx, c.DB), nil, input.Tags)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	posting, ok := guardPosting(ctx, c.DB, c.Spam, userID.(uint), utils.SpamActionResource, input.Title+"\n"+input.Description)
+	if !ok {
+		return
+	}
+
 	// 创建资源记录
 	resource := models.Resource{
-		Title:          input.Title,
-		Description:    input.Description,
-		CategoryID:     input.CategoryID,
-		FilePath:       input.FilePath,
-		FileSize:       input.FileSize,
-		FileType:       input.FileType,
-		PointsRequired: input.PointsRequired,
-		Status:         "pending", // 默认为待审核状态
-		UserID:         userID.(uint),
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
-	}
-
-	if result := c.DB.Create(&resource); result.Error != nil {
+		Title:               input.Title,
+		Description:         input.Description,
+		CategoryID:          input.CategoryID,
+		FilePath:            input.FilePath,
+		FileSize:            input.FileSize,
+		FileType:            input.FileType,
+		PointsRequired:      input.PointsRequired,
+		WatermarkOnDownload: input.WatermarkOnDownload,
+		Visibility:          input.Visibility,
+		GroupID:             input.GroupID,
+		Status:              "pending", // 默认为待审核状态
+		UserID:              userID.(uint),
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTags(tx, tags); err != nil {
+			return err
+		}
+		resource.Tags = tags
+		return tx.Create(&resource).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建资源失败"})
 		return
 	}
 
-	// 添加积分记录（上传资源奖励积分）
-	pointRecord := models.PointRecord{
-		UserID:      userID.(uint),
-		Points:      10, // 上传资源奖励10积分
-		Type:        "upload",
-		ResourceID:  &resource.ID,
-		Description: "上传资源奖励",
-		CreatedAt:   time.Now(),
-	}
+	posting.done(ctx.Request.Context())
 
-	c.DB.Create(&pointRecord)
+	// 被静默封禁的用户上传资源不奖励积分
+	if !posting.Shadowed {
+		// 添加积分记录（上传资源奖励积分）
+		pointRecord := models.PointRecord{
+			UserID:      userID.(uint),
+			Points:      10, // 上传资源奖励10积分
+			Type:        "upload",
+			ResourceID:  &resource.ID,
+			Description: "上传资源奖励",
+			CreatedAt:   time.Now(),
+		}
+
+		c.DB.Create(&pointRecord)
+
+		// 更新用户积分
+		c.DB.Model(&models.User{}).Where("id = ?", userID).Update("points", gorm.Expr("points + ?", 10))
+	}
 
-	// 更新用户积分
-	c.DB.Model(&models.User{}).Where("id = ?", userID).Update("points", gorm.Expr("points + ?", 10))
+	// 发布后删除草稿
+	discardDraft(c.DB, resource.UserID, models.DraftContextResource, 0)
+	moderation.record(c.DB, resource.ID)
 
 	// 返回创建的资源
 	ctx.JSON(http.StatusCreated, resource)
@@ -1113,10 +1503,14 @@ func (c *ResourceController) UpdateResource(ctx *gin.Context) {
 
 	// 绑定请求数据
 	var input struct {
-		Title          string `json:"title"`
-		Description    string `json:"description"`
-		CategoryID     uint   `json:"category_id"`
-		PointsRequired int    `json:"points_required"`
+		Title               string    `json:"title"`
+		Description         string    `json:"description"`
+		CategoryID          uint      `json:"category_id"`
+		PointsRequired      int       `json:"points_required"`
+		WatermarkOnDownload *bool     `json:"watermark_on_download"` // 下载时添加水印，仅支持PDF
+		Visibility          string    `json:"visibility"`            // public, logged_in, group, private
+		GroupID             *uint     `json:"group_id"`
+		Tags                *[]string `json:"tags"` // 不传时保持原有标签
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -1124,6 +1518,12 @@ func (c *ResourceController) UpdateResource(ctx *gin.Context) {
 		return
 	}
 
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceResource,
+		textField{"title", &input.Title}, textField{"description", &input.Description})
+	if !ok {
+		return
+	}
+
 	// 更新资源
 	updates := map[string]interface{}{
 		"updated_at": time.Now(),
@@ -1151,22 +1551,113 @@ func (c *ResourceController) UpdateResource(ctx *gin.Context) {
 		updates["points_required"] = input.PointsRequired
 	}
 
+	if input.WatermarkOnDownload != nil {
+		if *input.WatermarkOnDownload && !isPDFResource(resource.FileType, resource.FilePath) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "只有PDF资源支持下载水印"})
+			return
+		}
+		updates["watermark_on_download"] = *input.WatermarkOnDownload
+	}
+
+	if input.Visibility != "" {
+		if msg := validateResourceVisibility(c.DB, userID.(uint), input.Visibility, input.GroupID); msg != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["visibility"] = input.Visibility
+		if input.Visibility == models.VisibilityGroup {
+			updates["group_id"] = *input.GroupID
+		} else {
+			updates["group_id"] = nil
+		}
+	}
+
+	// 修改标签，拥有者不能移除管理员添加的保留标签
+	var tags []models.Tag
+	if input.Tags != nil {
+		var current []models.Tag
+		c.DB.Model(&resource).Association("Tags").Find(&current)
+		var msg string
+		if tags, msg = pickTags(c.DB, c.Filter, getResourceViewer(ctx, c.DB), current, *input.Tags); msg != "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
 	// 更新状态为待审核
 	updates["status"] = "pending"
 
 	// 保存更新
-	if result := c.DB.Model(&resource).Updates(updates); result.Error != nil {
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if input.Tags != nil {
+			if err := replaceTags(tx, &resource, tags); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&resource).Updates(updates).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新资源失败"})
 		return
 	}
 
+	// 修改提交后删除草稿
+	discardDraft(c.DB, resource.UserID, models.DraftContextResource, resource.ID)
+	moderation.record(c.DB, resource.ID)
+
 	// 重新查询资源以获取最新信息
-	c.DB.Preload("User").Preload("Category").First(&resource, id)
+	c.DB.Preload("User").Preload("Category").Preload("Tags").First(&resource, id)
 
 	// 返回更新后的资源
 	ctx.JSON(http.StatusOK, resource)
 }
 
+// SetResourceTags 修改资源的标签，拥有者和管理员可以修改，修改标签不需要重新审核
+// 管理员可以添加和移除保留标签，拥有者修改时原有的保留标签保持不变
+func (c *ResourceController) SetResourceTags(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var resource models.Resource
+	if err := c.DB.Preload("Tags").First(&resource, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+
+	viewer := getResourceViewer(ctx, c.DB)
+	if resource.UserID != userID.(uint) && !viewer.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权修改此资源"})
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, msg := pickTags(c.DB, c.Filter, viewer, resource.Tags, input.Tags)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := c.DB.Transaction(func(tx *gorm.DB) error { return replaceTags(tx, &resource, tags) }); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "修改标签失败"})
+		return
+	}
+
+	result := []models.Tag{}
+	c.DB.Model(&resource).Association("Tags").Find(&result)
+	ctx.JSON(http.StatusOK, gin.H{"tags": result})
+}
+
 // DeleteResource 删除资源
 func (c *ResourceController) DeleteResource(ctx *gin.Context) {
 	// 从上下文获取用户ID
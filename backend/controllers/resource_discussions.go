@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+const (
+	// maxResourceReferences 一个主题中最多展开的资源引用数
+	maxResourceReferences = 10
+	// maxCardDescription 资源卡片中描述的最多字符数
+	maxCardDescription = 200
+)
+
+// resourceLinkPattern 匹配内容中指向资源详情页的链接，如 /resources/12 或 https://example.com/resources/12
+var resourceLinkPattern = regexp.MustCompile(`/resources/(\d+)(?:[^\w/-]|$)`)
+
+var errDiscussionExists = errors.New("该资源已有讨论主题")
+
+// extractResourceIDs 提取内容中引用的资源ID，去重并保持出现顺序，最多maxResourceReferences个
+func extractResourceIDs(content string) []uint {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, match := range resourceLinkPattern.FindAllStringSubmatch(content, -1) {
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		ids = append(ids, uint(id))
+		if len(ids) == maxResourceReferences {
+			break
+		}
+	}
+	return ids
+}
+
+// resourceLink 指向资源详情页的Markdown链接，插入主题内容后会展开为资源卡片
+func resourceLink(resource *models.Resource) string {
+	return fmt.Sprintf("[%s](/resources/%d)", resource.Title, resource.ID)
+}
+
+// syncResourceReferences 保存主题内容中引用的资源，不再引用的资源记录会被删除
+func syncResourceReferences(db *gorm.DB, topicID uint, content string) {
+	var ids []uint
+	if referenced := extractResourceIDs(content); len(referenced) > 0 {
+		db.Model(&models.Resource{}).Where("id IN ?", referenced).Pluck("id", &ids)
+	}
+
+	removed := db.Where("topic_id = ?", topicID)
+	if len(ids) > 0 {
+		removed = removed.Where("resource_id NOT IN ?", ids)
+	}
+	removed.Delete(&models.ResourceReference{})
+
+	if len(ids) == 0 {
+		return
+	}
+	references := make([]models.ResourceReference, len(ids))
+	for i, id := range ids {
+		references[i] = models.ResourceReference{ResourceID: id, TopicID: topicID, CreatedAt: time.Now()}
+	}
+	db.Clauses(clause.OnConflict{DoNothing: true}).Create(&references)
+}
+
+// unlinkDiscussionTopic 讨论主题被删除后，资源可以重新创建讨论主题
+func unlinkDiscussionTopic(db *gorm.DB, topicID uint) {
+	db.Model(&models.Resource{}).Where("discussion_topic_id = ?", topicID).Update("discussion_topic_id", nil)
+}
+
+// resourceCards 将内容中引用的资源展开为资源卡片，访问者看不到的资源和未通过审核的资源不展开
+func resourceCards(db *gorm.DB, content string, viewer resourceViewer) []gin.H {
+	cards := []gin.H{}
+	ids := extractResourceIDs(content)
+	if len(ids) == 0 {
+		return cards
+	}
+
+	var resources []models.Resource
+	visibleResources(db.Model(&models.Resource{}).Where("resources.id IN ? AND resources.status = ?", ids, "approved"), viewer).
+		Preload("User").
+		Preload("Category").
+		Find(&resources)
+
+	byID := make(map[uint]*models.Resource, len(resources))
+	for i := range resources {
+		byID[resources[i].ID] = &resources[i]
+	}
+	for _, id := range ids {
+		resource, ok := byID[id]
+		if !ok {
+			continue
+		}
+		cards = append(cards, gin.H{
+			"id":              resource.ID,
+			"title":           resource.Title,
+			"description":     truncateRunes(resource.Description, maxCardDescription),
+			"category":        gin.H{"id": resource.Category.ID, "name": resource.Category.Name},
+			"user":            gin.H{"id": resource.User.ID, "username": resource.User.Username, "avatar": resource.User.Avatar},
+			"file_type":       resource.FileType,
+			"file_size":       resource.FileSize,
+			"download_count":  resource.DownloadCount,
+			"view_count":      resource.ViewCount,
+			"points_required": resource.PointsRequired,
+			"created_at":      resource.CreatedAt,
+		})
+	}
+	return cards
+}
+
+// GetResourceDiscussions 获取讨论该资源的主题：上传者创建的讨论主题排在最前，其余为内容中引用了该资源的主题
+func (c *ResourceController) GetResourceDiscussions(ctx *gin.Context) {
+	var resource models.Resource
+	if err := c.DB.First(&resource, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+
+	viewer := getResourceViewer(ctx, c.DB)
+	if !checkResourceAccess(ctx, c.DB, &resource, viewer) {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	var discussionID uint
+	if resource.DiscussionTopicID != nil {
+		discussionID = *resource.DiscussionTopicID
+	}
+
+	// 隐藏和静默封禁期间发布的主题只有作者和管理员能看到
+	query := visibleShadowed(c.DB.Model(&models.Topic{}), "topics", viewer).
+		Where("(topics.id IN (SELECT topic_id FROM resource_references WHERE resource_id = ?) OR topics.id = ?)", resource.ID, discussionID)
+	if !viewer.IsAdmin {
+		query = query.Where("topics.is_hidden = ?", false)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	topics := []models.Topic{}
+	query.Preload("User").Preload("Category").Preload("Tags").
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "topics.id = ? DESC, topics.created_at DESC, topics.id DESC", Vars: []interface{}{discussionID}, WithoutParentheses: true}}).
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&topics)
+
+	// 讨论主题被删除或访问者看不到时不返回其ID
+	var visibleDiscussion *uint
+	if discussionID != 0 {
+		discussion := visibleShadowed(c.DB.Model(&models.Topic{}), "topics", viewer).Where("topics.id = ?", discussionID)
+		if !viewer.IsAdmin {
+			discussion = discussion.Where("topics.is_hidden = ?", false)
+		}
+		var count int64
+		if discussion.Count(&count); count > 0 {
+			visibleDiscussion = &discussionID
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"discussion_topic_id": visibleDiscussion,
+		"topics":              topics,
+		"total":               total,
+		"page":                page,
+		"pageSize":            pageSize,
+	})
+}
+
+// CreateResourceDiscussion 资源的上传者一步创建资源的讨论主题
+// 标题、内容和分类都可以不填，默认使用资源的标题、描述和分类，内容中会带上资源链接并展开为资源卡片
+func (c *ForumController) CreateResourceDiscussion(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var resource models.Resource
+	if err := c.DB.First(&resource, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+	if resource.UserID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "只有资源的上传者可以创建讨论主题"})
+		return
+	}
+	if resource.Status != "approved" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "资源审核通过后才能创建讨论主题"})
+		return
+	}
+
+	// 已有的讨论主题被删除后可以重新创建
+	if resource.DiscussionTopicID != nil {
+		var count int64
+		c.DB.Model(&models.Topic{}).Where("id = ?", *resource.DiscussionTopicID).Count(&count)
+		if count > 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errDiscussionExists.Error(), "topic_id": *resource.DiscussionTopicID})
+			return
+		}
+	}
+
+	var input struct {
+		Title      string   `json:"title" binding:"max=100"`
+		Content    string   `json:"content"`
+		CategoryID uint     `json:"category_id"`
+		Tags       []string `json:"tags"` // 可选的标签
+	}
+
+	// 请求体可以为空
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Title == "" {
+		input.Title = truncateRunes("【资源讨论】"+resource.Title, 100)
+	}
+	if input.Content == "" {
+		input.Content = "资源：" + resourceLink(&resource) + "\n\n" + resource.Description
+	} else if !containsResourceLink(input.Content, resource.ID) {
+		input.Content += "\n\n资源：" + resourceLink(&resource)
+	}
+	if input.CategoryID == 0 {
+		input.CategoryID = resource.CategoryID
+	}
+
+	var category models.Category
+	if result := c.DB.First(&category, input.CategoryID); result.Error != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+		return
+	}
+
+	moderation, ok := filterContent(ctx, c.DB, c.Filter, userID.(uint), models.ModerationSourceTopic,
+		textField{"title", &input.Title}, textField{"content", &input.Content})
+	if !ok {
+		return
+	}
+
+	// 检查标签，保留标签只有管理员能添加
+	tags, msg := pickTags(c.DB, c.Filter, getResourceViewer(ctx, c.DB), nil, input.Tags)
+	if msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	posting, ok := guardPosting(ctx, c.DB, c.Spam, userID.(uint), utils.SpamActionTopic, input.Title+"\n"+input.Content)
+	if !ok {
+		return
+	}
+
+	topic, mentionedUsers := c.newTopic(userID.(uint), input.Title, input.Content, input.CategoryID,
+		models.TopicTypeDiscussion, posting, moderation)
+
+	// 同时提交的请求只有一个能创建成功
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := createTopic(tx, &topic, tags); err != nil {
+			return err
+		}
+		result := tx.Model(&models.Resource{}).
+			Where("id = ? AND (discussion_topic_id IS NULL OR discussion_topic_id = ?)", resource.ID, resource.DiscussionTopicID).
+			Update("discussion_topic_id", topic.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errDiscussionExists
+		}
+		return nil
+	})
+	if errors.Is(err, errDiscussionExists) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建讨论主题失败"})
+		return
+	}
+
+	c.publishTopic(ctx, &topic, posting, moderation, mentionedUsers)
+	ctx.JSON(http.StatusCreated, topic)
+}
+
+// containsResourceLink 内容中是否引用了该资源
+func containsResourceLink(content string, resourceID uint) bool {
+	for _, id := range extractResourceIDs(content) {
+		if id == resourceID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"g/front/backend/config"
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// ShareController 资源分享链接控制器
+type ShareController struct {
+	DB          *gorm.DB
+	MinioClient *minio.Client
+	Guard       *utils.ShareGuard
+}
+
+// NewShareController 创建分享链接控制器实例
+func NewShareController(db *gorm.DB, minioClient *minio.Client, guard *utils.ShareGuard) *ShareController {
+	return &ShareController{DB: db, MinioClient: minioClient, Guard: guard}
+}
+
+// CreateShareLink 为自己上传的资源创建分享链接
+func (c *ShareController) CreateShareLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 查询资源
+	var resource models.Resource
+	if err := c.DB.First(&resource, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+
+	// 只有资源上传者可以创建分享链接
+	if resource.UserID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权分享此资源"})
+		return
+	}
+
+	if resource.Status != "approved" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "资源未通过审核，无法分享"})
+		return
+	}
+
+	if resource.SharingDisabled {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "该资源已被管理员禁止分享"})
+		return
+	}
+
+	// 分享链接无需登录即可下载，限定范围的资源不能分享
+	if resource.Visibility == models.VisibilityGroup || resource.Visibility == models.VisibilityPrivate {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "仅限用户组或私有的资源不能分享"})
+		return
+	}
+
+	// 绑定请求数据
+	var input struct {
+		ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=1,max=720"`
+		MaxUses        int    `json:"max_uses" binding:"omitempty,min=0,max=1000"`
+		Password       string `json:"password" binding:"omitempty,min=8,max=32"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 默认有效期3天
+	if input.ExpiresInHours == 0 {
+		input.ExpiresInHours = 72
+	}
+
+	token, err := utils.RandomToken(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成分享令牌失败"})
+		return
+	}
+
+	link := models.ShareLink{
+		Token:      token,
+		ResourceID: resource.ID,
+		UserID:     userID.(uint),
+		ExpiresAt:  time.Now().Add(time.Duration(input.ExpiresInHours) * time.Hour),
+		MaxUses:    input.MaxUses,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	// 设置提取密码
+	if input.Password != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+			return
+		}
+		link.RequiresPassword = true
+		link.PasswordHash = string(hashedPassword)
+	}
+
+	if err := c.DB.Create(&link).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建分享链接失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, link)
+}
+
+// GetMyShareLinks 获取当前用户创建的分享链接
+func (c *ShareController) GetMyShareLinks(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query := c.DB.Where("user_id = ?", userID)
+
+	// 按资源过滤
+	if resourceID := ctx.Query("resource_id"); resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+
+	var links []models.ShareLink
+	query.Preload("Resource").Order("created_at DESC").Find(&links)
+
+	ctx.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// RevokeShareLink 撤销分享链接
+func (c *ShareController) RevokeShareLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var link models.ShareLink
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&link).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "分享链接不存在"})
+		return
+	}
+
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := c.DB.Model(&link).Updates(map[string]interface{}{
+			"revoked_at": now,
+			"updated_at": now,
+		}).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "撤销分享链接失败"})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "分享链接已撤销"})
+}
+
+// GetShareInfo 获取分享链接信息（公开接口）
+func (c *ShareController) GetShareInfo(ctx *gin.Context) {
+	link, resource, reason := c.loadShareLink(ctx.Param("token"))
+	if reason != "" {
+		ctx.JSON(shareErrorStatus(reason), gin.H{"error": shareErrorMessage(reason)})
+		return
+	}
+
+	remaining := -1
+	if link.MaxUses > 0 {
+		remaining = link.MaxUses - link.UseCount
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"title":             resource.Title,
+		"description":       resource.Description,
+		"file_size":         resource.FileSize,
+		"file_type":         resource.FileType,
+		"expires_at":        link.ExpiresAt,
+		"remaining_uses":    remaining,
+		"requires_password": link.RequiresPassword,
+	})
+}
+
+// DownloadShared 通过分享链接下载资源（公开接口），提取密码通过X-Share-Password请求头或POST请求体传递
+func (c *ShareController) DownloadShared(ctx *gin.Context) {
+	link, resource, reason := c.loadShareLink(ctx.Param("token"))
+	if link == nil {
+		ctx.JSON(shareErrorStatus(reason), gin.H{"error": shareErrorMessage(reason)})
+		return
+	}
+	if reason != "" {
+		c.recordAccess(ctx, link.ID, false, reason)
+		ctx.JSON(shareErrorStatus(reason), gin.H{"error": shareErrorMessage(reason)})
+		return
+	}
+
+	// 校验提取密码，密码只能通过请求头或POST请求体传递，避免出现在URL和访问日志中
+	// 同一分享链接或同一IP密码错误次数过多时暂时锁定，防止暴力破解
+	if link.RequiresPassword {
+		if wait := c.Guard.Locked(ctx.Request.Context(), link.ID, ctx.ClientIP()); wait > 0 {
+			c.recordAccess(ctx, link.ID, false, "locked")
+			writeShareLocked(ctx, wait)
+			return
+		}
+
+		password := ctx.GetHeader("X-Share-Password")
+		if password == "" && ctx.Request.Method == http.MethodPost {
+			var input struct {
+				Password string `json:"password"`
+			}
+			if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			password = input.Password
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			c.recordAccess(ctx, link.ID, false, "bad_password")
+			if wait := c.Guard.Fail(ctx.Request.Context(), link.ID, ctx.ClientIP()); wait > 0 {
+				writeShareLocked(ctx, wait)
+				return
+			}
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": shareErrorMessage("bad_password")})
+			return
+		}
+	}
+
+	// 先准备好文件，确认可以下载后再占用使用次数，准备失败时不消耗次数
+	bucketName := config.GetEnv("resources", "resources")
+	objectKey := resource.FilePath
+
+	// 需要水印的PDF资源，使用该分享链接专属的水印副本，泄露时可以追溯到具体的分享链接
+	if resource.WatermarkOnDownload && isPDFResource(resource.FileType, resource.FilePath) {
+		watermarkedKey, err := watermarkedObjectKey(c.DB, c.MinioClient, bucketName, resource, 0, link.ID)
+		if err != nil {
+			log.Printf("生成水印文件失败: %v, 资源ID: %d", err, resource.ID)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成水印文件失败"})
+			return
+		}
+		objectKey = watermarkedKey
+	}
+
+	object, err := c.MinioClient.GetObject(context.Background(), bucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件失败"})
+		return
+	}
+	defer object.Close()
+
+	stat, err := object.Stat()
+	if err != nil {
+		log.Printf("获取文件信息失败: %v, 资源ID: %d", err, resource.ID)
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		return
+	}
+
+	// 原子地占用一次使用次数，避免并发下载超过上限
+	result := c.DB.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_uses = 0 OR use_count < max_uses)", link.ID).
+		Update("use_count", gorm.Expr("use_count + 1"))
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "下载失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.recordAccess(ctx, link.ID, false, "exhausted")
+		ctx.JSON(shareErrorStatus("exhausted"), gin.H{"error": shareErrorMessage("exhausted")})
+		return
+	}
+
+	c.recordAccess(ctx, link.ID, true, "")
+	c.DB.Model(resource).Update("download_count", gorm.Expr("download_count + 1"))
+
+	ctx.DataFromReader(http.StatusOK, stat.Size, stat.ContentType, object, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(resourceFileName(resource))),
+	})
+}
+
+// GetShareLinkAccesses 查看分享链接的使用记录
+func (c *ShareController) GetShareLinkAccesses(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var link models.ShareLink
+	if err := c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), userID).First(&link).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "分享链接不存在"})
+		return
+	}
+
+	var accesses []models.ShareLinkAccess
+	c.DB.Where("share_link_id = ?", link.ID).Order("created_at DESC").Limit(200).Find(&accesses)
+
+	ctx.JSON(http.StatusOK, gin.H{"accesses": accesses})
+}
+
+// loadShareLink 查询分享链接及其资源，并检查链接是否仍然可用
+// 返回的reason为空表示可以使用
+func (c *ShareController) loadShareLink(token string) (*models.ShareLink, *models.Resource, string) {
+	var link models.ShareLink
+	if err := c.DB.Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, nil, "not_found"
+	}
+
+	var resource models.Resource
+	if err := c.DB.First(&resource, link.ResourceID).Error; err != nil {
+		return &link, nil, "not_found"
+	}
+
+	switch {
+	case link.RevokedAt != nil:
+		return &link, &resource, "revoked"
+	case resource.SharingDisabled || resource.Status != "approved" ||
+		resource.Visibility == models.VisibilityGroup || resource.Visibility == models.VisibilityPrivate:
+		return &link, &resource, "disabled"
+	case time.Now().After(link.ExpiresAt):
+		return &link, &resource, "expired"
+	case link.MaxUses > 0 && link.UseCount >= link.MaxUses:
+		return &link, &resource, "exhausted"
+	}
+
+	return &link, &resource, ""
+}
+
+// recordAccess 记录分享链接的访问
+func (c *ShareController) recordAccess(ctx *gin.Context, linkID uint, success bool, reason string) {
+	userAgent := ctx.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	access := models.ShareLinkAccess{
+		ShareLinkID: linkID,
+		IP:          ctx.ClientIP(),
+		UserAgent:   userAgent,
+		Success:     success,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	}
+	if err := c.DB.Create(&access).Error; err != nil {
+		log.Printf("记录分享链接访问失败: %v", err)
+	}
+}
+
+// shareErrorStatus 分享链接不可用原因对应的HTTP状态码
+func shareErrorStatus(reason string) int {
+	switch reason {
+	case "not_found":
+		return http.StatusNotFound
+	case "bad_password":
+		return http.StatusUnauthorized
+	case "disabled":
+		return http.StatusForbidden
+	case "locked":
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusGone
+	}
+}
+
+// shareErrorMessage 分享链接不可用原因对应的提示信息
+func shareErrorMessage(reason string) string {
+	switch reason {
+	case "not_found":
+		return "分享链接不存在"
+	case "revoked":
+		return "分享链接已被撤销"
+	case "disabled":
+		return "该资源已禁止分享"
+	case "expired":
+		return "分享链接已过期"
+	case "exhausted":
+		return "分享链接的下载次数已用完"
+	case "bad_password":
+		return "提取密码错误"
+	case "locked":
+		return "提取密码错误次数过多，请稍后再试"
+	default:
+		return "分享链接不可用"
+	}
+}
+
+// writeShareLocked 写入密码错误次数过多被锁定的响应，带上Retry-After
+func writeShareLocked(ctx *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+	ctx.JSON(shareErrorStatus("locked"), gin.H{"error": shareErrorMessage("locked"), "retry_after": seconds})
+}
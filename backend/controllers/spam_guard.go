@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// postingCheck 一次发帖的反垃圾检查结果
+type postingCheck struct {
+	Shadowed bool // 作者被静默封禁，内容只有作者和管理员可见，不奖励积分也不发送通知
+
+	guard       *utils.SpamGuard
+	userID      uint
+	fingerprint uint64
+	exempt      bool // 管理员不受限制
+}
+
+// spamRejection 发帖被拒绝的原因
+type spamRejection struct {
+	Status     int
+	Message    string
+	RetryAfter time.Duration
+}
+
+// formatWait 将等待时间格式化为便于阅读的文字
+func formatWait(d time.Duration) string {
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%d小时", int(math.Ceil(d.Hours())))
+	case d >= time.Minute:
+		return fmt.Sprintf("%d分钟", int(math.Ceil(d.Minutes())))
+	default:
+		return fmt.Sprintf("%d秒", int(math.Ceil(d.Seconds())))
+	}
+}
+
+// activeShadowBan 查询用户当前有效的静默封禁，没有时返回nil
+func activeShadowBan(db *gorm.DB, userID uint) *models.ShadowBan {
+	var ban models.ShadowBan
+	if db.Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Limit(1).
+		Find(&ban).RowsAffected == 0 {
+		return nil
+	}
+	return &ban
+}
+
+// checkPosting 发帖前的反垃圾检查：冷却、重复内容和发帖频率，text为用于检测重复的内容
+// 重复内容和超出频率都算一次违规，多次违规后进入冷却；Redis不可用时不限制
+func checkPosting(ctx context.Context, db *gorm.DB, guard *utils.SpamGuard, userID uint, action, text string) (*postingCheck, *spamRejection) {
+	check := &postingCheck{guard: guard, userID: userID, fingerprint: utils.ContentFingerprint(text)}
+
+	var user models.User
+	if err := db.Select("id", "role", "points", "created_at").First(&user, userID).Error; err != nil {
+		return check, nil
+	}
+	if user.Role == "admin" {
+		check.exempt = true
+		return check, nil
+	}
+	check.Shadowed = activeShadowBan(db, userID) != nil
+
+	if cooldown := guard.Cooldown(ctx, userID); cooldown > 0 {
+		return nil, &spamRejection{
+			Status:     http.StatusTooManyRequests,
+			Message:    fmt.Sprintf("由于多次违规发帖，请在%s后再试", formatWait(cooldown)),
+			RetryAfter: cooldown,
+		}
+	}
+
+	// strike 记录违规，进入冷却时在提示中说明
+	strike := func(rejection *spamRejection) *spamRejection {
+		if cooldown := guard.Strike(ctx, userID); cooldown > 0 {
+			rejection.Status = http.StatusTooManyRequests
+			rejection.Message += fmt.Sprintf("。由于多次违规，%s内不能发帖", formatWait(cooldown))
+			rejection.RetryAfter = cooldown
+		}
+		return rejection
+	}
+
+	duplicate, err := guard.IsDuplicate(ctx, userID, check.fingerprint)
+	if err != nil {
+		log.Printf("检查重复内容失败: %v", err)
+		return check, nil
+	}
+	if duplicate {
+		return nil, strike(&spamRejection{Status: http.StatusBadRequest, Message: "内容与你最近发布的内容重复"})
+	}
+
+	wait, err := guard.Allow(ctx, userID, action, guard.Tier(user.CreatedAt, user.Points))
+	if err != nil {
+		log.Printf("检查发帖频率失败: %v", err)
+		return check, nil
+	}
+	if wait > 0 {
+		return nil, strike(&spamRejection{
+			Status:     http.StatusTooManyRequests,
+			Message:    fmt.Sprintf("发帖过于频繁，请在%s后再试", formatWait(wait)),
+			RetryAfter: wait,
+		})
+	}
+	return check, nil
+}
+
+// guardPosting 发帖前的反垃圾检查，不通过时直接写入错误响应并返回false
+func guardPosting(ctx *gin.Context, db *gorm.DB, guard *utils.SpamGuard, userID uint, action, text string) (*postingCheck, bool) {
+	check, rejection := checkPosting(ctx.Request.Context(), db, guard, userID, action, text)
+	if rejection != nil {
+		writeSpamRejection(ctx, rejection, gin.H{"error": rejection.Message})
+		return nil, false
+	}
+	return check, true
+}
+
+// writeSpamRejection 写入发帖被拒绝的响应，需要等待时带上Retry-After
+func writeSpamRejection(ctx *gin.Context, rejection *spamRejection, body gin.H) {
+	if rejection.RetryAfter > 0 {
+		seconds := int(math.Ceil(rejection.RetryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.Itoa(seconds))
+		body["retry_after"] = seconds
+	}
+	ctx.JSON(rejection.Status, body)
+}
+
+// done 内容保存后记录其指纹，用于检测之后的重复内容
+func (p *postingCheck) done(ctx context.Context) {
+	if p.exempt {
+		return
+	}
+	p.guard.Remember(ctx, p.userID, p.fingerprint)
+}
+
+// visibleShadowed 过滤被静默封禁的用户发布的内容，作者本人和管理员仍能看到
+func visibleShadowed(query *gorm.DB, table string, viewer resourceViewer) *gorm.DB {
+	if viewer.IsAdmin {
+		return query
+	}
+	return query.Where("("+table+".shadowed = ? OR "+table+".user_id = ?)", false, viewer.UserID)
+}
+
+// visiblePublished 过滤静默和待审核的回复、评论，作者本人和管理员仍能看到
+func visiblePublished(query *gorm.DB, table string, viewer resourceViewer) *gorm.DB {
+	if viewer.IsAdmin {
+		return query
+	}
+	return query.Where("(("+table+".shadowed = ? AND "+table+".pending = ?) OR "+table+".user_id = ?)", false, false, viewer.UserID)
+}
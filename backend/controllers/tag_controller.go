@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+const (
+	// maxItemTags 每个主题或资源最多的标签数，不含管理员添加的保留标签
+	maxItemTags = 5
+	// maxTagLength 标签名最多字符数
+	maxTagLength = 20
+	// tagUsageOrder 按标签的使用次数排序
+	tagUsageOrder = "(SELECT COUNT(*) FROM topic_tags WHERE topic_tags.tag_id = tags.id) + " +
+		"(SELECT COUNT(*) FROM resource_tags WHERE resource_tags.tag_id = tags.id) DESC"
+)
+
+// TagController 标签控制器，主题和资源共用同一套标签
+type TagController struct {
+	DB *gorm.DB
+}
+
+// NewTagController 创建标签控制器实例
+func NewTagController(db *gorm.DB) *TagController {
+	return &TagController{DB: db}
+}
+
+// splitTagNames 拆分表单中以逗号分隔的标签
+func splitTagNames(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' })
+}
+
+// normalizeTagNames 整理输入的标签名：去掉首尾空白和开头的#，合并连续空白，忽略重复的标签
+func normalizeTagNames(names []string) ([]string, string) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.Join(strings.Fields(strings.TrimLeft(strings.TrimSpace(name), "#＃")), " ")
+		if name == "" {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagLength {
+			return nil, fmt.Sprintf("标签不能超过%d个字符", maxTagLength)
+		}
+		if strings.ContainsAny(name, ",，/") {
+			return nil, "标签不能包含逗号和斜杠"
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	if len(result) > maxItemTags {
+		return nil, fmt.Sprintf("最多添加%d个标签", maxItemTags)
+	}
+	return result, ""
+}
+
+// pickTags 根据输入的标签名选出内容的标签，不存在的标签ID为0，由saveTags创建
+// current为内容原有的标签。普通用户不能添加或移除保留标签，原有的保留标签会被保留
+func pickTags(db *gorm.DB, filter *utils.ContentFilter, viewer resourceViewer, current []models.Tag, names []string) ([]models.Tag, string) {
+	names, msg := normalizeTagNames(names)
+	if msg != "" {
+		return nil, msg
+	}
+
+	var existing []models.Tag
+	if len(names) > 0 {
+		db.Where("name IN ?", names).Find(&existing)
+	}
+	byName := make(map[string]models.Tag, len(existing))
+	for _, tag := range existing {
+		byName[strings.ToLower(tag.Name)] = tag
+	}
+	had := make(map[uint]bool, len(current))
+	for _, tag := range current {
+		had[tag.ID] = true
+	}
+
+	tags := make([]models.Tag, 0, len(names))
+	picked := make(map[uint]bool, len(names))
+	for _, name := range names {
+		tag, ok := byName[strings.ToLower(name)]
+		if !ok {
+			// 新标签的名称也需要检查敏感词
+			if result := filter.Check(name); result.Action != "" {
+				return nil, "标签包含敏感词：" + name
+			}
+			tags = append(tags, models.Tag{Name: name, CreatedBy: viewer.UserID})
+			continue
+		}
+		if tag.Reserved && !viewer.IsAdmin && !had[tag.ID] {
+			return nil, "只有管理员可以添加标签：" + tag.Name
+		}
+		tags = append(tags, tag)
+		picked[tag.ID] = true
+	}
+
+	if !viewer.IsAdmin {
+		for _, tag := range current {
+			if tag.Reserved && !picked[tag.ID] {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, ""
+}
+
+// saveTags 创建不存在的标签，同名标签已被其他请求创建时直接使用
+func saveTags(tx *gorm.DB, tags []models.Tag) error {
+	for i := range tags {
+		if tags[i].ID != 0 {
+			continue
+		}
+		if err := tx.Where("name = ?", tags[i].Name).FirstOrCreate(&tags[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceTags 替换主题或资源的标签，model为带ID的主题或资源
+func replaceTags(tx *gorm.DB, model interface{}, tags []models.Tag) error {
+	if err := saveTags(tx, tags); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return tx.Model(model).Association("Tags").Clear()
+	}
+	return tx.Model(model).Association("Tags").Replace(tags)
+}
+
+// filterTagged 只保留带有全部指定标签的内容，names为逗号分隔的标签名
+// table为内容表，joinTable和column为标签关联表及其中的内容ID列
+func filterTagged(query *gorm.DB, table, joinTable, column, names string) *gorm.DB {
+	for _, name := range splitTagNames(names) {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		query = query.Where(table+".id IN (SELECT "+joinTable+"."+column+" FROM "+joinTable+
+			" JOIN tags ON tags.id = "+joinTable+".tag_id WHERE tags.name = ?)", name)
+	}
+	return query
+}
+
+// fillTagCounts 统计标签下公开可见的主题数和资源数
+func fillTagCounts(db *gorm.DB, tags []models.Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	ids := make([]uint, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+
+	type tagCount struct {
+		TagID uint
+		Count int
+	}
+	var topicCounts, resourceCounts []tagCount
+	db.Table("topic_tags").
+		Select("topic_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN topics ON topics.id = topic_tags.topic_id").
+		Where("topic_tags.tag_id IN ? AND topics.deleted_at IS NULL AND topics.is_hidden = ? AND topics.shadowed = ?", ids, false, false).
+		Group("topic_tags.tag_id").
+		Scan(&topicCounts)
+	db.Table("resource_tags").
+		Select("resource_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN resources ON resources.id = resource_tags.resource_id").
+		Where("resource_tags.tag_id IN ? AND resources.deleted_at IS NULL AND resources.status = ? AND resources.visibility = ?",
+			ids, "approved", models.VisibilityPublic).
+		Group("resource_tags.tag_id").
+		Scan(&resourceCounts)
+
+	topics := make(map[uint]int, len(topicCounts))
+	for _, row := range topicCounts {
+		topics[row.TagID] = row.Count
+	}
+	resources := make(map[uint]int, len(resourceCounts))
+	for _, row := range resourceCounts {
+		resources[row.TagID] = row.Count
+	}
+	for i := range tags {
+		tags[i].TopicCount = topics[tags[i].ID]
+		tags[i].ResourceCount = resources[tags[i].ID]
+	}
+}
+
+// GetTags 标签自动补全，没有关键词时返回最常用的标签
+// 以关键词开头的标签排在前面，其次是包含关键词的标签，相同情况下按使用次数排序
+func (c *TagController) GetTags(ctx *gin.Context) {
+	keyword := strings.TrimLeft(strings.TrimSpace(ctx.Query("q")), "#＃")
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	tags := []models.Tag{}
+	if keyword == "" {
+		c.DB.Order(tagUsageOrder).Order("id ASC").Limit(limit).Find(&tags)
+	} else {
+		// 转义LIKE通配符
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(keyword)
+		c.DB.Where("name LIKE ?", escaped+"%").
+			Order("CHAR_LENGTH(name) = " + strconv.Itoa(utf8.RuneCountInString(keyword)) + " DESC").
+			Order(tagUsageOrder).
+			Order("id ASC").
+			Limit(limit).
+			Find(&tags)
+
+		if len(tags) < limit {
+			var more []models.Tag
+			c.DB.Where("name LIKE ? AND name NOT LIKE ?", "%"+escaped+"%", escaped+"%").
+				Order(tagUsageOrder).
+				Order("id ASC").
+				Limit(limit - len(tags)).
+				Find(&more)
+			tags = append(tags, more...)
+		}
+	}
+
+	fillTagCounts(c.DB, tags)
+	ctx.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// GetTag 标签页，返回带有该标签的主题和资源
+// type为topic或resource时只返回一种内容，为空时两种都返回
+func (c *TagController) GetTag(ctx *gin.Context) {
+	var tag models.Tag
+	if err := c.DB.Where("name = ?", ctx.Param("name")).First(&tag).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	kind := ctx.Query("type")
+	if kind != "" && kind != "topic" && kind != "resource" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容类型"})
+		return
+	}
+
+	tags := []models.Tag{tag}
+	fillTagCounts(c.DB, tags)
+	response := gin.H{
+		"tag":      tags[0],
+		"page":     page,
+		"pageSize": pageSize,
+	}
+
+	viewer := getResourceViewer(ctx, c.DB)
+	if kind != "resource" {
+		// 隐藏和静默封禁期间发布的主题不出现在标签页中
+		query := visibleShadowed(c.DB.Model(&models.Topic{}), "topics", viewer).
+			Where("topics.id IN (SELECT topic_id FROM topic_tags WHERE tag_id = ?)", tag.ID)
+		if !viewer.IsAdmin {
+			query = query.Where("topics.is_hidden = ?", false)
+		}
+
+		var total int64
+		query.Count(&total)
+
+		topics := []models.Topic{}
+		query.Preload("User").Preload("Category").Preload("Tags").
+			Order("topics.created_at DESC, topics.id DESC").
+			Limit(pageSize).
+			Offset((page - 1) * pageSize).
+			Find(&topics)
+		response["topics"] = topics
+		response["topic_total"] = total
+	}
+
+	if kind != "topic" {
+		query := visibleResources(c.DB.Model(&models.Resource{}).Where("resources.status = ?", "approved"), viewer).
+			Where("resources.id IN (SELECT resource_id FROM resource_tags WHERE tag_id = ?)", tag.ID)
+
+		var total int64
+		query.Count(&total)
+
+		resources := []models.Resource{}
+		query.Preload("User").Preload("Category").Preload("Tags").
+			Order("resources.created_at DESC, resources.id DESC").
+			Limit(pageSize).
+			Offset((page - 1) * pageSize).
+			Find(&resources)
+		response["resources"] = resources
+		response["resource_total"] = total
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -15,17 +15,19 @@ import (
 
 	"g/front/backend/middleware"
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
 // UserController 用户控制器
 type UserController struct {
 	DB          *gorm.DB
 	MinioClient *minio.Client
+	Filter      *utils.ContentFilter // 敏感词过滤
 }
 
 // NewUserController 创建用户控制器实例
-func NewUserController(db *gorm.DB, minioClient *minio.Client) *UserController {
-	return &UserController{DB: db, MinioClient: minioClient}
+func NewUserController(db *gorm.DB, minioClient *minio.Client, filter *utils.ContentFilter) *UserController {
+	return &UserController{DB: db, MinioClient: minioClient, Filter: filter}
 }
 
 // GetUserProfile 获取用户资料
@@ -68,6 +70,15 @@ func (c *UserController) Register(ctx *gin.Context) {
 		return
 	}
 
+	// 用户名不能修改，命中任何敏感词都不允许注册
+	if result := c.Filter.Check(input.Username); result.Action != "" {
+		entry := moderationEntry(0, models.ModerationSourceUsername, "username", input.Username, result)
+		entry.Action = models.SensitiveActionBlock
+		c.DB.Create(&entry)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "用户名包含敏感词"})
+		return
+	}
+
 	// 检查用户名是否已存在
 	var existingUser models.User
 	if result := c.DB.Where("username = ?", input.Username).First(&existingUser); result.Error == nil {
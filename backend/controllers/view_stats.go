@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// recordView 记录一次浏览，爬虫不计入
+// 返回尚未写入MySQL的浏览次数，加到记录上的浏览次数后即为实时的浏览次数
+func recordView(ctx *gin.Context, views *utils.ViewCounter, targetType string, targetID, userID uint) int {
+	if !utils.IsCrawler(ctx.Request.UserAgent()) {
+		visitor := utils.ViewVisitor(userID, ctx.ClientIP())
+		if _, err := views.Record(ctx.Request.Context(), targetType, targetID, visitor); err != nil {
+			log.Printf("记录浏览失败: %v", err)
+		}
+	}
+	return views.Pending(ctx.Request.Context(), targetType, targetID)
+}
+
+// viewStats 浏览统计：实时浏览次数、最近days天的独立访客数及每天的独立访客数
+func viewStats(ctx *gin.Context, views *utils.ViewCounter, targetType string, targetID uint, viewCount int) {
+	days, _ := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	if days <= 0 || days > utils.ViewUniqueDays {
+		days = 30
+	}
+
+	daily, err := views.UniqueVisitors(ctx.Request.Context(), targetType, targetID, days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取浏览统计失败"})
+		return
+	}
+	total, err := views.TotalUniqueVisitors(ctx.Request.Context(), targetType, targetID, days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取浏览统计失败"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"view_count":      viewCount + views.Pending(ctx.Request.Context(), targetType, targetID),
+		"unique_visitors": total,
+		"daily":           daily,
+		"days":            days,
+	})
+}
+
+// GetTopicViews 获取主题的浏览统计，只有作者和管理员可以查看
+func (c *ForumController) GetTopicViews(ctx *gin.Context) {
+	viewer := getResourceViewer(ctx, c.DB)
+	if viewer.UserID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var topic models.Topic
+	if err := c.DB.First(&topic, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "主题不存在"})
+		return
+	}
+	if topic.UserID != viewer.UserID && !viewer.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权查看浏览统计"})
+		return
+	}
+
+	viewStats(ctx, c.Views, utils.ViewTargetTopic, topic.ID, topic.ViewCount)
+}
+
+// GetResourceViews 获取资源的浏览统计，只有上传者和管理员可以查看
+func (c *ResourceController) GetResourceViews(ctx *gin.Context) {
+	viewer := getResourceViewer(ctx, c.DB)
+	if viewer.UserID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var resource models.Resource
+	if err := c.DB.First(&resource, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return
+	}
+	if resource.UserID != viewer.UserID && !viewer.IsAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权查看浏览统计"})
+		return
+	}
+
+	viewStats(ctx, c.Views, utils.ViewTargetResource, resource.ID, resource.ViewCount)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// resourceViewer 当前请求的访问者，游客的UserID为0
+type resourceViewer struct {
+	UserID  uint
+	IsAdmin bool
+}
+
+// getResourceViewer 从请求上下文中获取访问者信息
+func getResourceViewer(ctx *gin.Context, db *gorm.DB) resourceViewer {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		return resourceViewer{}
+	}
+
+	var user models.User
+	db.Select("id", "role").First(&user, userID)
+	return resourceViewer{UserID: userID.(uint), IsAdmin: user.Role == "admin"}
+}
+
+// visibleResources 将资源查询限制在访问者可见的范围内
+func visibleResources(query *gorm.DB, viewer resourceViewer) *gorm.DB {
+	if viewer.IsAdmin {
+		return query
+	}
+
+	if viewer.UserID == 0 {
+		return query.Where("resources.visibility = ?", models.VisibilityPublic)
+	}
+
+	return query.Where(
+		"resources.visibility IN ? OR resources.user_id = ? OR "+
+			"(resources.visibility = ? AND resources.group_id IN (SELECT group_id FROM user_group_members WHERE user_id = ?))",
+		[]string{models.VisibilityPublic, models.VisibilityLoggedIn}, viewer.UserID,
+		models.VisibilityGroup, viewer.UserID)
+}
+
+// canViewResource 检查访问者是否可以查看资源
+func canViewResource(db *gorm.DB, resource *models.Resource, viewer resourceViewer) bool {
+	if viewer.IsAdmin || resource.Visibility == "" || resource.Visibility == models.VisibilityPublic {
+		return true
+	}
+
+	if viewer.UserID == 0 {
+		return false
+	}
+
+	if resource.UserID == viewer.UserID {
+		return true
+	}
+
+	switch resource.Visibility {
+	case models.VisibilityLoggedIn:
+		return true
+	case models.VisibilityGroup:
+		return resource.GroupID != nil && isGroupMember(db, *resource.GroupID, viewer.UserID)
+	}
+
+	return false
+}
+
+// checkResourceAccess 检查访问者是否可以查看资源，不可见时直接写入错误响应
+// 需要登录才能查看的资源对游客返回401，其余情况返回404以免暴露资源是否存在
+func checkResourceAccess(ctx *gin.Context, db *gorm.DB, resource *models.Resource, viewer resourceViewer) bool {
+	if canViewResource(db, resource, viewer) {
+		return true
+	}
+
+	if viewer.UserID == 0 && resource.Visibility == models.VisibilityLoggedIn {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "请登录后查看此资源"})
+	} else {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+	}
+	return false
+}
+
+// isGroupMember 检查用户是否为用户组成员
+func isGroupMember(db *gorm.DB, groupID, userID uint) bool {
+	var count int64
+	db.Model(&models.UserGroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count)
+	return count > 0
+}
+
+// validateResourceVisibility 校验上传者设置的可见范围
+// 设置为用户组可见时，上传者必须是该用户组的成员
+func validateResourceVisibility(db *gorm.DB, userID uint, visibility string, groupID *uint) string {
+	if !models.IsValidVisibility(visibility) {
+		return "可见范围无效"
+	}
+
+	if visibility != models.VisibilityGroup {
+		return ""
+	}
+
+	if groupID == nil || *groupID == 0 {
+		return "用户组可见的资源必须指定用户组"
+	}
+
+	var group models.UserGroup
+	if err := db.First(&group, *groupID).Error; err != nil {
+		return "用户组不存在"
+	}
+
+	if !isGroupMember(db, group.ID, userID) {
+		return "只能选择自己所在的用户组"
+	}
+
+	return ""
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+	"g/front/backend/utils"
+)
+
+// isPDFResource 判断资源文件是否为PDF
+func isPDFResource(fileType, filePath string) bool {
+	return strings.Contains(strings.ToLower(fileType), "pdf") || strings.EqualFold(filepath.Ext(filePath), ".pdf")
+}
+
+// watermarkedObjectKey 获取下载者专属的带水印副本，没有可用缓存时重新生成
+// 通过分享链接下载时shareLinkID不为0，水印中记录分享链接；匿名下载时userID为0，记录中不关联用户
+// 返回副本在存储桶中的对象键
+func watermarkedObjectKey(db *gorm.DB, minioClient *minio.Client, bucketName string, resource *models.Resource, userID, shareLinkID uint) (string, error) {
+	ctxBg := context.Background()
+
+	// 优先使用该下载者最近一次生成的副本
+	query := db.Where("resource_id = ?", resource.ID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	} else {
+		query = query.Where("user_id IS NULL")
+	}
+	if shareLinkID != 0 {
+		query = query.Where("share_link_id = ?", shareLinkID)
+	} else {
+		query = query.Where("share_link_id IS NULL")
+	}
+	var record models.WatermarkRecord
+	err := query.Order("id DESC").First(&record).Error
+	if err == nil && record.SourcePath == resource.FilePath {
+		if _, err := minioClient.StatObject(ctxBg, bucketName, record.ObjectKey, minio.StatObjectOptions{}); err == nil {
+			return record.ObjectKey, nil
+		}
+	}
+
+	// 读取原文件
+	object, err := minioClient.GetObject(ctxBg, bucketName, resource.FilePath, minio.GetObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer object.Close()
+
+	original, err := io.ReadAll(object)
+	if err != nil {
+		return "", err
+	}
+
+	// 生成水印
+	now := time.Now()
+	traceCode, err := utils.NewTraceCode(userID, now)
+	if err != nil {
+		return "", err
+	}
+	visibleText := fmt.Sprintf("ResourcePool UID:%d %s", userID, now.Format("2006-01-02 15:04:05"))
+	if shareLinkID != 0 {
+		visibleText = fmt.Sprintf("ResourcePool Share:%d %s", shareLinkID, now.Format("2006-01-02 15:04:05"))
+	}
+
+	var stamped bytes.Buffer
+	if err := utils.StampPDFWatermark(bytes.NewReader(original), &stamped, visibleText, traceCode); err != nil {
+		return "", err
+	}
+
+	// 上传副本
+	objectKey := fmt.Sprintf("watermarked/%d/%s.pdf", resource.ID, traceCode)
+	if _, err := minioClient.PutObject(ctxBg, bucketName, objectKey, bytes.NewReader(stamped.Bytes()), int64(stamped.Len()),
+		minio.PutObjectOptions{ContentType: "application/pdf"}); err != nil {
+		return "", err
+	}
+
+	newRecord := models.WatermarkRecord{
+		ResourceID: resource.ID,
+		TraceCode:  traceCode,
+		SourcePath: resource.FilePath,
+		ObjectKey:  objectKey,
+		CreatedAt:  now,
+	}
+	if userID != 0 {
+		newRecord.UserID = &userID
+	}
+	if shareLinkID != 0 {
+		newRecord.ShareLinkID = &shareLinkID
+	}
+	if err := db.Create(&newRecord).Error; err != nil {
+		minioClient.RemoveObject(ctxBg, bucketName, objectKey, minio.RemoveObjectOptions{})
+		return "", err
+	}
+
+	return objectKey, nil
+}
+
+// resourceFileName 下载时使用的文件名：资源标题加原文件扩展名，不暴露存储路径
+func resourceFileName(resource *models.Resource) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", "\"", "_").Replace(resource.Title)
+	if name == "" {
+		name = "resource"
+	}
+	return name + filepath.Ext(resource.FilePath)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -44,6 +47,25 @@ func main() {
 	// 初始化基础数据
 	utils.InitData(db)
 
+	// 初始化Redis客户端
+	redisClient, err := config.InitRedisClient()
+	if err != nil {
+		log.Fatalf("Redis客户端初始化失败: %v", err)
+	}
+
+	// 带参数启动时执行维护命令后退出，例如 go run . rebuild-votes
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], db, redisClient)
+		return
+	}
+
+	// 从旧版本升级时先导入保存在Redis中的主题投票，否则新的计数会覆盖主题表上原有的点赞点踩数
+	if count, err := utils.NewTopicVoteStore(db, redisClient).ImportLegacy(context.Background()); err != nil {
+		log.Fatalf("导入旧版本的主题投票失败: %v", err)
+	} else if count > 0 {
+		log.Printf("已导入%d条旧版本的主题投票", count)
+	}
+
 	// 初始化Minio客户端
 	minioClient, err := config.InitMinioClient()
 	if err != nil {
@@ -52,6 +74,8 @@ func main() {
 
 	// 确保bucket存在
 	config.EnsureBucketExists(minioClient, config.GetEnv("MINIO_BUCKET", "pool"))
+	// 资源文件只能通过临时URL或分享链接下载，不允许匿名直接访问
+	config.EnsureBucketPrivate(minioClient, config.GetEnv("resources", "resources"))
 
 	// 创建Gin实例
 	r := gin.New()
@@ -67,7 +91,7 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Share-Password"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
@@ -76,24 +100,40 @@ func main() {
 	r.NoRoute(middleware.NotFoundHandler)
 
 	// 创建Minio工具实例
-	_ = utils.NewMinioUtils(minioClient)
+	minioUtils := utils.NewMinioUtils(minioClient)
 
-	// 注册控制器
-	userController := controllers.NewUserController(db, minioClient)
-	resourceController := controllers.NewResourceController(db, minioClient)
-	// 初始化Redis客户端
-	redisClient, err := config.InitRedisClient()
-	if err != nil {
-		log.Fatalf("Redis客户端初始化失败: %v", err)
-	}
+	// 敏感词过滤，词表缓存在内存中
+	contentFilter := utils.NewContentFilter(db)
 
-	forumController := controllers.NewForumController(db, redisClient)
-	chatController := controllers.NewChatController(db)
-	pointsController := controllers.NewPointsController(db)
-	adminController := controllers.NewAdminController(db)
+	// 反垃圾，发帖频率和违规记录保存在Redis中
+	spamGuard := utils.NewSpamGuard(redisClient)
+
+	// 注册控制器
+	userController := controllers.NewUserController(db, minioClient, contentFilter)
+
+	// 实时推送通过Redis在多个实例间广播
+	realtimeHub := utils.NewRealtimeHub(redisClient)
+	notifier := utils.NewNotifier(db, realtimeHub)
+	// 主题和资源的浏览次数在Redis中去重累加，定时写入MySQL
+	viewCounter := utils.NewViewCounter(db, redisClient)
+	resourceController := controllers.NewResourceController(db, minioClient, notifier, viewCounter, contentFilter, spamGuard)
+
+	forumController := controllers.NewForumController(db, redisClient, notifier, realtimeHub, viewCounter, minioUtils, contentFilter, spamGuard)
+	chatController := controllers.NewChatController(db, realtimeHub)
+	pointsController := controllers.NewPointsController(db, notifier)
+	adminController := controllers.NewAdminController(db, notifier, minioUtils, contentFilter, spamGuard, forumController)
+	favoriteController := controllers.NewFavoriteController(db)
+	// 分享链接提取密码的错误次数保存在Redis中
+	shareGuard := utils.NewShareGuard(redisClient)
+	shareController := controllers.NewShareController(db, minioClient, shareGuard)
+	groupController := controllers.NewGroupController(db)
+	notificationController := controllers.NewNotificationController(db)
+	realtimeController := controllers.NewRealtimeController(db, realtimeHub)
+	draftController := controllers.NewDraftController(db)
+	tagController := controllers.NewTagController(db)
 
 	// 注册路由
-	routes.SetupRoutes(r, userController, resourceController, forumController, chatController, pointsController, adminController)
+	routes.SetupRoutes(r, userController, resourceController, forumController, chatController, pointsController, adminController, favoriteController, shareController, groupController, notificationController, realtimeController, draftController, tagController)
 
 	// 获取端口
 	port := config.GetEnv("PORT", "8080")
@@ -104,3 +144,18 @@ func main() {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
 }
+
+// runCommand 执行维护命令
+func runCommand(name string, db *gorm.DB, redisClient *redis.Client) {
+	switch name {
+	case "rebuild-votes":
+		// 以MySQL中的投票为准重建Redis缓存和主题表上的点赞点踩数
+		count, err := utils.NewTopicVoteStore(db, redisClient).Rebuild(context.Background())
+		if err != nil {
+			log.Fatalf("重建主题投票失败: %v", err)
+		}
+		log.Printf("已重建%d个主题的投票", count)
+	default:
+		log.Fatalf("未知的命令: %s", name)
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -13,64 +14,101 @@ import (
 	"g/front/backend/models"
 )
 
-// AuthMiddleware 认证中间件
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 从请求头获取token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供认证令牌"})
-			c.Abort()
-			return
+// parseToken 从Authorization请求头解析并校验JWT令牌，返回用户ID
+// 令牌缺失、格式错误、签名无效或已过期时返回错误提示，AuthMiddleware和OptionalAuthMiddleware共用
+func parseToken(c *gin.Context) (uint, string) {
+	// 从请求头获取token
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return 0, "未提供认证令牌"
+	}
+
+	// 解析Bearer token
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return 0, "认证格式无效"
+	}
+
+	// 解析JWT token，jwt.Parse会同时校验exp
+	token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
+		// 验证签名算法
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("无效的签名方法: %v", token.Header["alg"])
 		}
 
-		// 解析Bearer token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "认证格式无效"})
-			c.Abort()
-			return
+		// 返回密钥
+		return []byte(config.GetEnv("JWT_SECRET", "your-secret-key")), nil
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return 0, "认证令牌已过期"
 		}
+		return 0, "无效的认证令牌"
+	}
 
-		tokenString := parts[1]
+	// 验证token是否有效
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, "无效的认证令牌"
+	}
 
-		// 解析JWT token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// 验证签名算法
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("无效的签名方法: %v", token.Header["alg"])
-			}
+	// 缺少过期时间的令牌不接受
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return 0, "无效的认证令牌"
+	}
+	if float64(time.Now().Unix()) > exp {
+		return 0, "认证令牌已过期"
+	}
 
-			// 返回密钥
-			return []byte(config.GetEnv("JWT_SECRET", "your-secret-key")), nil
-		})
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, "无效的认证令牌"
+	}
+	return uint(userID), ""
+}
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
+// AuthMiddleware 认证中间件
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, msg := parseToken(c)
+		if msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
 			c.Abort()
 			return
 		}
 
-		// 验证token是否有效
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// 检查token是否过期
-			if float64(time.Now().Unix()) > claims["exp"].(float64) {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "认证令牌已过期"})
-				c.Abort()
-				return
-			}
+		// 将用户ID存储在上下文中
+		c.Set("userID", userID)
 
-			// 将用户ID存储在上下文中
-			userID := uint(claims["user_id"].(float64))
+		// 继续处理请求
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware 可选认证中间件
+// 携带有效令牌时与AuthMiddleware一样设置userID，未携带或令牌无效时按游客继续处理
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, msg := parseToken(c); msg == "" {
 			c.Set("userID", userID)
+		}
 
-			// 继续处理请求
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
-			c.Abort()
-			return
+		c.Next()
+	}
+}
+
+// QueryTokenMiddleware 允许通过查询参数token传递认证令牌
+// 浏览器的WebSocket和EventSource无法设置请求头，需放在AuthMiddleware之前使用
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
 		}
+		c.Next()
 	}
 }
 
@@ -1,17 +1,23 @@
 package migrations
 
 import (
+	"fmt"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 
 	"g/front/backend/models"
+	"g/front/backend/utils"
 )
 
 // RunMigrations 运行数据库迁移
 func RunMigrations(db *gorm.DB) {
 	log.Println("开始数据库迁移...")
 
+	// 订阅表是新建的，迁移完成后为已有主题的作者补上订阅
+	backfillWatches := !db.Migrator().HasTable(&models.Watch{})
+
 	// 自动迁移数据库表结构
 	err := db.AutoMigrate(
 		&models.User{},
@@ -26,11 +32,208 @@ func RunMigrations(db *gorm.DB) {
 		&models.UserFavorite{},
 		&models.UserLike{},
 		&models.UserTopicFavorite{},
+		&models.FavoriteFolder{},
+		&models.FavoriteItem{},
+		&models.ShareLink{},
+		&models.ShareLinkAccess{},
+		&models.WatermarkRecord{},
+		&models.UserGroup{},
+		&models.UserGroupMember{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.Mention{},
+		&models.UserBlock{},
+		&models.ContentVote{},
+		&models.TopicVote{},
+		&models.Watch{},
+		&models.WatchDigestItem{},
+		&models.WatchSetting{},
+		&models.Revision{},
+		&models.Attachment{},
+		&models.Poll{},
+		&models.PollOption{},
+		&models.PollBallot{},
+		&models.PollChoice{},
+		&models.Draft{},
+		&models.SensitiveWord{},
+		&models.ModerationLog{},
+		&models.ShadowBan{},
+		&models.Tag{},
+		&models.ResourceReference{},
 	)
 
 	if err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
 
+	// 为旧回复补全楼中楼路径
+	if err := db.Model(&models.Reply{}).Where("path = ? OR path IS NULL", "").
+		Update("path", gorm.Expr("CONCAT(id, '/')")).Error; err != nil {
+		log.Fatalf("回复路径迁移失败: %v", err)
+	}
+
+	// 将旧的收藏记录迁移到默认收藏夹
+	if err := migrateLegacyFavorites(db); err != nil {
+		log.Fatalf("收藏数据迁移失败: %v", err)
+	}
+
+	// 渲染缓存缺失或规则版本过旧的Markdown内容
+	if err := renderStaleMarkdown(db); err != nil {
+		log.Fatalf("Markdown渲染缓存更新失败: %v", err)
+	}
+
+	// 主题作者的新回复通知改由订阅发送，已有主题的作者默认订阅自己的主题
+	if backfillWatches {
+		if err := watchOwnTopics(db); err != nil {
+			log.Fatalf("主题订阅迁移失败: %v", err)
+		}
+	}
+
+	// 论坛搜索使用的全文索引
+	if err := createFulltextIndexes(db); err != nil {
+		log.Fatalf("全文索引创建失败: %v", err)
+	}
+
 	log.Println("数据库迁移完成")
 }
+
+// fulltextIndexes 论坛搜索使用的全文索引，使用ngram分词器以支持中文
+var fulltextIndexes = []struct {
+	table   string
+	name    string
+	columns string
+}{
+	{"topics", "ft_topics_title_content", "title, content"},
+	{"replies", "ft_replies_content", "content"},
+}
+
+// createFulltextIndexes 创建缺失的全文索引
+func createFulltextIndexes(db *gorm.DB) error {
+	for _, index := range fulltextIndexes {
+		if db.Migrator().HasIndex(index.table, index.name) {
+			continue
+		}
+
+		log.Printf("创建全文索引%s，数据较多时需要一些时间", index.name)
+		if err := db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON %s (%s) WITH PARSER ngram",
+			index.name, index.table, index.columns)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchOwnTopics 为所有未删除主题的作者创建即时通知的主题订阅
+func watchOwnTopics(db *gorm.DB) error {
+	result := db.Exec(`INSERT IGNORE INTO watches (user_id, target_type, target_id, delivery, created_at, updated_at)
+		SELECT user_id, ?, id, ?, NOW(), NOW() FROM topics WHERE deleted_at IS NULL`,
+		models.WatchTargetTopic, models.WatchDeliveryInstant)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("已为%d个已有主题的作者创建订阅", result.RowsAffected)
+	}
+	return nil
+}
+
+// renderStaleMarkdown 重新渲染主题、回复和评论中规则版本低于当前版本的内容
+// 只更新渲染相关的列，不修改updated_at
+func renderStaleMarkdown(db *gorm.DB) error {
+	for _, table := range []string{"topics", "replies", "comments"} {
+		var rows []struct {
+			ID      uint
+			Content string
+		}
+
+		total := 0
+		err := db.Table(table).Select("id", "content").
+			Where("render_version < ?", utils.MarkdownRenderVersion).
+			FindInBatches(&rows, 200, func(tx *gorm.DB, batch int) error {
+				for _, row := range rows {
+					if err := db.Table(table).Where("id = ?", row.ID).UpdateColumns(map[string]interface{}{
+						"content_html":   utils.RenderMarkdownWithMentions(row.Content, utils.ResolveMentions(db, row.Content)),
+						"render_version": utils.MarkdownRenderVersion,
+					}).Error; err != nil {
+						return err
+					}
+				}
+				total += len(rows)
+				return nil
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if total > 0 {
+			log.Printf("已重新渲染%s表中%d条Markdown内容", table, total)
+		}
+	}
+	return nil
+}
+
+// migrateLegacyFavorites 将UserFavorite和UserTopicFavorite中的记录迁移到用户的默认收藏夹
+// 迁移完成的旧记录会被软删除，因此重复执行不会产生重复数据
+func migrateLegacyFavorites(db *gorm.DB) error {
+	var resourceFavorites []models.UserFavorite
+	if err := db.Find(&resourceFavorites).Error; err != nil {
+		return err
+	}
+
+	var topicFavorites []models.UserTopicFavorite
+	if err := db.Find(&topicFavorites).Error; err != nil {
+		return err
+	}
+
+	if len(resourceFavorites) == 0 && len(topicFavorites) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, favorite := range resourceFavorites {
+			if err := migrateFavoriteItem(tx, favorite.UserID, models.FavoriteItemResource, favorite.ResourceID, favorite.CreatedAt); err != nil {
+				return err
+			}
+			if err := tx.Delete(&favorite).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, favorite := range topicFavorites {
+			if err := migrateFavoriteItem(tx, favorite.UserID, models.FavoriteItemTopic, favorite.TopicID, favorite.CreatedAt); err != nil {
+				return err
+			}
+			if err := tx.Delete(&favorite).Error; err != nil {
+				return err
+			}
+		}
+
+		log.Printf("已迁移%d条资源收藏和%d条主题收藏到默认收藏夹", len(resourceFavorites), len(topicFavorites))
+		return nil
+	})
+}
+
+// migrateFavoriteItem 在用户的默认收藏夹中创建收藏条目（已存在则跳过）
+func migrateFavoriteItem(tx *gorm.DB, userID uint, itemType string, itemID uint, createdAt time.Time) error {
+	folder, err := models.GetDefaultFavoriteFolder(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	tx.Model(&models.FavoriteItem{}).Where("user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	item := models.FavoriteItem{
+		UserID:    userID,
+		FolderID:  folder.ID,
+		ItemType:  itemType,
+		ItemID:    itemID,
+		SortOrder: models.NextFavoriteSortOrder(tx, folder.ID),
+		CreatedAt: createdAt,
+	}
+	return tx.Create(&item).Error
+}
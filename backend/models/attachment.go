@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 附件所属的内容类型
+const (
+	AttachmentTargetTopic = "topic"
+	AttachmentTargetReply = "reply"
+	AttachmentTargetDraft = "draft" // 草稿中引用的附件，发布后关联到主题或回复
+)
+
+// Attachment 论坛主题或回复中的附件
+// 上传后TargetType为空，发布或编辑的内容中引用了附件时关联到该内容；
+// 长时间未被引用的附件会被定时清理
+type Attachment struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Key             string    `json:"key" gorm:"size:36;not null;uniqueIndex"` // 访问附件使用的随机标识
+	UserID          uint      `json:"user_id" gorm:"not null;index"`
+	TargetType      string    `json:"target_type" gorm:"size:20;default:'';index:idx_attachment_target"` // topic, reply, draft，未使用时为空
+	TargetID        uint      `json:"target_id" gorm:"default:0;index:idx_attachment_target"`
+	FileName        string    `json:"file_name" gorm:"size:255;not null"` // 上传时的文件名
+	ObjectName      string    `json:"-" gorm:"size:255;not null"`         // MinIO中的路径
+	ThumbnailObject string    `json:"-" gorm:"size:255"`                  // 缩略图在MinIO中的路径，不是图片时为空
+	ContentType     string    `json:"content_type" gorm:"size:100"`
+	Size            int64     `json:"size"`
+	IsImage         bool      `json:"is_image" gorm:"default:false"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	CreatedAt       time.Time `json:"created_at" gorm:"index"`
+}
@@ -9,11 +9,19 @@ import (
 // Comment 资源评论模型
 type Comment struct {
 	gorm.Model
-	ResourceID string    `gorm:"type:varchar(36);not null;index"` // 资源ID
-	UserID     uint      `gorm:"not null;index"`                  // 用户ID
-	Rating     int       `gorm:"not null"`                        // 评分(1-5)
-	Content    string    `gorm:"type:text;not null"`              // 评论内容
-	Time       time.Time `gorm:"not null"`                        // 评论时间
+	ResourceID    string    `gorm:"type:varchar(36);not null;index"` // 资源ID
+	UserID        uint      `gorm:"not null;index"`                  // 用户ID
+	Rating        int       `gorm:"not null"`                        // 评分(1-5)
+	Content       string    `gorm:"type:text;not null"`              // 评论内容（Markdown原文）
+	ContentHTML   string    `gorm:"type:mediumtext"`                 // 渲染并过滤后的HTML
+	RenderVersion int       `json:"-" gorm:"default:0"`              // 渲染时使用的规则版本
+	Time          time.Time `gorm:"not null"`                        // 评论时间
+	UpvoteCount   int       `gorm:"default:0"`
+	DownvoteCount int       `gorm:"default:0"`
+	VoteScore     int       `gorm:"default:0;index"`              // 赞同数减反对数
+	MyVote        int       `gorm:"-"`                            // 当前用户的投票，不保存到数据库
+	Shadowed      bool      `json:"-" gorm:"default:false;index"` // 作者被静默封禁时发布，只有作者和管理员可见
+	Pending       bool      `gorm:"default:false;index"`          // 命中审核词，审核通过前只有作者和管理员可见
 
 	// 关联模型
 	User     User     `gorm:"foreignKey:UserID"`
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// 可以投票的内容类型
+const (
+	VoteTargetReply   = "reply"
+	VoteTargetComment = "comment"
+)
+
+// ContentVote 用户对回复或资源评论的投票，每个用户对同一内容只保留一票
+type ContentVote struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_content_vote_user"`
+	TargetType string    `json:"target_type" gorm:"size:20;not null;uniqueIndex:idx_content_vote_user;index:idx_content_vote_target"` // reply, comment
+	TargetID   uint      `json:"target_id" gorm:"not null;uniqueIndex:idx_content_vote_user;index:idx_content_vote_target"`
+	Value      int       `json:"value" gorm:"not null"` // 1 赞同，-1 反对
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 草稿对应的编辑场景
+const (
+	DraftContextTopic    = "topic"    // 发布新主题，ContextID为0
+	DraftContextReply    = "reply"    // 回复主题，ContextID为主题ID
+	DraftContextResource = "resource" // 提交资源，ContextID为0时为新资源，否则为修改的资源ID
+)
+
+// Draft 服务端保存的草稿，每个用户在同一个编辑场景下只有一份
+// 发布后删除，超过有效期未更新的草稿会被定时清理
+type Draft struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	UserID      uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_draft_user_context"`
+	ContextType string          `json:"context_type" gorm:"size:20;not null;uniqueIndex:idx_draft_user_context"` // topic, reply, resource
+	ContextID   uint            `json:"context_id" gorm:"not null;default:0;uniqueIndex:idx_draft_user_context"`
+	Title       string          `json:"title" gorm:"size:200"`
+	Content     string          `json:"content" gorm:"type:text"`
+	Data        json.RawMessage `json:"data" gorm:"type:text"` // 其他表单字段，如分类、投票设置，格式由客户端决定
+	ExpiresAt   time.Time       `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
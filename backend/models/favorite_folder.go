@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 收藏条目类型
+const (
+	FavoriteItemResource = "resource"
+	FavoriteItemTopic    = "topic"
+)
+
+// DefaultFavoriteFolderName 默认收藏夹名称
+const DefaultFavoriteFolderName = "默认收藏夹"
+
+// FavoriteFolder 用户收藏夹
+type FavoriteFolder struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"index;not null"`
+	Name        string    `json:"name" gorm:"size:50;not null"`
+	Description string    `json:"description" gorm:"size:255"`
+	IsDefault   bool      `json:"is_default" gorm:"default:false"`
+	SortOrder   int       `json:"sort_order" gorm:"default:0"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// 非数据库字段，仅用于API响应
+	ItemCount int64 `json:"item_count" gorm:"-"`
+}
+
+// FavoriteItem 收藏夹中的条目，可以是资源或论坛主题
+type FavoriteItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_favorite_item_user_target"`
+	FolderID  uint      `json:"folder_id" gorm:"not null;index"`
+	ItemType  string    `json:"item_type" gorm:"size:20;not null;uniqueIndex:idx_favorite_item_user_target"` // resource, topic
+	ItemID    uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_favorite_item_user_target"`
+	Note      string    `json:"note" gorm:"type:text"` // 仅自己可见的备注
+	SortOrder int       `json:"sort_order" gorm:"default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// 非数据库字段，仅用于API响应
+	Resource    *Resource `json:"resource,omitempty" gorm:"-"`
+	Topic       *Topic    `json:"topic,omitempty" gorm:"-"`
+	Unavailable bool      `json:"unavailable,omitempty" gorm:"-"` // 收藏的内容已删除或当前用户不能再查看
+}
+
+// GetDefaultFavoriteFolder 获取用户的默认收藏夹，不存在时自动创建
+func GetDefaultFavoriteFolder(db *gorm.DB, userID uint) (*FavoriteFolder, error) {
+	folder := FavoriteFolder{
+		UserID:    userID,
+		Name:      DefaultFavoriteFolderName,
+		IsDefault: true,
+	}
+	if err := db.Where("user_id = ? AND is_default = ?", userID, true).FirstOrCreate(&folder).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// NextFavoriteSortOrder 获取收藏夹中下一个条目的排序值
+func NextFavoriteSortOrder(db *gorm.DB, folderID uint) int {
+	var maxOrder int
+	db.Model(&FavoriteItem{}).Where("folder_id = ?", folderID).Select("COALESCE(MAX(sort_order), 0)").Scan(&maxOrder)
+	return maxOrder + 1
+}
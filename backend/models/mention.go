@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// 提及所在的内容类型
+const (
+	MentionSourceTopic   = "topic"
+	MentionSourceReply   = "reply"
+	MentionSourceComment = "comment"
+)
+
+// Mention 内容中@提及的用户，编辑内容时用于判断哪些用户是新提及的
+type Mention struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SourceType string    `json:"source_type" gorm:"size:20;not null;uniqueIndex:idx_mention_source_user"` // topic, reply, comment
+	SourceID   uint      `json:"source_id" gorm:"not null;uniqueIndex:idx_mention_source_user"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_mention_source_user;index"` // 被提及的用户
+	AuthorID   uint      `json:"author_id" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// 屏蔽类型
+const (
+	BlockKindMute  = "mute"  // 免打扰：不接收对方的@提及通知
+	BlockKindBlock = "block" // 拉黑：不接收对方触发的任何通知
+)
+
+// UserBlock 用户对其他用户的屏蔽设置
+type UserBlock struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_block"`
+	BlockedUserID uint      `json:"blocked_user_id" gorm:"not null;uniqueIndex:idx_user_block"`
+	BlockedUser   *User     `json:"blocked_user,omitempty" gorm:"foreignKey:BlockedUserID"`
+	Kind          string    `json:"kind" gorm:"size:10;not null"` // mute, block
+	CreatedAt     time.Time `json:"created_at"`
+}
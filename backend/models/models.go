@@ -22,24 +22,33 @@ type User struct {
 
 // Resource 资源模型
 type Resource struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	Title          string         `json:"title" gorm:"size:100;not null"`
-	Description    string         `json:"description" gorm:"type:text"`
-	CategoryID     uint           `json:"category_id"`
-	Category       Category       `json:"category" gorm:"foreignKey:CategoryID"`
-	FilePath       string         `json:"file_path" gorm:"size:255"`
-	FileSize       int64          `json:"file_size"`
-	FileType       string         `json:"file_type" gorm:"size:50"`
-	DownloadCount  int            `json:"download_count" gorm:"default:0"`
-	PointsRequired int            `json:"points_required" gorm:"default:0"`
-	Status         string         `json:"status" gorm:"size:20;default:'pending'"` // pending, approved, rejected
-	UserID         uint           `json:"user_id"`
-	User           User           `json:"user" gorm:"foreignKey:UserID"`
-	Likes          []UserLike     `json:"likes" gorm:"foreignKey:ResourceID"`
-	Favorites      []UserFavorite `json:"favorites" gorm:"foreignKey:ResourceID"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  uint           `json:"id" gorm:"primaryKey"`
+	Title               string         `json:"title" gorm:"size:100;not null"`
+	Description         string         `json:"description" gorm:"type:text"`
+	CategoryID          uint           `json:"category_id"`
+	Category            Category       `json:"category" gorm:"foreignKey:CategoryID"`
+	FilePath            string         `json:"-" gorm:"size:255"` // 存储桶中的对象键，不返回给前端，下载通过临时URL
+	FileSize            int64          `json:"file_size"`
+	FileType            string         `json:"file_type" gorm:"size:50"`
+	DownloadCount       int            `json:"download_count" gorm:"default:0"`
+	ViewCount           int            `json:"view_count" gorm:"default:0"`
+	PointsRequired      int            `json:"points_required" gorm:"default:0"`
+	Status              string         `json:"status" gorm:"size:20;default:'pending'"`          // pending, approved, rejected
+	SharingDisabled     bool           `json:"sharing_disabled" gorm:"default:false"`            // 管理员禁止通过分享链接下载
+	WatermarkOnDownload bool           `json:"watermark_on_download" gorm:"default:false"`       // 下载PDF时添加用户水印
+	Visibility          string         `json:"visibility" gorm:"size:20;default:'public';index"` // public, logged_in, group, private
+	GroupID             *uint          `json:"group_id"`                                         // 可见范围为group时允许访问的用户组
+	DiscussionTopicID   *uint          `json:"discussion_topic_id"`                              // 上传者为资源创建的讨论主题
+	UserID              uint           `json:"user_id"`
+	User                User           `json:"user" gorm:"foreignKey:UserID"`
+	Likes               []UserLike     `json:"likes" gorm:"foreignKey:ResourceID"`
+	Favorites           []UserFavorite `json:"favorites" gorm:"foreignKey:ResourceID"`
+	Tags                []Tag          `json:"tags" gorm:"many2many:resource_tags"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
+	// 非数据库字段，仅用于API响应：图片资源的临时预览URL
+	PreviewURL string `json:"preview_url,omitempty" gorm:"-"`
 }
 
 // Category 资源分类模型
@@ -72,35 +81,106 @@ type PointRecord struct {
 
 // Topic 论坛主题模型
 type Topic struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	Title        string         `json:"title" gorm:"size:100;not null"`
-	Content      string         `json:"content" gorm:"type:text;not null"`
-	UserID       uint           `json:"user_id"`
-	User         User           `json:"user" gorm:"foreignKey:UserID"`
-	CategoryID   uint           `json:"category_id"`
-	Category     Category       `json:"category" gorm:"foreignKey:CategoryID"`
-	ViewCount    int            `json:"view_count" gorm:"default:0"`
-	ReplyCount   int            `json:"reply_count" gorm:"default:0"`
-	LikeCount    int64          `json:"like_count" gorm:"default:0"`
-	DislikeCount int64          `json:"dislike_count" gorm:"default:0"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Title           string         `json:"title" gorm:"size:100;not null"`
+	Content         string         `json:"content" gorm:"type:text;not null"`   // Markdown原文
+	ContentHTML     string         `json:"content_html" gorm:"type:mediumtext"` // 渲染并过滤后的HTML
+	RenderVersion   int            `json:"-" gorm:"default:0"`                  // 渲染时使用的规则版本
+	UserID          uint           `json:"user_id"`
+	User            User           `json:"user" gorm:"foreignKey:UserID"`
+	CategoryID      uint           `json:"category_id"`
+	Category        Category       `json:"category" gorm:"foreignKey:CategoryID"`
+	ViewCount       int            `json:"view_count" gorm:"default:0"`
+	ReplyCount      int            `json:"reply_count" gorm:"default:0"`
+	LikeCount       int64          `json:"like_count" gorm:"default:0"`
+	DislikeCount    int64          `json:"dislike_count" gorm:"default:0"`
+	PinScope        string         `json:"pin_scope" gorm:"size:10;default:'';index"` // 置顶范围：global 全站，category 本分类，空表示未置顶
+	IsLocked        bool           `json:"is_locked" gorm:"default:false"`            // 锁定后不能回复
+	IsFeatured      bool           `json:"is_featured" gorm:"default:false;index"`    // 精华
+	FeatureRewarded bool           `json:"-" gorm:"default:false"`                    // 是否已发放精华奖励，取消后再加精不重复奖励
+	IsHidden        bool           `json:"is_hidden" gorm:"default:false;index"`      // 隐藏后只有作者和管理员可见
+	HiddenReason    string         `json:"hidden_reason,omitempty" gorm:"size:255"`
+	HiddenAt        *time.Time     `json:"hidden_at,omitempty"`
+	Shadowed        bool           `json:"-" gorm:"default:false;index"`                   // 作者被静默封禁时发布，只有作者和管理员可见
+	Type            string         `json:"type" gorm:"size:20;default:'discussion';index"` // discussion 普通讨论，question 问答
+	IsSolved        bool           `json:"is_solved" gorm:"default:false;index"`           // 问答是否已采纳答案
+	AcceptedReplyID *uint          `json:"accepted_reply_id"`
+	SolvedAt        *time.Time     `json:"solved_at,omitempty"`
+	BountyPoints    int            `json:"bounty_points" gorm:"default:0"`          // 托管的悬赏积分
+	BountyStatus    string         `json:"bounty_status" gorm:"size:20;default:''"` // 悬赏状态，没有悬赏时为空
+	BountyDeadline  *time.Time     `json:"bounty_deadline,omitempty" gorm:"index"`  // 到期未采纳时自动结算
+	IsEdited        bool           `json:"is_edited" gorm:"default:false"`          // 发布后标题或内容被修改过
+	EditedAt        *time.Time     `json:"edited_at"`                               // 最后一次修改标题或内容的时间
+	Tags            []Tag          `json:"tags" gorm:"many2many:topic_tags"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 主题置顶范围
+const (
+	PinScopeGlobal   = "global"
+	PinScopeCategory = "category"
+)
+
+// 主题类型
+const (
+	TopicTypeDiscussion = "discussion"
+	TopicTypeQuestion   = "question"
+)
+
+// 悬赏状态
+const (
+	BountyStatusOpen     = "open"     // 积分已托管，等待采纳
+	BountyStatusPaid     = "paid"     // 提问者采纳答案后发放
+	BountyStatusAwarded  = "awarded"  // 到期后自动发放给得分最高的回答
+	BountyStatusRefunded = "refunded" // 到期没有合适的回答或主题被删除，退回提问者
+)
+
 // Reply 论坛回复模型
 type Reply struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Content   string         `json:"content" gorm:"type:text;not null"`
-	UserID    uint           `json:"user_id"`
-	User      User           `json:"user" gorm:"foreignKey:UserID"`
-	TopicID   uint           `json:"topic_id"`
-	Topic     Topic          `json:"topic" gorm:"foreignKey:TopicID"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Content         string         `json:"content" gorm:"type:text;not null"`   // Markdown原文
+	ContentHTML     string         `json:"content_html" gorm:"type:mediumtext"` // 渲染并过滤后的HTML
+	RenderVersion   int            `json:"-" gorm:"default:0"`                  // 渲染时使用的规则版本
+	UserID          uint           `json:"user_id"`
+	User            User           `json:"user" gorm:"foreignKey:UserID"`
+	TopicID         uint           `json:"topic_id"`
+	Topic           Topic          `json:"topic" gorm:"foreignKey:TopicID"`
+	ParentID        *uint          `json:"parent_id" gorm:"index"` // 直接回复的上级回复，为空表示直接回复主题
+	ReplyToUserID   *uint          `json:"reply_to_user_id"`       // 上级回复的作者
+	ReplyToUser     *User          `json:"reply_to_user,omitempty" gorm:"foreignKey:ReplyToUserID"`
+	Path            string         `json:"path" gorm:"size:255;index"`        // 从顶层回复到自身的ID路径，例如 12/34/56/
+	Depth           int            `json:"depth" gorm:"default:0"`            // 顶层回复为0
+	ChildCount      int            `json:"child_count" gorm:"default:0"`      // 直接子回复数
+	DescendantCount int            `json:"descendant_count" gorm:"default:0"` // 整个子树的回复数
+	QuotedReplyID   *uint          `json:"quoted_reply_id"`                   // 引用的回复
+	QuotedUserID    *uint          `json:"quoted_user_id"`
+	QuotedUser      *User          `json:"quoted_user,omitempty" gorm:"foreignKey:QuotedUserID"`
+	QuoteContent    string         `json:"quote_content" gorm:"type:text"`               // 引用的原文片段
+	IsDeleted       bool           `json:"is_deleted" gorm:"default:false"`              // 有子回复的回复被删除后保留占位，维持楼中楼结构
+	RewardPoints    int            `json:"-" gorm:"default:0"`                           // 发表回复时获得的积分，删除回复时扣回
+	IsAccepted      bool           `json:"is_accepted" gorm:"default:false"`             // 被提问者采纳为答案
+	Shadowed        bool           `json:"-" gorm:"default:false;index"`                 // 作者被静默封禁时发布，只有作者和管理员可见
+	Pending         bool           `json:"pending,omitempty" gorm:"default:false;index"` // 命中审核词，审核通过前只有作者和管理员可见
+	Unpublished     bool           `json:"-" gorm:"default:false"`                       // 创建时就待审核，还没有发送过回复通知
+	UpvoteCount     int            `json:"upvote_count" gorm:"default:0"`
+	DownvoteCount   int            `json:"downvote_count" gorm:"default:0"`
+	VoteScore       int            `json:"vote_score" gorm:"default:0"`    // 赞同数减反对数，用于按最佳排序和悬赏到期时选出最佳回答
+	IsEdited        bool           `json:"is_edited" gorm:"default:false"` // 发布后内容被修改过
+	EditedAt        *time.Time     `json:"edited_at"`                      // 最后一次修改内容的时间
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	MyVote          int            `json:"my_vote" gorm:"-"` // 当前用户的投票：1 赞同，-1 反对，0 未投票
+	// 非数据库字段，仅用于树形视图
+	Children        []Reply `json:"children,omitempty" gorm:"-"`
+	HasMoreChildren bool    `json:"has_more_children,omitempty" gorm:"-"`
 }
 
+// MaxReplyDepth 楼中楼的最大嵌套层数，超过后挂到同一层
+const MaxReplyDepth = 10
+
 type UserFavorite struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
 	UserID     uint           `json:"user_id"`
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationReply      = "reply"      // 主题或楼层收到回复、回复被引用
+	NotificationReview     = "review"     // 资源审核结果
+	NotificationPoints     = "points"     // 管理员调整积分
+	NotificationLike       = "like"       // 主题或资源被点赞
+	NotificationMention    = "mention"    // 被@提及
+	NotificationModeration = "moderation" // 主题被加精、隐藏、移动等版务操作
+	NotificationAnswer     = "answer"     // 回答被采纳、悬赏到期结算
+	NotificationWatch      = "watch"      // 订阅的主题或分类有新动态
+)
+
+// NotificationTypes 所有通知类型及其名称，用于通知偏好设置
+var NotificationTypes = map[string]string{
+	NotificationReply:      "回复",
+	NotificationReview:     "资源审核",
+	NotificationPoints:     "积分变动",
+	NotificationLike:       "点赞",
+	NotificationMention:    "@提及",
+	NotificationModeration: "版务通知",
+	NotificationAnswer:     "问答悬赏",
+	NotificationWatch:      "订阅动态",
+}
+
+// 通知关联的对象类型
+const (
+	NotificationTargetTopic    = "topic"
+	NotificationTargetReply    = "reply"
+	NotificationTargetResource = "resource"
+	NotificationTargetPoints   = "points"
+	NotificationTargetDigest   = "digest" // 订阅摘要，包含多个主题的动态
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index:idx_notification_user_read"` // 接收者
+	Type       string     `json:"type" gorm:"size:20;not null;index"`
+	Title      string     `json:"title" gorm:"size:100"`
+	Content    string     `json:"content" gorm:"size:500"`
+	ActorID    *uint      `json:"actor_id"` // 触发通知的用户，系统通知为空
+	Actor      *User      `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+	TargetType string     `json:"target_type" gorm:"size:20"` // topic, reply, resource, points, digest
+	TargetID   uint       `json:"target_id"`
+	TopicID    *uint      `json:"topic_id"` // 回复类通知所在的主题，方便前端跳转
+	IsRead     bool       `json:"is_read" gorm:"default:false;index:idx_notification_user_read"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NotificationPreference 用户的通知偏好，没有记录的类型默认开启
+type NotificationPreference struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_notification_pref"`
+	Type      string    `json:"type" gorm:"size:20;not null;uniqueIndex:idx_notification_pref"`
+	Enabled   bool      `json:"enabled" gorm:"default:true"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Poll 主题中的投票
+type Poll struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	TopicID    uint         `json:"topic_id" gorm:"not null;uniqueIndex"`
+	Question   string       `json:"question" gorm:"size:200"`       // 为空时使用主题标题
+	Multiple   bool         `json:"multiple" gorm:"default:false"`  // 是否多选
+	MaxChoices int          `json:"max_choices" gorm:"default:0"`   // 多选时最多选几项，0表示不限
+	Anonymous  bool         `json:"anonymous" gorm:"default:false"` // 匿名投票不公开投票人
+	ClosesAt   *time.Time   `json:"closes_at"`                      // 截止时间，为空表示不截止
+	VoterCount int          `json:"voter_count" gorm:"default:0"`
+	Options    []PollOption `json:"options" gorm:"foreignKey:PollID"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// PollOption 投票的选项
+type PollOption struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	PollID    uint   `json:"poll_id" gorm:"not null;index"`
+	Content   string `json:"content" gorm:"size:100;not null"`
+	SortOrder int    `json:"sort_order" gorm:"default:0"`
+	VoteCount int    `json:"vote_count" gorm:"default:0"`
+}
+
+// PollBallot 用户在投票中的选票，每人只能投一次
+type PollBallot struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PollID    uint      `json:"poll_id" gorm:"not null;uniqueIndex:idx_poll_ballot_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_poll_ballot_user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PollChoice 选票中选择的一个选项
+type PollChoice struct {
+	ID       uint  `json:"id" gorm:"primaryKey"`
+	BallotID uint  `json:"ballot_id" gorm:"not null;index"`
+	PollID   uint  `json:"poll_id" gorm:"not null;index"`
+	OptionID uint  `json:"option_id" gorm:"not null;index"`
+	UserID   uint  `json:"user_id" gorm:"not null"`
+	User     *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// IsClosed 投票是否已经截止
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosesAt != nil && !now.Before(*p.ClosesAt)
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ResourceReference 主题内容中引用的资源，用于查询讨论某个资源的主题
+type ResourceReference struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ResourceID uint      `json:"resource_id" gorm:"not null;uniqueIndex:idx_resource_reference"`
+	TopicID    uint      `json:"topic_id" gorm:"not null;uniqueIndex:idx_resource_reference;index"`
+	CreatedAt  time.Time `json:"created_at"`
+}
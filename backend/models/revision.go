@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 编辑历史所属的内容类型
+const (
+	RevisionTargetTopic = "topic"
+	RevisionTargetReply = "reply"
+)
+
+// Revision 主题或回复的一个版本
+// 第一次编辑时把发布时的内容保存为版本1，之后每次编辑或回滚追加一个版本，最新版本与当前内容一致
+type Revision struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TargetType string    `json:"target_type" gorm:"size:20;not null;uniqueIndex:idx_revision_target_version"` // topic, reply
+	TargetID   uint      `json:"target_id" gorm:"not null;uniqueIndex:idx_revision_target_version"`
+	Version    int       `json:"version" gorm:"not null;uniqueIndex:idx_revision_target_version"`
+	Title      string    `json:"title" gorm:"size:100"` // 主题标题，回复为空
+	Content    string    `json:"content" gorm:"type:text"`
+	EditorID   uint      `json:"editor_id"`
+	Editor     *User     `json:"editor,omitempty" gorm:"foreignKey:EditorID"`
+	Reason     string    `json:"reason" gorm:"size:200"`
+	RevertOf   *int      `json:"revert_of"` // 管理员回滚时恢复的版本号
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// 敏感词的处理方式，同一段文本命中多个敏感词时按 block > review > mask 取最严格的
+const (
+	SensitiveActionBlock  = "block"  // 拒绝提交
+	SensitiveActionReview = "review" // 允许提交，进入人工审核
+	SensitiveActionMask   = "mask"   // 替换为*后提交
+)
+
+// 审核队列中记录的状态
+const (
+	ModerationStatusPending  = "pending"
+	ModerationStatusApproved = "approved"
+	ModerationStatusRejected = "rejected"
+)
+
+// 命中敏感词的内容来源
+const (
+	ModerationSourceTopic    = "topic"
+	ModerationSourceReply    = "reply"
+	ModerationSourceComment  = "comment"
+	ModerationSourceResource = "resource"
+	ModerationSourceUsername = "username"
+)
+
+// SensitiveWord 管理员维护的敏感词，匹配时忽略大小写、全角半角以及词中夹杂的空格和符号
+type SensitiveWord struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Word      string    `json:"word" gorm:"size:100;not null;uniqueIndex"`
+	Action    string    `json:"action" gorm:"size:10;not null;default:'block'"` // block, review, mask
+	Category  string    `json:"category" gorm:"size:50"`                        // 分类，如广告、辱骂，仅用于管理
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ModerationLog 敏感词命中记录
+// 命中审核词的记录同时是审核队列中的一项，Status为pending，管理员处理后改为approved或rejected
+type ModerationLog struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Source     string     `json:"source" gorm:"size:20;not null;index:idx_moderation_source"` // topic, reply, comment, resource, username
+	SourceID   uint       `json:"source_id" gorm:"index:idx_moderation_source"`               // 被拒绝提交的内容为0
+	Field      string     `json:"field" gorm:"size:20"`                                       // 命中的字段，如title、content
+	Action     string     `json:"action" gorm:"size:10;not null;index"`                       // 实际采取的处理方式
+	Words      string     `json:"words" gorm:"size:255"`                                      // 命中的敏感词，逗号分隔
+	Excerpt    string     `json:"excerpt" gorm:"type:text"`                                   // 提交的原文片段
+	Status     string     `json:"status" gorm:"size:10;index"`                                // 审核状态，不需要审核时为空
+	ReviewerID *uint      `json:"reviewer_id"`
+	ReviewNote string     `json:"review_note" gorm:"size:255"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+}
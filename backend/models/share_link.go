@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// ShareLink 资源分享链接，由资源上传者创建，未注册用户也可以通过链接下载
+type ShareLink struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	Token            string     `json:"token" gorm:"size:64;not null;uniqueIndex"`
+	ResourceID       uint       `json:"resource_id" gorm:"not null;index"`
+	Resource         *Resource  `json:"resource,omitempty" gorm:"foreignKey:ResourceID"`
+	UserID           uint       `json:"user_id" gorm:"not null;index"` // 创建者
+	ExpiresAt        time.Time  `json:"expires_at"`
+	MaxUses          int        `json:"max_uses" gorm:"default:0"` // 0表示不限次数
+	UseCount         int        `json:"use_count" gorm:"default:0"`
+	RequiresPassword bool       `json:"requires_password" gorm:"default:false"`
+	PasswordHash     string     `json:"-" gorm:"size:100"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// ShareLinkAccess 分享链接的访问记录
+type ShareLinkAccess struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ShareLinkID uint      `json:"share_link_id" gorm:"not null;index"`
+	IP          string    `json:"ip" gorm:"size:64"`
+	UserAgent   string    `json:"user_agent" gorm:"size:255"`
+	Success     bool      `json:"success"`
+	Reason      string    `json:"reason" gorm:"size:50"` // 失败原因，如 expired, exhausted, bad_password, locked
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ShadowBan 静默封禁
+// 被静默封禁的用户可以正常发帖，但发布的主题、回复和评论只有自己和管理员能看到，也不会获得积分和触发通知
+type ShadowBan struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	User      *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Reason    string     `json:"reason" gorm:"size:255"`
+	CreatedBy uint       `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"` // 为空表示永久
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Active 封禁在某一时刻是否有效
+func (b *ShadowBan) Active(now time.Time) bool {
+	return b.ExpiresAt == nil || b.ExpiresAt.After(now)
+}
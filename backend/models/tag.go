@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Tag 标签，主题和资源共用同一套标签
+type Tag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:30;not null;uniqueIndex"`
+	Description string    `json:"description,omitempty" gorm:"size:255"`
+	Reserved    bool      `json:"reserved" gorm:"default:false"` // 保留标签只有管理员能添加和移除，如“官方”“已解决”
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// 非数据库字段，仅用于API响应
+	TopicCount    int `json:"topic_count,omitempty" gorm:"-"`
+	ResourceCount int `json:"resource_count,omitempty" gorm:"-"`
+}
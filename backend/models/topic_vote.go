@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TopicVote 用户对主题的点赞或点踩，是主题投票的持久化数据
+// Redis中的投票缓存和主题表上的点赞数都可以由这张表重建
+type TopicVote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TopicID   uint      `json:"topic_id" gorm:"not null;uniqueIndex:idx_topic_vote_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_topic_vote_user;index"`
+	Value     int       `json:"value" gorm:"not null"` // 1 点赞，-1 点踩
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// 资源可见范围
+const (
+	VisibilityPublic   = "public"    // 所有人可见
+	VisibilityLoggedIn = "logged_in" // 登录用户可见
+	VisibilityGroup    = "group"     // 指定用户组成员可见
+	VisibilityPrivate  = "private"   // 仅上传者可见
+)
+
+// 用户组成员角色
+const (
+	GroupRoleOwner  = "owner"
+	GroupRoleMember = "member"
+)
+
+// UserGroup 用户组，例如某个班级或助教组
+type UserGroup struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:50;not null"`
+	Description string    `json:"description" gorm:"size:255"`
+	OwnerID     uint      `json:"owner_id" gorm:"index;not null"`
+	Owner       *User     `json:"owner,omitempty" gorm:"foreignKey:OwnerID"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// 非数据库字段，仅用于API响应
+	MemberCount int64 `json:"member_count" gorm:"-"`
+}
+
+// UserGroupMember 用户组成员
+type UserGroupMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	GroupID   uint      `json:"group_id" gorm:"not null;uniqueIndex:idx_group_member"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_group_member;index"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Role      string    `json:"role" gorm:"size:20;default:'member'"` // owner, member
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsValidVisibility 检查可见范围取值是否合法
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityLoggedIn, VisibilityGroup, VisibilityPrivate:
+		return true
+	}
+	return false
+}
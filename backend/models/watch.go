@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// 订阅对象类型
+const (
+	WatchTargetTopic    = "topic"    // 订阅主题：接收主题的新回复
+	WatchTargetCategory = "category" // 订阅分类：接收分类下的新主题
+)
+
+// 订阅动态的送达方式
+const (
+	WatchDeliveryInstant = "instant" // 每条动态即时通知
+	WatchDeliveryDaily   = "daily"   // 每日摘要
+	WatchDeliveryWeekly  = "weekly"  // 每周摘要
+)
+
+// Watch 用户对主题或分类的订阅
+type Watch struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_watch_user_target"`
+	TargetType string    `json:"target_type" gorm:"size:20;not null;uniqueIndex:idx_watch_user_target;index:idx_watch_target"` // topic, category
+	TargetID   uint      `json:"target_id" gorm:"not null;uniqueIndex:idx_watch_user_target;index:idx_watch_target"`
+	Delivery   string    `json:"delivery" gorm:"size:10;not null;default:'instant'"` // instant, daily, weekly
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WatchDigestItem 等待汇总到每日或每周摘要中的订阅动态
+// ReplyID为空时表示订阅的分类下发布了新主题，否则为订阅的主题收到了新回复
+type WatchDigestItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index:idx_digest_delivery_user"`
+	WatchID   uint      `json:"watch_id" gorm:"not null;index"`
+	Delivery  string    `json:"delivery" gorm:"size:10;not null;index:idx_digest_delivery_user,priority:1"` // daily, weekly
+	TopicID   uint      `json:"topic_id" gorm:"not null"`
+	ReplyID   *uint     `json:"reply_id"`
+	ActorID   uint      `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WatchSetting 用户的订阅设置，没有记录时使用默认值
+type WatchSetting struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	UserID           uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+	AutoWatchOwn     bool      `json:"auto_watch_own" gorm:"default:true"`                         // 发布主题时自动订阅
+	AutoWatchReplied bool      `json:"auto_watch_replied" gorm:"default:true"`                     // 回复主题时自动订阅
+	DefaultDelivery  string    `json:"default_delivery" gorm:"size:10;not null;default:'instant'"` // 自动订阅使用的送达方式
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// DefaultWatchSetting 用户没有保存过设置时使用的订阅设置
+func DefaultWatchSetting(userID uint) WatchSetting {
+	return WatchSetting{
+		UserID:           userID,
+		AutoWatchOwn:     true,
+		AutoWatchReplied: true,
+		DefaultDelivery:  WatchDeliveryInstant,
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// WatermarkRecord 带水印的下载副本记录
+// 同一用户（或同一分享链接）对同一资源的最新一条记录作为缓存使用，旧记录保留用于水印溯源
+type WatermarkRecord struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ResourceID uint      `json:"resource_id" gorm:"not null;index:idx_watermark_resource_user"`
+	Resource   *Resource `json:"resource,omitempty" gorm:"foreignKey:ResourceID"`
+	UserID     *uint     `json:"user_id" gorm:"index:idx_watermark_resource_user"` // 通过分享链接匿名下载时为空
+	User       *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	// 通过分享链接下载时记录分享链接
+	ShareLinkID *uint      `json:"share_link_id,omitempty" gorm:"index"`
+	ShareLink   *ShareLink `json:"share_link,omitempty" gorm:"foreignKey:ShareLinkID"`
+	TraceCode   string     `json:"trace_code" gorm:"size:64;uniqueIndex;not null"`
+	SourcePath  string     `json:"source_path" gorm:"size:255"` // 生成水印时的原文件路径，原文件被替换后缓存失效
+	ObjectKey   string     `json:"object_key" gorm:"size:255;not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
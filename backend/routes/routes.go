@@ -8,7 +8,7 @@ import (
 )
 
 // SetupRoutes 设置API路由
-func SetupRoutes(r *gin.Engine, userController *controllers.UserController, resourceController *controllers.ResourceController, forumController *controllers.ForumController, chatController *controllers.ChatController, pointsController *controllers.PointsController, adminController *controllers.AdminController) {
+func SetupRoutes(r *gin.Engine, userController *controllers.UserController, resourceController *controllers.ResourceController, forumController *controllers.ForumController, chatController *controllers.ChatController, pointsController *controllers.PointsController, adminController *controllers.AdminController, favoriteController *controllers.FavoriteController, shareController *controllers.ShareController, groupController *controllers.GroupController, notificationController *controllers.NotificationController, realtimeController *controllers.RealtimeController, draftController *controllers.DraftController, tagController *controllers.TagController) {
 	// API路由组
 	api := r.Group("/api")
 
@@ -19,23 +19,54 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 		public.POST("/register", userController.Register)
 		public.POST("/login", userController.Login)
 
-		// 资源相关路由
+		// 资源相关路由，登录用户可以看到更多受限资源
 		resourceRoutes := public.Group("/resources")
+		resourceRoutes.Use(middleware.OptionalAuthMiddleware())
 		{
 			resourceRoutes.GET("", resourceController.GetResources)
 			resourceRoutes.GET("/categories", resourceController.GetCategories)
 			resourceRoutes.GET("/:id", resourceController.GetResourceById)
 			resourceRoutes.GET("/search", resourceController.SearchResources)
 			resourceRoutes.GET("/:id/comments", resourceController.GetComments)
+			resourceRoutes.GET("/:id/recommendations", resourceController.GetRecommendations)
+			resourceRoutes.GET("/:id/views", resourceController.GetResourceViews)
+			resourceRoutes.GET("/:id/discussions", resourceController.GetResourceDiscussions)
 		}
 
 		// 资源评论
 
-		// 论坛相关
-		public.GET("/forum/categories", forumController.GetCategories)
-		public.GET("/forum/topics", forumController.GetTopics)
-		public.GET("/forum/topics/:id", forumController.GetTopicById)
-		public.GET("/forum/topics/:id/likes", forumController.GetTopicLikes)
+		// 论坛相关，登录用户可以看到自己被隐藏的主题
+		forumRoutes := public.Group("/forum")
+		forumRoutes.Use(middleware.OptionalAuthMiddleware())
+		{
+			forumRoutes.GET("/categories", forumController.GetCategories)
+			forumRoutes.GET("/topics", forumController.GetTopics)
+			forumRoutes.GET("/search", forumController.SearchForum)
+			forumRoutes.GET("/topics/:id", forumController.GetTopicById)
+			forumRoutes.GET("/topics/:id/likes", forumController.GetTopicLikes)
+			forumRoutes.GET("/topics/:id/poll", forumController.GetPoll)
+			forumRoutes.GET("/topics/:id/views", forumController.GetTopicViews)
+			forumRoutes.GET("/replies/:id/children", forumController.GetReplyChildren)
+			forumRoutes.GET("/attachments/:key", forumController.GetAttachment)
+			forumRoutes.GET("/attachments/:key/thumbnail", forumController.GetAttachmentThumbnail)
+			forumRoutes.GET("/topics/:id/revisions", forumController.GetTopicRevisions)
+			forumRoutes.GET("/topics/:id/revisions/diff", forumController.GetTopicRevisionDiff)
+			forumRoutes.GET("/replies/:id/revisions", forumController.GetReplyRevisions)
+			forumRoutes.GET("/replies/:id/revisions/diff", forumController.GetReplyRevisionDiff)
+		}
+
+		// 标签，标签页中的主题和资源按访问者的权限过滤
+		tagRoutes := public.Group("/tags")
+		tagRoutes.Use(middleware.OptionalAuthMiddleware())
+		{
+			tagRoutes.GET("", tagController.GetTags)
+			tagRoutes.GET("/:name", tagController.GetTag)
+		}
+
+		// 资源分享链接
+		public.GET("/share/:token", shareController.GetShareInfo)
+		public.GET("/share/:token/download", shareController.DownloadShared)
+		public.POST("/share/:token/download", shareController.DownloadShared)
 	}
 
 	// 需要认证的路由
@@ -48,6 +79,13 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 		protected.PUT("/user/avatar", userController.UploadAvatar)
 		protected.POST("/user/avatar", userController.UploadAvatar)
 		protected.POST("/user/refresh-token", userController.RefreshToken)
+
+		// @提及补全和用户屏蔽
+		protected.GET("/users/autocomplete", userController.AutocompleteUsers)
+		protected.GET("/user/blocks", userController.GetBlockedUsers)
+		protected.POST("/user/blocks", userController.BlockUser)
+		protected.DELETE("/user/blocks/:userId", userController.UnblockUser)
+
 		// 评论管理
 		protected.DELETE("/resources/comments/:id", resourceController.DeleteComment)
 
@@ -62,8 +100,11 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 
 		// 资源管理
 		protected.POST("/resources/:id/comments", resourceController.PostComment)
+		protected.POST("/resources/comments/:id/vote", resourceController.VoteComment)
 		protected.POST("/resources", resourceController.CreateResource)
 		protected.PUT("/resources/:id", resourceController.UpdateResource)
+		protected.PUT("/resources/:id/tags", resourceController.SetResourceTags)
+		protected.POST("/resources/:id/discussion", forumController.CreateResourceDiscussion)
 		protected.DELETE("/resources/:id", resourceController.DeleteResource)
 		protected.GET("/user/resources", resourceController.GetUserResources)
 		protected.GET("/user/my-resources", resourceController.GetMyResources)
@@ -74,9 +115,53 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 
 		protected.GET("/resources/:id/favorite-status", resourceController.GetFavoriteStatus)
 		protected.GET("/favorites/resources", resourceController.GetUserFavorites)
+
+		// 收藏夹
+		protected.GET("/favorites", favoriteController.GetFavorites)
+		protected.POST("/favorites", favoriteController.AddFavorite)
+		protected.PUT("/favorites/:id", favoriteController.UpdateFavoriteNote)
+		protected.PUT("/favorites/:id/move", favoriteController.MoveFavorite)
+		protected.DELETE("/favorites/:id", favoriteController.RemoveFavorite)
+		protected.GET("/favorites/folders", favoriteController.GetFolders)
+		protected.POST("/favorites/folders", favoriteController.CreateFolder)
+		protected.PUT("/favorites/folders/order", favoriteController.ReorderFolders)
+		protected.PUT("/favorites/folders/:id", favoriteController.UpdateFolder)
+		protected.DELETE("/favorites/folders/:id", favoriteController.DeleteFolder)
+		protected.PUT("/favorites/folders/:id/order", favoriteController.ReorderItems)
+
 		protected.POST("/resources/upload", resourceController.UploadResource)
 		protected.GET("/download/:id", resourceController.GetResourceDownloadUrl)
 
+		// 资源分享链接管理
+		protected.POST("/resources/:id/share-links", shareController.CreateShareLink)
+		protected.GET("/user/share-links", shareController.GetMyShareLinks)
+		protected.GET("/user/share-links/:id/accesses", shareController.GetShareLinkAccesses)
+		protected.DELETE("/user/share-links/:id", shareController.RevokeShareLink)
+
+		// 用户组
+		protected.GET("/groups", groupController.GetMyGroups)
+		protected.POST("/groups", groupController.CreateGroup)
+		protected.GET("/groups/:id", groupController.GetGroup)
+		protected.PUT("/groups/:id", groupController.UpdateGroup)
+		protected.DELETE("/groups/:id", groupController.DeleteGroup)
+		protected.POST("/groups/:id/members", groupController.AddMember)
+		protected.DELETE("/groups/:id/members/:userId", groupController.RemoveMember)
+
+		// 通知中心
+		protected.GET("/notifications", notificationController.GetNotifications)
+		protected.GET("/notifications/unread-count", notificationController.GetUnreadCount)
+		protected.PUT("/notifications/read-all", notificationController.MarkAllRead)
+		protected.PUT("/notifications/:id/read", notificationController.MarkRead)
+		protected.DELETE("/notifications/:id", notificationController.DeleteNotification)
+		protected.GET("/notifications/preferences", notificationController.GetPreferences)
+		protected.PUT("/notifications/preferences", notificationController.UpdatePreferences)
+
+		// 草稿
+		protected.GET("/drafts", draftController.GetDrafts)
+		protected.PUT("/drafts", draftController.SaveDraft)
+		protected.GET("/drafts/:id", draftController.GetDraft)
+		protected.DELETE("/drafts/:id", draftController.DeleteDraft)
+
 		// 资源点赞
 		protected.POST("/resources/:id/like", resourceController.LikeResource)
 		protected.DELETE("/resources/:id/dislike", resourceController.DislikeResource)
@@ -85,10 +170,18 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 		// 论坛管理
 		protected.POST("/forum/topics", forumController.CreateTopic)
 		protected.PUT("/forum/topics/:id", forumController.UpdateTopic)
+		protected.PUT("/forum/topics/:id/tags", forumController.SetTopicTags)
 		protected.DELETE("/forum/topics/:id", forumController.DeleteTopic)
 		protected.POST("/forum/topics/:id/replies", forumController.CreateReply)
 		protected.PUT("/forum/replies/:id", forumController.UpdateReply)
 		protected.DELETE("/forum/replies/:id", forumController.DeleteReply)
+		protected.POST("/forum/topics/:id/accept", forumController.AcceptAnswer)
+		protected.POST("/forum/topics/:id/poll/vote", forumController.VotePoll)
+		protected.POST("/forum/replies/:id/vote", forumController.VoteReply)
+		protected.POST("/forum/markdown/preview", forumController.PreviewMarkdown)
+		protected.POST("/forum/attachments", forumController.UploadAttachment)
+		protected.GET("/forum/attachments", forumController.GetMyAttachments)
+		protected.DELETE("/forum/attachments/:key", forumController.DeleteAttachment)
 		protected.POST("/forum/topics/:id/like", forumController.LikeTopic)
 		protected.DELETE("/forum/topics/:id/like", forumController.UnlikeTopic)
 		protected.POST("/forum/topics/:id/dislike", forumController.DislikeTopic)
@@ -99,6 +192,15 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 		protected.DELETE("/forum/topics/:id/favorite", forumController.RemoveFavorite)
 		protected.GET("/forum/topics/:id/favorite-status", forumController.GetFavoriteStatus)
 
+		// 论坛订阅
+		protected.GET("/forum/watches", forumController.GetWatches)
+		protected.PUT("/forum/topics/:id/watch", forumController.WatchTopic)
+		protected.DELETE("/forum/topics/:id/watch", forumController.UnwatchTopic)
+		protected.PUT("/forum/categories/:id/watch", forumController.WatchCategory)
+		protected.DELETE("/forum/categories/:id/watch", forumController.UnwatchCategory)
+		protected.GET("/forum/watch-settings", forumController.GetWatchSettings)
+		protected.PUT("/forum/watch-settings", forumController.UpdateWatchSettings)
+
 		// 管理员路由
 		admin := api.Group("/admin")
 		admin.Use(middleware.AuthMiddleware())
@@ -115,10 +217,40 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 			// 资源管理
 			admin.GET("/resources", adminController.GetResources)
 			admin.DELETE("/resources/:id", adminController.DeleteResource)
+			admin.PUT("/resources/:id/sharing", adminController.SetResourceSharing)
+			admin.POST("/watermarks/extract", adminController.ExtractWatermark)
 
 			// 论坛管理
 			admin.GET("/forum/topics", adminController.GetTopics)
 			admin.DELETE("/forum/topics/:id", adminController.DeleteTopic)
+			admin.PUT("/forum/topics/:id/pin", adminController.PinTopic)
+			admin.PUT("/forum/topics/:id/lock", adminController.LockTopic)
+			admin.PUT("/forum/topics/:id/feature", adminController.FeatureTopic)
+			admin.PUT("/forum/topics/:id/move", adminController.MoveTopic)
+			admin.PUT("/forum/topics/:id/hide", adminController.HideTopic)
+			admin.POST("/forum/topics/:id/revisions/:version/revert", adminController.RevertTopic)
+			admin.POST("/forum/replies/:id/revisions/:version/revert", adminController.RevertReply)
+
+			// 敏感词和内容审核
+			admin.GET("/sensitive-words", adminController.GetSensitiveWords)
+			admin.POST("/sensitive-words", adminController.AddSensitiveWords)
+			admin.PUT("/sensitive-words/:id", adminController.UpdateSensitiveWord)
+			admin.DELETE("/sensitive-words/:id", adminController.DeleteSensitiveWord)
+			admin.GET("/moderation/logs", adminController.GetModerationLogs)
+			admin.PUT("/moderation/logs/:id/review", adminController.ReviewModerationLog)
+
+			// 反垃圾
+			admin.GET("/shadow-bans", adminController.GetShadowBans)
+			admin.POST("/users/:id/shadow-ban", adminController.ShadowBanUser)
+			admin.DELETE("/users/:id/shadow-ban", adminController.LiftShadowBan)
+			admin.GET("/users/:id/spam", adminController.GetUserSpamStatus)
+			admin.DELETE("/users/:id/spam", adminController.ResetUserSpam)
+
+			// 标签管理
+			admin.POST("/tags", adminController.CreateTag)
+			admin.PUT("/tags/:id", adminController.UpdateTag)
+			admin.POST("/tags/:id/merge", adminController.MergeTag)
+			admin.DELETE("/tags/:id", adminController.DeleteTag)
 
 			// 积分管理
 			admin.GET("/points/records", adminController.GetPointsRecords)
@@ -141,4 +273,12 @@ func SetupRoutes(r *gin.Engine, userController *controllers.UserController, reso
 			chat.POST("/messages", chatController.SendMessage)
 		}
 	}
+
+	// 实时推送，浏览器无法为WebSocket和EventSource设置请求头，允许通过token参数认证
+	realtime := api.Group("/realtime")
+	realtime.Use(middleware.QueryTokenMiddleware(), middleware.AuthMiddleware())
+	{
+		realtime.GET("/ws", realtimeController.ServeWebSocket)
+		realtime.GET("/events", realtimeController.ServeSSE)
+	}
 }
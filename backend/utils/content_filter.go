@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// contentFilterRefresh 词表的最长缓存时间，其他实例修改词表后最迟在该时间后生效
+const contentFilterRefresh = time.Minute
+
+// sensitiveActionRank 处理方式的严格程度
+var sensitiveActionRank = map[string]int{
+	models.SensitiveActionMask:   1,
+	models.SensitiveActionReview: 2,
+	models.SensitiveActionBlock:  3,
+}
+
+// FilterResult 一段文本的检查结果
+type FilterResult struct {
+	Action string   // 命中的最严格的处理方式，未命中时为空
+	Text   string   // 替换屏蔽词后的文本
+	Words  []string // 命中的敏感词，按首次出现的顺序去重
+}
+
+// ContentFilter 敏感词过滤
+// 词表缓存在内存中并构建为Aho-Corasick自动机，一次扫描完成全部匹配
+type ContentFilter struct {
+	DB *gorm.DB
+
+	mu       sync.RWMutex
+	matcher  *WordMatcher
+	words    []models.SensitiveWord
+	loadedAt time.Time
+}
+
+// NewContentFilter 创建敏感词过滤并加载词表
+func NewContentFilter(db *gorm.DB) *ContentFilter {
+	f := &ContentFilter{DB: db}
+	f.Reload()
+	return f
+}
+
+// Reload 从数据库重新加载词表，管理员修改词表后调用
+func (f *ContentFilter) Reload() {
+	var words []models.SensitiveWord
+	if err := f.DB.Order("id ASC").Find(&words).Error; err != nil {
+		log.Printf("加载敏感词失败: %v", err)
+		return
+	}
+
+	patterns := make([]string, len(words))
+	for i, word := range words {
+		patterns[i] = word.Word
+	}
+	matcher := NewWordMatcher(patterns)
+
+	f.mu.Lock()
+	f.matcher = matcher
+	f.words = words
+	f.loadedAt = time.Now()
+	f.mu.Unlock()
+}
+
+// snapshot 当前的词表和自动机，缓存过期时先重新加载
+func (f *ContentFilter) snapshot() (*WordMatcher, []models.SensitiveWord) {
+	f.mu.RLock()
+	stale := time.Since(f.loadedAt) > contentFilterRefresh
+	f.mu.RUnlock()
+	if stale {
+		f.Reload()
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.matcher, f.words
+}
+
+// Check 检查一段文本，屏蔽词替换为*，其他处理方式由调用方决定
+func (f *ContentFilter) Check(text string) FilterResult {
+	result := FilterResult{Text: text}
+	matcher, words := f.snapshot()
+	if matcher == nil || text == "" {
+		return result
+	}
+
+	matches := matcher.FindAll(text)
+	if len(matches) == 0 {
+		return result
+	}
+
+	runes := []rune(text)
+	masked := false
+	seen := make(map[int]bool)
+	for _, match := range matches {
+		word := words[match.Pattern]
+		if !seen[match.Pattern] {
+			seen[match.Pattern] = true
+			result.Words = append(result.Words, word.Word)
+		}
+		if sensitiveActionRank[word.Action] > sensitiveActionRank[result.Action] {
+			result.Action = word.Action
+		}
+		if word.Action == models.SensitiveActionMask {
+			// 夹在词中的空格和符号一并替换
+			for i := match.Start; i < match.End; i++ {
+				runes[i] = '*'
+			}
+			masked = true
+		}
+	}
+	if masked {
+		result.Text = string(runes)
+	}
+	return result
+}
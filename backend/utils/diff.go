@@ -0,0 +1,108 @@
+package utils
+
+import "strings"
+
+// 差异行的类型
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffCells 逐行比较时LCS表格的最大单元数，超出时不同的部分整体作为删除和插入
+const maxDiffCells = 1000000
+
+// DiffLine 行级差异中的一行
+type DiffLine struct {
+	Type string `json:"type"` // equal, insert, delete
+	Text string `json:"text"`
+}
+
+// DiffLines 按行比较两段文本，返回把from变为to的差异，删除的行排在插入的行之前
+func DiffLines(from, to string) []DiffLine {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// 去掉相同的开头和结尾，只比较中间不同的部分
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, DiffLine{Type: DiffEqual, Text: text})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Type: DiffEqual, Text: text})
+	}
+	return lines
+}
+
+// splitLines 将文本拆分为行，统一换行符，空文本没有行
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffMiddle 用最长公共子序列比较两组行
+func diffMiddle(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	var lines []DiffLine
+
+	if n == 0 || m == 0 || (n+1)*(m+1) > maxDiffCells {
+		for _, text := range a {
+			lines = append(lines, DiffLine{Type: DiffDelete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, DiffLine{Type: DiffInsert, Text: text})
+		}
+		return lines
+	}
+
+	// lcs[i*(m+1)+j] 为a[i:]和b[j:]的最长公共子序列长度
+	width := m + 1
+	lcs := make([]int32, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Type: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			lines = append(lines, DiffLine{Type: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Type: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, DiffLine{Type: DiffDelete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, DiffLine{Type: DiffInsert, Text: b[j]})
+	}
+	return lines
+}
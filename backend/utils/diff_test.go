@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) DiffLine { return DiffLine{Type: DiffEqual, Text: text} }
+	ins := func(text string) DiffLine { return DiffLine{Type: DiffInsert, Text: text} }
+	del := func(text string) DiffLine { return DiffLine{Type: DiffDelete, Text: text} }
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []DiffLine
+	}{
+		{"都为空", "", "", []DiffLine{}},
+		{"相同", "a\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"新增全部", "", "a\nb", []DiffLine{ins("a"), ins("b")}},
+		{"删除全部", "a\nb", "", []DiffLine{del("a"), del("b")}},
+		{"末尾追加", "a\nb", "a\nb\nc", []DiffLine{eq("a"), eq("b"), ins("c")}},
+		{"中间修改", "a\nb\nc", "a\nx\nc", []DiffLine{eq("a"), del("b"), ins("x"), eq("c")}},
+		{"删除排在插入之前", "a\nb\nc\nd", "a\nx\ny\nd", []DiffLine{eq("a"), del("b"), del("c"), ins("x"), ins("y"), eq("d")}},
+		{"保留中间的公共行", "a\nb\nc\nd\ne", "a\nx\nc\ny\ne", []DiffLine{eq("a"), del("b"), ins("x"), eq("c"), del("d"), ins("y"), eq("e")}},
+		{"统一换行符", "a\r\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"空行", "a\n\nb", "a\nb", []DiffLine{eq("a"), del(""), eq("b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesLarge 超出LCS表格上限时不同的部分整体作为删除和插入
+func TestDiffLinesLarge(t *testing.T) {
+	var from, to []byte
+	for i := 0; i < 1200; i++ {
+		from = append(from, "a\n"...)
+		to = append(to, "b\n"...)
+	}
+	lines := DiffLines("head\n"+string(from)+"tail", "head\n"+string(to)+"tail")
+
+	counts := map[string]int{}
+	for _, line := range lines {
+		counts[line.Type]++
+	}
+	want := map[string]int{DiffEqual: 2, DiffDelete: 1200, DiffInsert: 1200}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if lines[1].Type != DiffDelete || lines[len(lines)-2].Type != DiffInsert {
+		t.Errorf("删除的行应排在插入的行之前")
+	}
+}
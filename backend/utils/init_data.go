@@ -16,6 +16,9 @@ func InitData(db *gorm.DB) {
 
 	// 初始化资源分类
 	initCategories(db)
+
+	// 初始化保留标签
+	initReservedTags(db)
 }
 
 // 初始化管理员账号
@@ -133,3 +136,24 @@ func initCategories(db *gorm.DB) {
 		log.Printf("基础分类创建完成，共创建了%d个主分类和%d个论坛分类\n", len(mainCategories), len(forumCategories))
 	}
 }
+
+// 初始化保留标签，已存在的同名标签保持不变
+func initReservedTags(db *gorm.DB) {
+	tags := []models.Tag{
+		{Name: "官方", Description: "管理员发布的官方内容", Reserved: true},
+		{Name: "已解决", Description: "问题已经解决", Reserved: true},
+	}
+
+	for _, tag := range tags {
+		var count int64
+		if db.Model(&models.Tag{}).Where("name = ?", tag.Name).Count(&count); count > 0 {
+			continue
+		}
+
+		if err := db.Create(&tag).Error; err != nil {
+			log.Printf("创建标签失败: %v\n", err)
+		} else {
+			log.Printf("成功创建标签: %s\n", tag.Name)
+		}
+	}
+}
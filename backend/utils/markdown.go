@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// MarkdownRenderVersion Markdown渲染规则的版本号
+// 修改渲染或过滤规则后递增，启动时会重新渲染缓存的HTML
+const MarkdownRenderVersion = 1
+
+var (
+	markdownRenderer = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, &mathExtension{}, &mentionExtension{}),
+	)
+	markdownPolicy = newMarkdownPolicy()
+)
+
+// RenderMarkdown 将Markdown渲染为经过白名单过滤的HTML
+// 支持GFM（表格、删除线、任务列表、自动链接）、代码块和 $...$ / $$...$$ 数学公式，
+// 原始HTML不会被输出
+func RenderMarkdown(source string) string {
+	if source == "" {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &buf); err != nil {
+		log.Printf("Markdown渲染失败: %v", err)
+		return markdownPolicy.Sanitize(source)
+	}
+	return markdownPolicy.Sanitize(buf.String())
+}
+
+// newMarkdownPolicy 创建渲染结果的HTML白名单
+func newMarkdownPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements("p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"blockquote", "pre", "code", "em", "strong", "del", "sup", "sub",
+		"ul", "ol", "li", "table", "thead", "tbody", "tr", "th", "td")
+
+	// 链接和图片只允许http、https和mailto
+	p.AllowStandardURLs()
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^mention$`)).OnElements("a")
+	p.AllowAttrs("src", "alt", "title").OnElements("img")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowStyles("text-align").MatchingEnum("left", "center", "right").OnElements("th", "td")
+
+	// 任务列表
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	// 代码高亮和数学公式由前端根据class渲染
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^math math-(inline|display)$`)).OnElements("span", "div")
+
+	return p
+}
+
+// 数学公式节点
+var (
+	kindMathInline = ast.NewNodeKind("MathInline")
+	kindMathBlock  = ast.NewNodeKind("MathBlock")
+)
+
+// mathInline 行内公式 $...$ 或单行的 $$...$$
+type mathInline struct {
+	ast.BaseInline
+	display bool
+}
+
+func (n *mathInline) Kind() ast.NodeKind { return kindMathInline }
+
+func (n *mathInline) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+// mathBlock 独占多行的 $$ 公式块
+type mathBlock struct {
+	ast.BaseBlock
+}
+
+func (n *mathBlock) Kind() ast.NodeKind { return kindMathBlock }
+
+func (n *mathBlock) IsRaw() bool { return true }
+
+func (n *mathBlock) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+// mathInlineParser 解析行内公式，公式不能跨行
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+
+	opener := 1
+	if len(line) > 1 && line[1] == '$' {
+		opener = 2
+	}
+
+	for i := opener; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] != '$' {
+			continue
+		}
+
+		closer := 0
+		for j := i; j < len(line) && line[j] == '$'; j++ {
+			closer++
+		}
+		if closer != opener {
+			i += closer - 1
+			continue
+		}
+
+		content := line[opener:i]
+		if len(bytes.TrimSpace(content)) == 0 {
+			return nil
+		}
+
+		// 单个$时要求公式首尾不是空白、结束符后不是数字，避免把“$5 到 $10”识别成公式
+		if opener == 1 {
+			if util.IsSpace(content[0]) || util.IsSpace(content[len(content)-1]) {
+				return nil
+			}
+			if i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+				return nil
+			}
+		}
+
+		node := &mathInline{display: opener == 2}
+		node.AppendChild(node, ast.NewRawTextSegment(text.NewSegment(segment.Start+opener, segment.Start+i)))
+		block.Advance(i + closer)
+		return node
+	}
+
+	return nil
+}
+
+// mathBlockParser 解析以单独一行 $$ 开始和结束的公式块
+type mathBlockParser struct{}
+
+func (b *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (b *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	if !bytes.Equal(bytes.TrimSpace(line), []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - 1)
+	return &mathBlock{}, parser.NoChildren
+}
+
+func (b *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if bytes.Equal(bytes.TrimSpace(line), []byte("$$")) {
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *mathBlockParser) CanInterruptParagraph() bool { return true }
+
+func (b *mathBlockParser) CanAcceptIndentedLine() bool { return false }
+
+// mathRenderer 输出带class的公式原文，由前端的KaTeX等库渲染
+type mathRenderer struct{}
+
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMathInline, r.renderInline)
+	reg.Register(kindMathBlock, r.renderBlock)
+}
+
+func (r *mathRenderer) renderInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	class := "math math-inline"
+	if node.(*mathInline).display {
+		class = "math math-display"
+	}
+
+	w.WriteString(`<span class="` + class + `">`)
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		w.Write(util.EscapeHTML(c.(*ast.Text).Segment.Value(source)))
+	}
+	w.WriteString("</span>")
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	w.WriteString(`<div class="math math-display">`)
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		w.Write(util.EscapeHTML(line.Value(source)))
+	}
+	w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// mathExtension 为goldmark添加数学公式支持
+type mathExtension struct{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 850)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 150)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&mathRenderer{}, 500)))
+}
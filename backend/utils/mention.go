@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// maxMentionLength 用户名的最大长度，与用户表一致
+const maxMentionLength = 50
+
+var (
+	// mentionCandidatesKey 解析过程中遇到的@用户名
+	mentionCandidatesKey = parser.NewContextKey()
+	// mentionUsersKey 已解析的用户，键为小写用户名
+	mentionUsersKey = parser.NewContextKey()
+)
+
+// ExtractMentions 提取内容中@提及的用户名，代码块和行内代码中的@不算提及
+// 返回的用户名去重，保持出现顺序
+func ExtractMentions(source string) []string {
+	if !strings.Contains(source, "@") {
+		return nil
+	}
+
+	pc := parser.NewContext()
+	markdownRenderer.Parser().Parse(text.NewReader([]byte(source)), parser.WithContext(pc))
+
+	candidates, _ := pc.Get(mentionCandidatesKey).([]string)
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(candidates))
+	for _, name := range candidates {
+		key := strings.ToLower(name)
+		if !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ResolveMentions 查询内容中@提及的用户，返回小写用户名到用户ID的映射，不存在的用户名被忽略
+func ResolveMentions(db *gorm.DB, source string) map[string]uint {
+	names := ExtractMentions(source)
+	if len(names) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	db.Select("id", "username").Where("username IN ?", names).Find(&users)
+
+	resolved := make(map[string]uint, len(users))
+	for _, user := range users {
+		resolved[strings.ToLower(user.Username)] = user.ID
+	}
+	return resolved
+}
+
+// RenderMarkdownWithMentions 渲染Markdown，并将已解析的@用户名渲染为指向用户主页的链接
+// users的键为小写用户名，值为用户ID
+func RenderMarkdownWithMentions(source string, users map[string]uint) string {
+	if len(users) == 0 {
+		return RenderMarkdown(source)
+	}
+
+	pc := parser.NewContext()
+	pc.Set(mentionUsersKey, users)
+
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &buf, parser.WithContext(pc)); err != nil {
+		return RenderMarkdown(source)
+	}
+	return markdownPolicy.Sanitize(buf.String())
+}
+
+// isMentionRune 用户名中允许的字符
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// mentionParser 解析 @用户名
+// @前面是字母或数字时（例如邮箱地址）不算提及
+type mentionParser struct{}
+
+func (p *mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (p *mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if previous := block.PrecendingCharacter(); isMentionRune(previous) {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+
+	end := 1
+	count := 0
+	for end < len(line) && count < maxMentionLength {
+		r, size := utf8.DecodeRune(line[end:])
+		if !isMentionRune(r) {
+			break
+		}
+		end += size
+		count++
+	}
+
+	// 句末的标点不属于用户名
+	end = 1 + len(bytes.TrimRight(line[1:end], ".-"))
+	if end == 1 {
+		return nil
+	}
+
+	name := string(line[1:end])
+	candidates, _ := pc.Get(mentionCandidatesKey).([]string)
+	pc.Set(mentionCandidatesKey, append(candidates, name))
+
+	users, _ := pc.Get(mentionUsersKey).(map[string]uint)
+	userID, ok := users[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+
+	link := ast.NewLink()
+	link.Destination = []byte("/users/" + strconv.FormatUint(uint64(userID), 10))
+	link.SetAttributeString("class", []byte("mention"))
+	link.AppendChild(link, ast.NewTextSegment(text.NewSegment(segment.Start, segment.Start+end)))
+	block.Advance(end)
+	return link
+}
+
+// mentionExtension 为goldmark添加@提及支持
+type mentionExtension struct{}
+
+func (e *mentionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithInlineParsers(util.Prioritized(&mentionParser{}, 500)),
+	)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"没有@", "你好", nil},
+		{"单个", "@alice 你好", []string{"alice"}},
+		{"中文用户名", "谢谢 @张三。", []string{"张三"}},
+		{"多个并去重", "@alice @bob @Alice", []string{"alice", "bob"}},
+		{"允许的符号", "@a.b_c-d", []string{"a.b_c-d"}},
+		{"邮箱不算", "mail@example.com", []string{}},
+		{"行内代码不算", "`@alice` 和 @bob", []string{"bob"}},
+		{"代码块不算", "```\n@alice\n```\n@bob", []string{"bob"}},
+		{"只有@", "@ 你好", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractMentions(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractMentions(%q) = %#v, want %#v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
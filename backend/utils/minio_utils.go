@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"mime/multipart"
+	"net/url"
 	"path/filepath"
 	"time"
 
@@ -60,6 +61,31 @@ func (m *MinioUtils) UploadFile(file *multipart.FileHeader, directory string) (s
 	return fileName, info.Size, nil
 }
 
+// PutObject 上传数据到指定路径
+func (m *MinioUtils) PutObject(objectName string, reader io.Reader, size int64, contentType string) error {
+	_, err := m.Client.PutObject(
+		context.Background(),
+		m.BucketName,
+		objectName,
+		reader,
+		size,
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	return err
+}
+
+// GetDownloadURL 获取以附件形式下载文件的临时URL，下载时使用fileName作为文件名
+func (m *MinioUtils) GetDownloadURL(objectName, fileName string, expires time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(fileName)))
+
+	presignedURL, err := m.Client.PresignedGetObject(context.Background(), m.BucketName, objectName, expires, params)
+	if err != nil {
+		return "", err
+	}
+	return presignedURL.String(), nil
+}
+
 // GetFileURL 获取文件的临时URL
 func (m *MinioUtils) GetFileURL(fileName string, expires time.Duration) (string, error) {
 	// 检查文件是否存在
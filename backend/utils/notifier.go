@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"g/front/backend/models"
+)
+
+// Notifier 站内通知的统一发送入口
+// 各模块只需构造通知内容，是否发送由接收者的通知偏好决定，
+// 发送后同时推送到接收者的实时频道
+type Notifier struct {
+	DB  *gorm.DB
+	Hub *RealtimeHub
+}
+
+// NewNotifier 创建通知发送器
+func NewNotifier(db *gorm.DB, hub *RealtimeHub) *Notifier {
+	return &Notifier{DB: db, Hub: hub}
+}
+
+// Notify 发送一条通知
+// 不会通知触发者本人；接收者关闭了该类型的通知或屏蔽了触发者时不发送；
+// 同一用户对同一对象的未读点赞通知只保留一条
+func (n *Notifier) Notify(notification *models.Notification) {
+	if notification.UserID == 0 {
+		return
+	}
+
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return
+	}
+
+	if !n.Enabled(notification.UserID, notification.Type) {
+		return
+	}
+
+	if notification.ActorID != nil && n.Blocked(notification.UserID, *notification.ActorID, notification.Type) {
+		return
+	}
+
+	if notification.Type == models.NotificationLike {
+		var count int64
+		n.DB.Model(&models.Notification{}).
+			Where("user_id = ? AND type = ? AND actor_id = ? AND target_type = ? AND target_id = ? AND is_read = ?",
+				notification.UserID, notification.Type, notification.ActorID, notification.TargetType, notification.TargetID, false).
+			Count(&count)
+		if count > 0 {
+			return
+		}
+	}
+
+	// 标题中带有主题名等用户输入，超出列长度时截断，避免写入失败
+	if len([]rune(notification.Title)) > 100 {
+		notification.Title = string([]rune(notification.Title)[:99]) + "…"
+	}
+	if len([]rune(notification.Content)) > 200 {
+		notification.Content = string([]rune(notification.Content)[:200]) + "…"
+	}
+	notification.CreatedAt = time.Now()
+
+	if err := n.DB.Create(notification).Error; err != nil {
+		log.Printf("发送通知失败: %v", err)
+		return
+	}
+
+	var unreadCount int64
+	n.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", notification.UserID, false).Count(&unreadCount)
+	n.Hub.Publish(UserChannel(notification.UserID), EventNotification, map[string]interface{}{
+		"notification": notification,
+		"unread_count": unreadCount,
+	})
+}
+
+// NotifyMany 向多个用户发送同一内容的通知，重复的接收者只通知一次
+func (n *Notifier) NotifyMany(userIDs []uint, template models.Notification) {
+	sent := make(map[uint]bool)
+	for _, userID := range userIDs {
+		if sent[userID] {
+			continue
+		}
+		sent[userID] = true
+
+		notification := template
+		notification.UserID = userID
+		n.Notify(&notification)
+	}
+}
+
+// Enabled 检查用户是否开启了某类通知
+func (n *Notifier) Enabled(userID uint, notificationType string) bool {
+	var preference models.NotificationPreference
+	if err := n.DB.Where("user_id = ? AND type = ?", userID, notificationType).First(&preference).Error; err != nil {
+		return true
+	}
+	return preference.Enabled
+}
+
+// Blocked 检查接收者是否屏蔽了触发者的这类通知
+// 拉黑屏蔽对方的所有通知，免打扰只屏蔽@提及
+func (n *Notifier) Blocked(userID, actorID uint, notificationType string) bool {
+	kinds := []string{models.BlockKindBlock}
+	if notificationType == models.NotificationMention {
+		kinds = append(kinds, models.BlockKindMute)
+	}
+
+	var count int64
+	n.DB.Model(&models.UserBlock{}).
+		Where("user_id = ? AND blocked_user_id = ? AND kind IN ?", userID, actorID, kinds).
+		Count(&count)
+	return count > 0
+}
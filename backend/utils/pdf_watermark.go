@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// WatermarkPropertyKey PDF信息字典中保存隐形水印的键名
+const WatermarkPropertyKey = "RPTrace"
+
+// traceCodePattern 隐形水印追踪码格式: RPWM-<用户ID>-<Unix时间戳>-<随机串>
+var traceCodePattern = regexp.MustCompile(`RPWM-(\d+)-(\d+)-([0-9a-f]{8})`)
+
+// ErrWatermarkNotFound 文件中没有找到水印
+var ErrWatermarkNotFound = errors.New("未找到水印")
+
+func init() {
+	// 不读取pdfcpu的用户配置目录
+	api.DisableConfigDir()
+}
+
+// NewTraceCode 生成携带用户ID和时间戳的追踪码
+func NewTraceCode(userID uint, at time.Time) (string, error) {
+	suffix, err := RandomToken(4)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("RPWM-%d-%d-%s", userID, at.Unix(), suffix), nil
+}
+
+// ParseTraceCode 解析追踪码中的用户ID和时间戳
+func ParseTraceCode(code string) (uint, time.Time, bool) {
+	m := traceCodePattern.FindStringSubmatch(code)
+	if m == nil {
+		return 0, time.Time{}, false
+	}
+	var userID uint
+	var ts int64
+	fmt.Sscan(m[1], &userID)
+	fmt.Sscan(m[2], &ts)
+	return userID, time.Unix(ts, 0), true
+}
+
+// StampPDFWatermark 为PDF的每一页添加可见水印，并写入隐形水印
+// 可见水印是半透明的斜向文字；隐形水印同时写入页面（透明文字）和文档信息字典
+func StampPDFWatermark(src io.ReadSeeker, w io.Writer, visibleText, traceCode string) error {
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	visible, err := api.TextWatermark(visibleText,
+		"font:Helvetica, points:24, scale:0.8 rel, rotation:45, opacity:0.15, fillcolor:0.5 0.5 0.5",
+		true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+
+	hidden, err := api.TextWatermark(traceCode,
+		"font:Helvetica, points:4, scale:1 abs, rotation:0, opacity:0, position:bl, offset:2 2",
+		true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+
+	var stamped bytes.Buffer
+	if err := api.AddWatermarks(src, &stamped, nil, visible, conf); err != nil {
+		return err
+	}
+
+	var withHidden bytes.Buffer
+	if err := api.AddWatermarks(bytes.NewReader(stamped.Bytes()), &withHidden, nil, hidden, conf); err != nil {
+		return err
+	}
+
+	return api.AddProperties(bytes.NewReader(withHidden.Bytes()), w, map[string]string{WatermarkPropertyKey: traceCode}, conf)
+}
+
+// ExtractPDFWatermark 从PDF中提取隐形水印追踪码
+// 优先读取文档信息字典，信息字典被清除时再扫描解压后的页面内容
+func ExtractPDFWatermark(rs io.ReadSeeker) (string, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	if properties, err := api.Properties(rs, conf); err == nil {
+		if code := traceCodePattern.FindString(properties[WatermarkPropertyKey]); code != "" {
+			return code, nil
+		}
+	}
+
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	ctx, err := api.ReadContext(rs, conf)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range ctx.XRefTable.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok {
+			continue
+		}
+		if err := sd.Decode(); err != nil {
+			continue
+		}
+		if code := traceCodePattern.Find(sd.Content); code != nil {
+			return string(code), nil
+		}
+	}
+
+	return "", ErrWatermarkNotFound
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceCode(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	code, err := NewTraceCode(42, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, ts, ok := ParseTraceCode(code)
+	if !ok || userID != 42 || !ts.Equal(at) {
+		t.Errorf("ParseTraceCode(%q) = %d, %v, %v", code, userID, ts, ok)
+	}
+	if other, _ := NewTraceCode(42, at); other == code {
+		t.Errorf("两次生成的追踪码不应相同: %q", code)
+	}
+}
+
+func TestParseTraceCode(t *testing.T) {
+	tests := []struct {
+		code   string
+		userID uint
+		unix   int64
+		ok     bool
+	}{
+		{"RPWM-7-1700000000-0a1b2c3d", 7, 1700000000, true},
+		{"匿名分享 RPWM-0-1700000000-deadbeef 附带文字", 0, 1700000000, true},
+		{"", 0, 0, false},
+		{"RPWM-7-1700000000", 0, 0, false},
+		{"RPWM-x-1700000000-0a1b2c3d", 0, 0, false},
+		{"RPWM-7-1700000000-0A1B2C3D", 0, 0, false},
+	}
+	for _, tt := range tests {
+		userID, ts, ok := ParseTraceCode(tt.code)
+		if ok != tt.ok || userID != tt.userID || (ok && ts.Unix() != tt.unix) {
+			t.Errorf("ParseTraceCode(%q) = %d, %v, %v, want %d, %d, %v", tt.code, userID, ts.Unix(), ok, tt.userID, tt.unix, tt.ok)
+		}
+	}
+}
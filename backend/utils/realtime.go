@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 实时推送的事件类型
+const (
+	EventReplyCreated = "reply.created"
+	EventReplyUpdated = "reply.updated"
+	EventReplyDeleted = "reply.deleted"
+	EventReplyVotes   = "reply.votes"
+	EventTopicVotes   = "topic.votes"
+	EventPollResults  = "poll.results"
+	EventNotification = "notification"
+	EventChatMessage  = "chat.message"
+)
+
+// realtimeChannelPrefix Redis发布订阅频道的前缀
+const realtimeChannelPrefix = "realtime:"
+
+// subscriberBuffer 每个连接缓存的待发送事件数，超出时丢弃新事件，避免慢连接拖慢推送
+const subscriberBuffer = 64
+
+// TopicChannel 主题频道，推送新回复和投票变化
+func TopicChannel(topicID uint) string {
+	return fmt.Sprintf("topic:%d", topicID)
+}
+
+// UserChannel 用户的私有频道，推送通知
+func UserChannel(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// ChatChannel AI对话会话频道，推送新消息
+func ChatChannel(sessionID uint) string {
+	return fmt.Sprintf("chat:%d", sessionID)
+}
+
+// RealtimeEvent 推送给客户端的事件
+type RealtimeEvent struct {
+	Type    string      `json:"type"`
+	Channel string      `json:"channel"`
+	Data    interface{} `json:"data"`
+}
+
+// RealtimeHub 实时推送中心
+// 事件统一发布到Redis，每个后端实例订阅Redis后再分发给本实例上的连接，
+// 因此多实例部署时连接在哪个实例上都能收到事件
+type RealtimeHub struct {
+	Redis *redis.Client
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*RealtimeSubscriber]struct{}
+}
+
+// RealtimeSubscriber 一个客户端连接的订阅
+type RealtimeSubscriber struct {
+	Events chan []byte
+
+	hub      *RealtimeHub
+	mu       sync.Mutex
+	channels map[string]bool
+	closed   bool
+}
+
+// NewRealtimeHub 创建推送中心并开始监听Redis
+func NewRealtimeHub(redisClient *redis.Client) *RealtimeHub {
+	h := &RealtimeHub{
+		Redis:       redisClient,
+		subscribers: make(map[string]map[*RealtimeSubscriber]struct{}),
+	}
+	if redisClient != nil {
+		go h.listen()
+	}
+	return h
+}
+
+// listen 订阅Redis中的所有推送频道，断线后由go-redis自动重连
+func (h *RealtimeHub) listen() {
+	pubsub := h.Redis.PSubscribe(context.Background(), realtimeChannelPrefix+"*")
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		h.dispatch(strings.TrimPrefix(msg.Channel, realtimeChannelPrefix), []byte(msg.Payload))
+	}
+}
+
+// Publish 向频道发布事件
+func (h *RealtimeHub) Publish(channel, eventType string, data interface{}) {
+	payload, err := json.Marshal(RealtimeEvent{Type: eventType, Channel: channel, Data: data})
+	if err != nil {
+		log.Printf("推送事件序列化失败: %v", err)
+		return
+	}
+
+	// 没有Redis时只在本实例内分发
+	if h.Redis == nil {
+		h.dispatch(channel, payload)
+		return
+	}
+
+	if err := h.Redis.Publish(context.Background(), realtimeChannelPrefix+channel, payload).Err(); err != nil {
+		log.Printf("推送事件发布失败: %v", err)
+	}
+}
+
+// dispatch 将事件分发给本实例上订阅了该频道的连接
+func (h *RealtimeHub) dispatch(channel string, payload []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for subscriber := range h.subscribers[channel] {
+		select {
+		case subscriber.Events <- payload:
+		default:
+			log.Printf("推送连接缓冲已满，丢弃频道 %s 的事件", channel)
+		}
+	}
+}
+
+// NewSubscriber 为一个客户端连接创建订阅
+func (h *RealtimeHub) NewSubscriber() *RealtimeSubscriber {
+	return &RealtimeSubscriber{
+		Events:   make(chan []byte, subscriberBuffer),
+		hub:      h,
+		channels: make(map[string]bool),
+	}
+}
+
+// Subscribe 订阅频道，频道权限由调用方校验
+func (s *RealtimeSubscriber) Subscribe(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.channels[channel] {
+		return
+	}
+	s.channels[channel] = true
+
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if s.hub.subscribers[channel] == nil {
+		s.hub.subscribers[channel] = make(map[*RealtimeSubscriber]struct{})
+	}
+	s.hub.subscribers[channel][s] = struct{}{}
+}
+
+// Unsubscribe 取消订阅频道
+func (s *RealtimeSubscriber) Unsubscribe(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.channels[channel] {
+		return
+	}
+	delete(s.channels, channel)
+	s.hub.remove(channel, s)
+}
+
+// Channels 当前订阅的频道
+func (s *RealtimeSubscriber) Channels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := make([]string, 0, len(s.channels))
+	for channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// Close 取消全部订阅，连接断开时调用
+func (s *RealtimeSubscriber) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for channel := range s.channels {
+		s.hub.remove(channel, s)
+	}
+	s.channels = nil
+}
+
+// remove 从频道中移除连接，频道没有连接时一并删除
+func (h *RealtimeHub) remove(channel string, subscriber *RealtimeSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[channel], subscriber)
+	if len(h.subscribers[channel]) == 0 {
+		delete(h.subscribers, channel)
+	}
+}
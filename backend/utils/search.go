@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// plainTextPolicy 去掉所有HTML标签，用于从渲染后的内容中提取纯文本
+var plainTextPolicy = bluemonday.StrictPolicy()
+
+// PlainText 提取渲染后HTML中的纯文本，连续的空白合并为一个空格
+func PlainText(content string) string {
+	// 在结束标签前补空格，避免相邻段落的文字连在一起
+	text := plainTextPolicy.Sanitize(strings.ReplaceAll(content, "</", " </"))
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// SearchTerms 将搜索关键词按空白拆分为高亮用的词，去重并按长度从长到短排列
+func SearchTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		key := strings.ToLower(term)
+		if !seen[key] {
+			seen[key] = true
+			terms = append(terms, term)
+		}
+	}
+
+	// 较长的词优先匹配，避免只高亮了其中一部分
+	sort.SliceStable(terms, func(i, j int) bool {
+		return len([]rune(terms[i])) > len([]rune(terms[j]))
+	})
+	return terms
+}
+
+// HighlightSnippet 截取文本中第一个匹配附近的片段，并用<em>标记所有匹配的词
+// maxRunes为片段的最大字符数，小于等于0时不截取；返回的片段已经过HTML转义
+func HighlightSnippet(text string, terms []string, maxRunes int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	patterns := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		pattern := []rune(strings.ToLower(term))
+		if len(pattern) > 0 {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	// matchAt 返回位置i处匹配的词的长度，没有匹配时为0
+	matchAt := func(i int) int {
+		for _, pattern := range patterns {
+			if i+len(pattern) <= len(lower) && string(lower[i:i+len(pattern)]) == string(pattern) {
+				return len(pattern)
+			}
+		}
+		return 0
+	}
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		first := 0
+		for i := range lower {
+			if matchAt(i) > 0 {
+				first = i
+				break
+			}
+		}
+
+		// 匹配位置前保留四分之一的上下文
+		start = first - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxRunes
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		n := matchAt(i)
+		if n == 0 {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		if i+n > end {
+			n = end - i
+		}
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(string(runes[i : i+n])))
+		b.WriteString("</em>")
+		i += n
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", ""},
+		{"<p>你好</p>", "你好"},
+		{"<p>第一段</p><p>第二段</p>", "第一段 第二段"},
+		{"<p>a &amp; b &lt;c&gt;</p>", "a & b <c>"},
+		{"<script>alert(1)</script>正文", "正文"},
+		{"<ul>\n<li>一</li>\n<li>二</li>\n</ul>", "一 二"},
+	}
+	for _, tt := range tests {
+		if got := PlainText(tt.content); got != tt.want {
+			t.Errorf("PlainText(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"go", []string{"go"}},
+		{"go golang", []string{"golang", "go"}},
+		{"Go go GO", []string{"Go"}},
+		{"数据 数据库", []string{"数据库", "数据"}},
+	}
+	for _, tt := range tests {
+		if got := SearchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		maxRunes int
+		want     string
+	}{
+		{"没有关键词", "hello", nil, 0, "hello"},
+		{"忽略大小写并保留原文", "Hello World", []string{"world"}, 0, "Hello <em>World</em>"},
+		{"所有匹配", "go to go", []string{"go"}, 0, "<em>go</em> to <em>go</em>"},
+		{"长词优先", "golang", SearchTerms("go golang"), 0, "<em>golang</em>"},
+		{"转义HTML", "<b>go</b>", []string{"go"}, 0, "&lt;b&gt;<em>go</em>&lt;/b&gt;"},
+		{"关键词本身转义", "a<b", []string{"<"}, 0, "a<em>&lt;</em>b"},
+		{"不超过长度不截取", "abcdef", []string{"d"}, 6, "abc<em>d</em>ef"},
+		{"截取匹配附近", "0123456789abcdefghij", []string{"k"}, 100, "0123456789abcdefghij"},
+		{"匹配前保留四分之一", "0123456789abcdefghij", []string{"a"}, 8, "…89<em>a</em>bcdef…"},
+		{"靠近末尾", "0123456789abcdefghij", []string{"j"}, 8, "…cdefghi<em>j</em>"},
+		{"靠近开头", "0123456789abcdefghij", []string{"1"}, 8, "0<em>1</em>234567…"},
+		{"没有匹配时取开头", "0123456789abcdefghij", []string{"z"}, 8, "01234567…"},
+		{"匹配被截断", "0123456789abc", []string{"89ab"}, 4, "…7<em>89a</em>…"},
+		{"中文", "今天天气很好，适合出去玩", []string{"天气"}, 0, "今天<em>天气</em>很好，适合出去玩"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HighlightSnippet(tt.text, tt.terms, tt.maxRunes); got != tt.want {
+				t.Errorf("HighlightSnippet(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
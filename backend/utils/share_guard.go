@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// shareFailKeyPrefix 提取密码错误次数，share_fail:link:<分享链接ID> 或 share_fail:ip:<IP>
+	shareFailKeyPrefix = "share_fail:"
+	// shareLockKeyPrefix 因密码错误次数过多被锁定的分享链接或IP，键的有效期即剩余锁定时间
+	shareLockKeyPrefix = "share_lock:"
+)
+
+// ShareGuard 防止暴力破解分享链接的提取密码
+// 分别按分享链接和IP统计一段时间内的密码错误次数，达到上限后锁定，锁定期间不再校验密码
+type ShareGuard struct {
+	Redis     *redis.Client
+	LinkLimit int           // SHARE_PASSWORD_LINK_LIMIT，同一分享链接允许的错误次数，防止多个IP分散尝试
+	IPLimit   int           // SHARE_PASSWORD_IP_LIMIT，同一IP对所有分享链接允许的错误次数
+	Window    time.Duration // SHARE_PASSWORD_WINDOW_MINUTES，错误次数的统计窗口
+	Lockout   time.Duration // SHARE_PASSWORD_LOCK_MINUTES，达到上限后的锁定时间
+}
+
+// NewShareGuard 创建提取密码的防暴力破解检查，设置从环境变量读取
+func NewShareGuard(redisClient *redis.Client) *ShareGuard {
+	return &ShareGuard{
+		Redis:     redisClient,
+		LinkLimit: envInt("SHARE_PASSWORD_LINK_LIMIT", 20),
+		IPLimit:   envInt("SHARE_PASSWORD_IP_LIMIT", 5),
+		Window:    time.Duration(envInt("SHARE_PASSWORD_WINDOW_MINUTES", 15)) * time.Minute,
+		Lockout:   time.Duration(envInt("SHARE_PASSWORD_LOCK_MINUTES", 30)) * time.Minute,
+	}
+}
+
+// shareGuardKeys 分享链接和IP对应的计数键名，不含前缀
+func shareGuardKeys(linkID uint, ip string) []string {
+	return []string{"link:" + strconv.FormatUint(uint64(linkID), 10), "ip:" + ip}
+}
+
+// Locked 分享链接或IP剩余的锁定时间，未锁定时为0
+func (g *ShareGuard) Locked(ctx context.Context, linkID uint, ip string) time.Duration {
+	var wait time.Duration
+	for _, key := range shareGuardKeys(linkID, ip) {
+		ttl, err := g.Redis.PTTL(ctx, shareLockKeyPrefix+key).Result()
+		if err == nil && ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait
+}
+
+// Fail 记录一次密码错误，分享链接或IP的错误次数达到上限时锁定并返回锁定时间，否则返回0
+func (g *ShareGuard) Fail(ctx context.Context, linkID uint, ip string) time.Duration {
+	var locked time.Duration
+	for i, key := range shareGuardKeys(linkID, ip) {
+		limit := g.LinkLimit
+		if i == 1 {
+			limit = g.IPLimit
+		}
+		if limit <= 0 {
+			continue
+		}
+
+		pipe := g.Redis.TxPipeline()
+		failures := pipe.Incr(ctx, shareFailKeyPrefix+key)
+		pipe.Expire(ctx, shareFailKeyPrefix+key, g.Window)
+		if _, err := pipe.Exec(ctx); err != nil || failures.Val() < int64(limit) {
+			continue
+		}
+
+		pipe = g.Redis.TxPipeline()
+		pipe.Set(ctx, shareLockKeyPrefix+key, failures.Val(), g.Lockout)
+		pipe.Del(ctx, shareFailKeyPrefix+key)
+		if _, err := pipe.Exec(ctx); err == nil {
+			locked = g.Lockout
+		}
+	}
+	return locked
+}
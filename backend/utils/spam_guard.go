@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"context"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"g/front/backend/config"
+)
+
+// 受发帖频率限制的操作
+const (
+	SpamActionTopic    = "topic"
+	SpamActionReply    = "reply"
+	SpamActionComment  = "comment"
+	SpamActionResource = "resource"
+)
+
+// 用户的信誉等级，决定发帖频率上限
+const (
+	SpamTierNew     = 0 // 注册时间较短的新用户
+	SpamTierNormal  = 1
+	SpamTierTrusted = 2 // 积分较高的老用户
+)
+
+const (
+	// spamRateKeyPrefix 发帖记录，spam_rate:<操作>:<用户ID>，有序集合的分值为发帖时间（毫秒）
+	spamRateKeyPrefix = "spam_rate:"
+	// spamRecentKeyPrefix 用户最近发布内容的指纹列表，用于检测重复内容
+	spamRecentKeyPrefix = "spam_recent:"
+	// spamStrikeKeyPrefix 违规次数，达到上限后进入冷却
+	spamStrikeKeyPrefix = "spam_strike:"
+	// spamCooldownKeyPrefix 冷却中的用户，键的有效期即剩余冷却时间
+	spamCooldownKeyPrefix = "spam_cooldown:"
+	// spamLevelKeyPrefix 冷却的次数，每次冷却时间翻倍
+	spamLevelKeyPrefix = "spam_level:"
+	// spamRateWindow 发帖频率的统计窗口
+	spamRateWindow = time.Hour
+	// spamStrikeWindow 违规次数的统计窗口
+	spamStrikeWindow = time.Hour
+	// spamLevelWindow 冷却次数的保留时间，期间没有再次冷却则恢复为初始冷却时间
+	spamLevelWindow = 7 * 24 * time.Hour
+	// maxSpamCooldown 最长冷却时间
+	maxSpamCooldown = 24 * time.Hour
+)
+
+// spamRateScript 检查发帖间隔和窗口内的发帖数，通过时记录本次发帖
+// KEYS[1] 发帖记录；ARGV 依次为当前时间、窗口、最小间隔（毫秒）、窗口内上限和本次发帖的成员名
+// 返回0表示通过，否则为需要等待的毫秒数
+var spamRateScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local last = redis.call('ZREVRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if last[2] and now - tonumber(last[2]) < tonumber(ARGV[3]) then
+	return tonumber(ARGV[3]) - (now - tonumber(last[2]))
+end
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[4]) then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return math.max(tonumber(oldest[2]) + window - now, 1)
+end
+redis.call('ZADD', KEYS[1], now, ARGV[5])
+redis.call('PEXPIRE', KEYS[1], window)
+return 0
+`)
+
+// SpamConfig 反垃圾设置，均可通过环境变量配置
+type SpamConfig struct {
+	NewAccountDays    int              // SPAM_NEW_ACCOUNT_DAYS，注册不满该天数的是新用户
+	TrustedPoints     int              // SPAM_TRUSTED_POINTS，积分达到该值的老用户是可信用户
+	Limits            map[string][]int // SPAM_LIMIT_<操作>，新用户、普通用户、可信用户每小时的发帖上限，逗号分隔
+	MinInterval       time.Duration    // SPAM_MIN_INTERVAL_SECONDS，同一操作两次之间的最小间隔
+	DuplicateDistance int              // SPAM_DUPLICATE_DISTANCE，指纹的汉明距离不超过该值视为重复内容，小于0时不检查
+	DuplicateRecent   int              // SPAM_DUPLICATE_RECENT，与最近多少条内容比较
+	DuplicateWindow   time.Duration    // SPAM_DUPLICATE_WINDOW_HOURS，与多长时间内的内容比较
+	StrikeLimit       int              // SPAM_STRIKE_LIMIT，一小时内违规达到该次数后进入冷却
+	Cooldown          time.Duration    // SPAM_COOLDOWN_MINUTES，第一次冷却的时间，之后每次翻倍
+}
+
+// envInt 读取整数类型的环境变量，格式错误时使用默认值
+func envInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(config.GetEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// envLimits 读取三个等级的发帖上限
+func envLimits(key string, defaults []int) []int {
+	parts := strings.Split(config.GetEnv(key, ""), ",")
+	if len(parts) != len(defaults) {
+		return defaults
+	}
+	limits := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return defaults
+		}
+		limits[i] = n
+	}
+	return limits
+}
+
+// LoadSpamConfig 从环境变量读取反垃圾设置
+func LoadSpamConfig() SpamConfig {
+	return SpamConfig{
+		NewAccountDays: envInt("SPAM_NEW_ACCOUNT_DAYS", 3),
+		TrustedPoints:  envInt("SPAM_TRUSTED_POINTS", 500),
+		Limits: map[string][]int{
+			SpamActionTopic:    envLimits("SPAM_LIMIT_TOPIC", []int{3, 10, 30}),
+			SpamActionReply:    envLimits("SPAM_LIMIT_REPLY", []int{10, 60, 200}),
+			SpamActionComment:  envLimits("SPAM_LIMIT_COMMENT", []int{10, 60, 200}),
+			SpamActionResource: envLimits("SPAM_LIMIT_RESOURCE", []int{2, 10, 30}),
+		},
+		MinInterval:       time.Duration(envInt("SPAM_MIN_INTERVAL_SECONDS", 10)) * time.Second,
+		DuplicateDistance: envInt("SPAM_DUPLICATE_DISTANCE", 3),
+		DuplicateRecent:   envInt("SPAM_DUPLICATE_RECENT", 20),
+		DuplicateWindow:   time.Duration(envInt("SPAM_DUPLICATE_WINDOW_HOURS", 24)) * time.Hour,
+		StrikeLimit:       envInt("SPAM_STRIKE_LIMIT", 3),
+		Cooldown:          time.Duration(envInt("SPAM_COOLDOWN_MINUTES", 30)) * time.Minute,
+	}
+}
+
+// SpamGuard 发帖反垃圾：按信誉等级限制发帖频率，检测与最近发布内容重复的内容，多次违规后进入冷却
+// 计数保存在Redis中，多个实例共享
+type SpamGuard struct {
+	Redis  *redis.Client
+	Config SpamConfig
+}
+
+// NewSpamGuard 创建发帖反垃圾检查
+func NewSpamGuard(redisClient *redis.Client) *SpamGuard {
+	return &SpamGuard{Redis: redisClient, Config: LoadSpamConfig()}
+}
+
+func spamUserKey(prefix string, userID uint) string {
+	return prefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+// Tier 根据注册时间和积分计算用户的信誉等级
+func (g *SpamGuard) Tier(createdAt time.Time, points int) int {
+	if time.Since(createdAt) < time.Duration(g.Config.NewAccountDays)*24*time.Hour {
+		return SpamTierNew
+	}
+	if points >= g.Config.TrustedPoints {
+		return SpamTierTrusted
+	}
+	return SpamTierNormal
+}
+
+// Cooldown 用户剩余的冷却时间，不在冷却中时为0
+func (g *SpamGuard) Cooldown(ctx context.Context, userID uint) time.Duration {
+	ttl, err := g.Redis.PTTL(ctx, spamUserKey(spamCooldownKeyPrefix, userID)).Result()
+	if err != nil || ttl <= 0 {
+		return 0
+	}
+	return ttl
+}
+
+// Allow 检查发帖频率，通过时计入本次发帖，否则返回需要等待的时间
+func (g *SpamGuard) Allow(ctx context.Context, userID uint, action string, tier int) (time.Duration, error) {
+	limits, ok := g.Config.Limits[action]
+	if !ok || tier < 0 || tier >= len(limits) {
+		return 0, nil
+	}
+
+	now := time.Now()
+	key := spamRateKeyPrefix + action + ":" + strconv.FormatUint(uint64(userID), 10)
+	wait, err := spamRateScript.Run(ctx, g.Redis, []string{key},
+		now.UnixMilli(), spamRateWindow.Milliseconds(), g.Config.MinInterval.Milliseconds(),
+		limits[tier], strconv.FormatInt(now.UnixNano(), 36)).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// IsDuplicate 内容是否与用户最近发布的内容重复或高度相似，指纹为0（没有有效文字）时不检查
+func (g *SpamGuard) IsDuplicate(ctx context.Context, userID uint, fingerprint uint64) (bool, error) {
+	if g.Config.DuplicateDistance < 0 || fingerprint == 0 {
+		return false, nil
+	}
+
+	recent, err := g.Redis.LRange(ctx, spamUserKey(spamRecentKeyPrefix, userID), 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, item := range recent {
+		previous, err := strconv.ParseUint(item, 16, 64)
+		if err != nil {
+			continue
+		}
+		if bits.OnesCount64(previous^fingerprint) <= g.Config.DuplicateDistance {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Remember 记录用户发布的内容的指纹，内容保存成功后调用
+func (g *SpamGuard) Remember(ctx context.Context, userID uint, fingerprint uint64) {
+	if fingerprint == 0 {
+		return
+	}
+	key := spamUserKey(spamRecentKeyPrefix, userID)
+	pipe := g.Redis.TxPipeline()
+	pipe.LPush(ctx, key, strconv.FormatUint(fingerprint, 16))
+	pipe.LTrim(ctx, key, 0, int64(g.Config.DuplicateRecent-1))
+	pipe.Expire(ctx, key, g.Config.DuplicateWindow)
+	pipe.Exec(ctx)
+}
+
+// Strike 记录一次违规，违规次数达到上限时进入冷却并返回冷却时间，否则返回0
+// 每次冷却的时间是上一次的两倍，最长24小时
+func (g *SpamGuard) Strike(ctx context.Context, userID uint) time.Duration {
+	key := spamUserKey(spamStrikeKeyPrefix, userID)
+	pipe := g.Redis.TxPipeline()
+	strikes := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, spamStrikeWindow)
+	if _, err := pipe.Exec(ctx); err != nil || strikes.Val() < int64(g.Config.StrikeLimit) {
+		return 0
+	}
+
+	levelKey := spamUserKey(spamLevelKeyPrefix, userID)
+	pipe = g.Redis.TxPipeline()
+	level := pipe.Incr(ctx, levelKey)
+	pipe.Expire(ctx, levelKey, spamLevelWindow)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0
+	}
+
+	cooldown := g.Config.Cooldown
+	for i := int64(1); i < level.Val() && cooldown < maxSpamCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > maxSpamCooldown {
+		cooldown = maxSpamCooldown
+	}
+	g.Redis.Set(ctx, spamUserKey(spamCooldownKeyPrefix, userID), level.Val(), cooldown)
+	return cooldown
+}
+
+// Strikes 用户当前的违规次数和累计冷却次数
+func (g *SpamGuard) Strikes(ctx context.Context, userID uint) (int, int) {
+	strikes, _ := g.Redis.Get(ctx, spamUserKey(spamStrikeKeyPrefix, userID)).Int()
+	level, _ := g.Redis.Get(ctx, spamUserKey(spamLevelKeyPrefix, userID)).Int()
+	return strikes, level
+}
+
+// Reset 清除用户的违规记录和冷却，由管理员解除限制时调用
+func (g *SpamGuard) Reset(ctx context.Context, userID uint) error {
+	return g.Redis.Del(ctx,
+		spamUserKey(spamStrikeKeyPrefix, userID),
+		spamUserKey(spamLevelKeyPrefix, userID),
+		spamUserKey(spamCooldownKeyPrefix, userID),
+	).Err()
+}
+
+// ContentFingerprint 计算文本的SimHash指纹，相似的文本指纹的汉明距离较小
+// 忽略大小写、全角半角、空格和符号，以相邻两个字符为特征，只有一个字符时以该字符为特征
+func ContentFingerprint(text string) uint64 {
+	var runes []rune
+	for _, r := range text {
+		if !isNoiseRune(r) {
+			runes = append(runes, normalizeRune(r))
+		}
+	}
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(runes) == 1 {
+		add(string(runes))
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		add(string(runes[i : i+2]))
+	}
+
+	var fingerprint uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
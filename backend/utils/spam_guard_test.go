@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"math/bits"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSpamGuardTier(t *testing.T) {
+	g := &SpamGuard{Config: SpamConfig{NewAccountDays: 3, TrustedPoints: 500}}
+	now := time.Now()
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		points    int
+		want      int
+	}{
+		{"刚注册", now, 0, SpamTierNew},
+		{"新用户积分再高也是新用户", now.Add(-48 * time.Hour), 10000, SpamTierNew},
+		{"超过注册天数", now.Add(-4 * 24 * time.Hour), 0, SpamTierNormal},
+		{"积分未达到", now.Add(-30 * 24 * time.Hour), 499, SpamTierNormal},
+		{"积分刚好达到", now.Add(-30 * 24 * time.Hour), 500, SpamTierTrusted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.Tier(tt.createdAt, tt.points); got != tt.want {
+				t.Errorf("Tier() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// 不区分新用户时所有人至少是普通用户
+	g.Config.NewAccountDays = 0
+	if got := g.Tier(now, 0); got != SpamTierNormal {
+		t.Errorf("NewAccountDays为0时 Tier() = %d, want %d", got, SpamTierNormal)
+	}
+}
+
+func TestEnvLimits(t *testing.T) {
+	defaults := []int{3, 10, 30}
+	tests := []struct {
+		name  string
+		value string
+		want  []int
+	}{
+		{"未设置", "", defaults},
+		{"正常", "1,2,3", []int{1, 2, 3}},
+		{"允许空格", " 5, 6 ,7 ", []int{5, 6, 7}},
+		{"允许0", "0,0,0", []int{0, 0, 0}},
+		{"数量不足", "1,2", defaults},
+		{"数量过多", "1,2,3,4", defaults},
+		{"不是数字", "1,x,3", defaults},
+		{"负数", "1,-2,3", defaults},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SPAM_LIMIT_TEST", tt.value)
+			if got := envLimits("SPAM_LIMIT_TEST", defaults); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("envLimits(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentFingerprint(t *testing.T) {
+	base := "出售二手笔记本电脑，九成新，电池续航五小时，配件齐全，附赠原装充电器和电脑包，价格面议，有意者请私信联系，非诚勿扰，谢谢大家"
+	tests := []struct {
+		name        string
+		a, b        string
+		maxDistance int // 相似内容的汉明距离上限
+		minDistance int // 不相似内容的汉明距离下限
+	}{
+		{"相同内容", base, base, 0, 0},
+		{"只有符号和空格不同", "出售 二手笔记本电脑 九成新！！", "出售二手笔记本电脑，九成新。", 0, 0},
+		{"大小写和全角", "buy now", "Buy ＮＯＷ", 0, 0},
+		{"改动一个字", base, "出售二手笔记本电脑，八成新，电池续航五小时，配件齐全，附赠原装充电器和电脑包，价格面议，有意者请私信联系，非诚勿扰，谢谢大家", 8, 0},
+		{"完全不同", base, "今天讨论一下Go语言的并发模型和调度器的实现细节，欢迎大家在评论区补充自己的看法和经验", 64, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := bits.OnesCount64(ContentFingerprint(tt.a) ^ ContentFingerprint(tt.b))
+			if distance > tt.maxDistance || distance < tt.minDistance {
+				t.Errorf("汉明距离 = %d, want [%d, %d]", distance, tt.minDistance, tt.maxDistance)
+			}
+		})
+	}
+
+	if got := ContentFingerprint(" ，。！"); got != 0 {
+		t.Errorf("只有符号时指纹应为0，got %x", got)
+	}
+	if ContentFingerprint("a") == 0 {
+		t.Errorf("单个字符也应有指纹")
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	_ "image/png" // 注册PNG解码器
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册WebP解码器
+)
+
+// MaxImagePixels 允许处理的图片的最大像素数，避免解码超大图片占用过多内存
+const MaxImagePixels = 40000000
+
+// ImageConfig 读取图片的格式和尺寸，不解码像素；不是支持的图片格式时返回错误
+func ImageConfig(data []byte) (string, int, int, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return format, config.Width, config.Height, nil
+}
+
+// MakeThumbnail 生成长边不超过maxSide的JPEG缩略图，透明部分填充为白色
+// 图片本身不超过该尺寸时返回nil，直接使用原图
+func MakeThumbnail(data []byte, maxSide int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return nil, nil
+	}
+
+	if width >= height {
+		height = height * maxSide / width
+		width = maxSide
+	} else {
+		width = width * maxSide / height
+		height = maxSide
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
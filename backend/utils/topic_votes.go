@@ -0,0 +1,417 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"g/front/backend/models"
+)
+
+const (
+	// topicVoteKeyPrefix 主题投票缓存的键前缀，每个主题一个哈希：
+	// likes、dislikes 为点赞点踩数，u:<用户ID> 为该用户的投票
+	topicVoteKeyPrefix = "topic_votes:"
+	// topicVoteDirtyKey 投票有变化、等待同步到主题表的主题ID集合
+	topicVoteDirtyKey = "topic_votes_dirty"
+	// topicVoteTTL 主题投票缓存的有效期，过期后下次访问时从MySQL重新加载
+	topicVoteTTL = 7 * 24 * time.Hour
+	// topicVoteSyncBatch 每批同步的主题数
+	topicVoteSyncBatch = 100
+)
+
+// 投票操作
+const (
+	TopicVoteSet    = "set"    // 设置为指定的投票
+	TopicVoteToggle = "toggle" // 与当前投票相同时取消，否则设置
+	TopicVoteClear  = "clear"  // 当前投票与指定的投票相同时取消
+)
+
+// topicVoteScript 原子地修改用户对主题的投票并更新计数
+// KEYS[1] 主题投票哈希，KEYS[2] 待同步集合；ARGV 依次为用户ID、投票值、操作、主题ID、有效期（秒）
+// 缓存不存在时返回 {-2}，调用方需要先从MySQL加载；否则返回 {原投票, 新投票, 点赞数, 点踩数}
+var topicVoteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return {-2}
+end
+
+local field = 'u:' .. ARGV[1]
+local previous = tonumber(redis.call('HGET', KEYS[1], field) or '0')
+local value = tonumber(ARGV[2])
+local op = ARGV[3]
+
+if op == 'toggle' and previous == value then
+	value = 0
+elseif op == 'clear' then
+	if previous == value then
+		value = 0
+	else
+		value = previous
+	end
+end
+
+if previous ~= value then
+	if previous == 1 then
+		redis.call('HINCRBY', KEYS[1], 'likes', -1)
+	elseif previous == -1 then
+		redis.call('HINCRBY', KEYS[1], 'dislikes', -1)
+	end
+	if value == 1 then
+		redis.call('HINCRBY', KEYS[1], 'likes', 1)
+	elseif value == -1 then
+		redis.call('HINCRBY', KEYS[1], 'dislikes', 1)
+	end
+
+	if value == 0 then
+		redis.call('HDEL', KEYS[1], field)
+	else
+		redis.call('HSET', KEYS[1], field, value)
+	end
+	redis.call('SADD', KEYS[2], ARGV[4])
+end
+
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+local counts = redis.call('HMGET', KEYS[1], 'likes', 'dislikes')
+return {previous, value, tonumber(counts[1] or '0'), tonumber(counts[2] or '0')}
+`)
+
+// topicVoteLoadScript 缓存不存在时写入从MySQL加载的投票，已存在时不覆盖，避免丢失并发的投票
+// KEYS[1] 主题投票哈希；ARGV[1] 有效期（秒），其后为字段和值
+var topicVoteLoadScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+for i = 2, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+return 1
+`)
+
+// TopicVoteCounts 主题的点赞点踩数
+type TopicVoteCounts struct {
+	Likes    int64 `json:"likes"`
+	Dislikes int64 `json:"dislikes"`
+}
+
+// TopicVoteResult 一次投票操作的结果
+type TopicVoteResult struct {
+	Previous int // 操作前的投票
+	Current  int // 操作后的投票
+	TopicVoteCounts
+}
+
+// TopicVoteStore 主题投票存储
+// MySQL的topic_votes表是持久化数据，Redis缓存每个主题的计数和用户投票，
+// 投票通过Lua脚本在Redis中原子完成后立即写入MySQL，主题表上的计数由后台按变化的主题批量同步
+type TopicVoteStore struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+}
+
+// NewTopicVoteStore 创建主题投票存储
+func NewTopicVoteStore(db *gorm.DB, redisClient *redis.Client) *TopicVoteStore {
+	return &TopicVoteStore{DB: db, Redis: redisClient}
+}
+
+func topicVoteKey(topicID uint) string {
+	return topicVoteKeyPrefix + strconv.FormatUint(uint64(topicID), 10)
+}
+
+// Vote 修改用户对主题的投票，value为1点赞、-1点踩、0取消，op为投票操作
+func (s *TopicVoteStore) Vote(ctx context.Context, topicID, userID uint, value int, op string) (*TopicVoteResult, error) {
+	result, err := s.runVote(ctx, topicID, userID, value, op)
+	if err != nil {
+		return nil, err
+	}
+	if result.Previous == result.Current {
+		return result, nil
+	}
+
+	if err := s.persist(topicID, userID, result.Current); err != nil {
+		// 写入MySQL失败时撤销Redis中的修改
+		if _, revertErr := s.runVote(ctx, topicID, userID, result.Previous, TopicVoteSet); revertErr != nil {
+			log.Printf("撤销主题%d的投票失败: %v", topicID, revertErr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// runVote 执行投票脚本，缓存不存在时先从MySQL加载
+func (s *TopicVoteStore) runVote(ctx context.Context, topicID, userID uint, value int, op string) (*TopicVoteResult, error) {
+	keys := []string{topicVoteKey(topicID), topicVoteDirtyKey}
+	args := []interface{}{userID, value, op, topicID, int(topicVoteTTL.Seconds())}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		values, err := topicVoteScript.Run(ctx, s.Redis, keys, args...).Int64Slice()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 4 {
+			return &TopicVoteResult{
+				Previous:        int(values[0]),
+				Current:         int(values[1]),
+				TopicVoteCounts: TopicVoteCounts{Likes: values[2], Dislikes: values[3]},
+			}, nil
+		}
+
+		if err := s.load(ctx, topicID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("主题%d的投票缓存加载失败", topicID)
+}
+
+// persist 将用户的投票写入MySQL
+func (s *TopicVoteStore) persist(topicID, userID uint, value int) error {
+	if value == 0 {
+		return s.DB.Where("topic_id = ? AND user_id = ?", topicID, userID).Delete(&models.TopicVote{}).Error
+	}
+
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "topic_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.TopicVote{TopicID: topicID, UserID: userID, Value: value}).Error
+}
+
+// load 从MySQL加载主题的投票到Redis，缓存已存在时不做修改
+// 旧版本只保存了计数的主题，主题表上的点赞点踩数多于topic_votes中的投票，计数以两者中较大的为准，
+// 避免第一次投票后按topic_votes重新计算的计数覆盖主题表上原有的计数
+func (s *TopicVoteStore) load(ctx context.Context, topicID uint) error {
+	var votes []models.TopicVote
+	if err := s.DB.Select("user_id", "value").Where("topic_id = ?", topicID).Find(&votes).Error; err != nil {
+		return err
+	}
+
+	var counts TopicVoteCounts
+	args := []interface{}{int(topicVoteTTL.Seconds())}
+	for _, vote := range votes {
+		if vote.Value > 0 {
+			counts.Likes++
+		} else {
+			counts.Dislikes++
+		}
+		args = append(args, "u:"+strconv.FormatUint(uint64(vote.UserID), 10), vote.Value)
+	}
+
+	var topic models.Topic
+	if err := s.DB.Unscoped().Select("id", "like_count", "dislike_count").Where("id = ?", topicID).
+		Limit(1).Find(&topic).Error; err != nil {
+		return err
+	}
+	if topic.LikeCount > counts.Likes {
+		counts.Likes = topic.LikeCount
+	}
+	if topic.DislikeCount > counts.Dislikes {
+		counts.Dislikes = topic.DislikeCount
+	}
+	args = append(args, "likes", counts.Likes, "dislikes", counts.Dislikes)
+
+	return topicVoteLoadScript.Run(ctx, s.Redis, []string{topicVoteKey(topicID)}, args...).Err()
+}
+
+// State 获取主题的点赞点踩数和用户的投票，userID为0时只返回计数
+func (s *TopicVoteStore) State(ctx context.Context, topicID, userID uint) (TopicVoteCounts, int, error) {
+	key := topicVoteKey(topicID)
+	fields := []string{"likes", "dislikes", "u:" + strconv.FormatUint(uint64(userID), 10)}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		values, err := s.Redis.HMGet(ctx, key, fields...).Result()
+		if err != nil {
+			return TopicVoteCounts{}, 0, err
+		}
+
+		// 计数字段不存在说明缓存已过期或被清空
+		if values[0] != nil {
+			counts := TopicVoteCounts{Likes: parseRedisInt(values[0]), Dislikes: parseRedisInt(values[1])}
+			return counts, int(parseRedisInt(values[2])), nil
+		}
+
+		if err := s.load(ctx, topicID); err != nil {
+			return TopicVoteCounts{}, 0, err
+		}
+	}
+	return TopicVoteCounts{}, 0, fmt.Errorf("主题%d的投票缓存加载失败", topicID)
+}
+
+// parseRedisInt 解析HMGET返回的整数，字段不存在时为0
+func parseRedisInt(value interface{}) int64 {
+	text, ok := value.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(text, 10, 64)
+	return n
+}
+
+// SyncDirty 将投票有变化的主题的点赞点踩数同步到主题表，返回同步的主题数
+func (s *TopicVoteStore) SyncDirty(ctx context.Context) int {
+	synced := 0
+	for {
+		ids, err := s.Redis.SPopN(ctx, topicVoteDirtyKey, topicVoteSyncBatch).Result()
+		if err != nil {
+			log.Printf("获取待同步的主题失败: %v", err)
+			return synced
+		}
+
+		for _, rawID := range ids {
+			topicID, err := strconv.ParseUint(rawID, 10, 64)
+			if err != nil {
+				continue
+			}
+			if err := s.syncTopic(ctx, uint(topicID)); err != nil {
+				log.Printf("同步主题%d的点赞数失败: %v", topicID, err)
+				// 放回集合，下次再同步
+				s.Redis.SAdd(ctx, topicVoteDirtyKey, rawID)
+				continue
+			}
+			synced++
+		}
+
+		if len(ids) < topicVoteSyncBatch {
+			return synced
+		}
+	}
+}
+
+// syncTopic 将单个主题的点赞点踩数写入主题表
+func (s *TopicVoteStore) syncTopic(ctx context.Context, topicID uint) error {
+	counts, _, err := s.State(ctx, topicID, 0)
+	if err != nil {
+		return err
+	}
+
+	// 点赞数由投票派生，不算对主题的修改，不更新updated_at
+	return s.DB.Model(&models.Topic{}).Where("id = ?", topicID).UpdateColumns(map[string]interface{}{
+		"like_count":    counts.Likes,
+		"dislike_count": counts.Dislikes,
+	}).Error
+}
+
+// Rebuild 以MySQL中的投票为准重建Redis缓存和主题表上的点赞点踩数，返回重建的主题数
+// 会先导入旧版本保存在 user_likes:* 和 user_dislikes:* 集合中的投票；
+// 主题表上没有对应用户投票的旧计数会保留，计数只会补足，不会减少
+func (s *TopicVoteStore) Rebuild(ctx context.Context) (int, error) {
+	if _, err := s.importLegacy(ctx); err != nil {
+		return 0, err
+	}
+
+	if err := s.deleteKeys(ctx, topicVoteKeyPrefix+"*"); err != nil {
+		return 0, err
+	}
+	if err := s.Redis.Del(ctx, topicVoteDirtyKey).Err(); err != nil {
+		return 0, err
+	}
+
+	var topicIDs []uint
+	if err := s.DB.Model(&models.TopicVote{}).Distinct("topic_id").Pluck("topic_id", &topicIDs).Error; err != nil {
+		return 0, err
+	}
+
+	for _, topicID := range topicIDs {
+		if err := s.load(ctx, topicID); err != nil {
+			return 0, err
+		}
+		if err := s.syncTopic(ctx, topicID); err != nil {
+			return 0, err
+		}
+	}
+	return len(topicIDs), nil
+}
+
+// ImportLegacy 导入旧版本保存在Redis中的投票，启动时调用，返回导入的投票数
+// 导入后清除相关主题的缓存并等待同步，避免按缓存中的计数覆盖主题表上旧的点赞点踩数
+func (s *TopicVoteStore) ImportLegacy(ctx context.Context) (int, error) {
+	imported, err := s.importLegacy(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	topics := make(map[uint]bool)
+	for _, vote := range imported {
+		topics[vote.TopicID] = true
+	}
+	for topicID := range topics {
+		if err := s.Redis.Del(ctx, topicVoteKey(topicID)).Err(); err != nil {
+			return 0, err
+		}
+		if err := s.Redis.SAdd(ctx, topicVoteDirtyKey, topicID).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return len(imported), nil
+}
+
+// importLegacy 将旧版本按用户保存的点赞点踩集合导入MySQL，导入后删除旧的键，返回导入的投票
+// 同一主题既点赞又点踩时以点赞为准
+func (s *TopicVoteStore) importLegacy(ctx context.Context) ([]models.TopicVote, error) {
+	var imported []models.TopicVote
+	for _, legacy := range []struct {
+		pattern string
+		value   int
+	}{
+		{"user_likes:*", 1},
+		{"user_dislikes:*", -1},
+	} {
+		iter := s.Redis.Scan(ctx, 0, legacy.pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+			userID, err := strconv.ParseUint(key[strings.LastIndex(key, ":")+1:], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			members, err := s.Redis.SMembers(ctx, key).Result()
+			if err != nil {
+				return nil, err
+			}
+
+			votes := make([]models.TopicVote, 0, len(members))
+			for _, member := range members {
+				topicID, err := strconv.ParseUint(member, 10, 64)
+				if err != nil {
+					continue
+				}
+				votes = append(votes, models.TopicVote{TopicID: uint(topicID), UserID: uint(userID), Value: legacy.value})
+			}
+
+			if len(votes) > 0 {
+				if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&votes).Error; err != nil {
+					return nil, err
+				}
+				imported = append(imported, votes...)
+			}
+			if err := s.Redis.Del(ctx, key).Err(); err != nil {
+				return nil, err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 旧版本的计数键已经没有用处
+	if err := s.deleteKeys(ctx, "topic_likes:*"); err != nil {
+		return nil, err
+	}
+	return imported, s.deleteKeys(ctx, "topic_dislikes:*")
+}
+
+// deleteKeys 删除匹配的所有键
+func (s *TopicVoteStore) deleteKeys(ctx context.Context, pattern string) error {
+	iter := s.Redis.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		if err := s.Redis.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
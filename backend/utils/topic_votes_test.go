@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestParseRedisInt(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int64
+	}{
+		{nil, 0},
+		{"", 0},
+		{"12", 12},
+		{"-3", -3},
+		{"abc", 0},
+		{int64(5), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRedisInt(tt.value); got != tt.want {
+			t.Errorf("parseRedisInt(%#v) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+// testRedis 连接TEST_REDIS_ADDR指定的Redis，未设置时跳过测试
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("未设置TEST_REDIS_ADDR，跳过需要Redis的测试")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("无法连接Redis %s: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestTopicVoteScripts(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	suffix, err := RandomToken(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "test_topic_votes:" + suffix
+	dirtyKey := "test_topic_votes_dirty:" + suffix
+	t.Cleanup(func() { client.Del(ctx, key, dirtyKey) })
+
+	vote := func(userID uint, value int, op string) []int64 {
+		t.Helper()
+		result, err := topicVoteScript.Run(ctx, client, []string{key, dirtyKey}, userID, value, op, 1, 60).Int64Slice()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// 缓存不存在时要求调用方先加载
+	if got := vote(1, 1, TopicVoteSet); !reflect.DeepEqual(got, []int64{-2}) {
+		t.Fatalf("未加载时 = %v, want [-2]", got)
+	}
+
+	// 加载已有的计数和投票，已存在时不覆盖
+	load := func(fields ...interface{}) int64 {
+		t.Helper()
+		n, err := topicVoteLoadScript.Run(ctx, client, []string{key}, append([]interface{}{60}, fields...)...).Int64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := load("likes", 10, "dislikes", 2, "u:2", 1); n != 1 {
+		t.Fatalf("第一次加载 = %d, want 1", n)
+	}
+	if n := load("likes", 0, "dislikes", 0); n != 0 {
+		t.Fatalf("重复加载 = %d, want 0", n)
+	}
+
+	// 每一步返回 {原投票, 新投票, 点赞数, 点踩数}
+	steps := []struct {
+		name   string
+		userID uint
+		value  int
+		op     string
+		want   []int64
+	}{
+		{"点赞", 1, 1, TopicVoteSet, []int64{0, 1, 11, 2}},
+		{"重复点赞不变", 1, 1, TopicVoteSet, []int64{1, 1, 11, 2}},
+		{"改为点踩", 1, -1, TopicVoteSet, []int64{1, -1, 10, 3}},
+		{"切换相同的投票即取消", 1, -1, TopicVoteToggle, []int64{-1, 0, 10, 2}},
+		{"切换为点赞", 1, 1, TopicVoteToggle, []int64{0, 1, 11, 2}},
+		{"取消不同的投票不生效", 1, -1, TopicVoteClear, []int64{1, 1, 11, 2}},
+		{"取消相同的投票", 1, 1, TopicVoteClear, []int64{1, 0, 10, 2}},
+		{"已加载的投票可以取消", 2, 1, TopicVoteClear, []int64{1, 0, 9, 2}},
+		{"直接设置为0", 3, -1, TopicVoteSet, []int64{0, -1, 9, 3}},
+		{"设置为0即取消", 3, 0, TopicVoteSet, []int64{-1, 0, 9, 2}},
+	}
+	for _, step := range steps {
+		if got := vote(step.userID, step.value, step.op); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: got %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	if n, _ := client.HLen(ctx, key).Result(); n != 2 {
+		t.Errorf("取消的投票应从哈希中删除，剩余字段数 = %d, want 2", n)
+	}
+	if ok, _ := client.SIsMember(ctx, dirtyKey, 1).Result(); !ok {
+		t.Errorf("投票有变化的主题应加入待同步集合")
+	}
+}
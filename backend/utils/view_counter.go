@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// viewSeenKeyPrefix 去重键前缀，view_seen:<类型>:<ID>:<访客>，在去重窗口内存在
+	viewSeenKeyPrefix = "view_seen:"
+	// viewPendingKeyPrefix 等待写入MySQL的浏览次数，每种类型一个哈希，字段为ID
+	viewPendingKeyPrefix = "view_pending:"
+	// viewUniqueKeyPrefix 每日独立访客的HyperLogLog，view_uv:<类型>:<ID>:<日期>
+	viewUniqueKeyPrefix = "view_uv:"
+	// ViewDedupWindow 同一访客在该时间内重复浏览只计一次
+	ViewDedupWindow = 30 * time.Minute
+	// ViewUniqueDays 每日独立访客保留的天数
+	ViewUniqueDays = 90
+	// viewFlushInterval 浏览次数写入MySQL的间隔
+	viewFlushInterval = time.Minute
+	// viewFlushBatch 每条UPDATE语句更新的记录数
+	viewFlushBatch = 200
+)
+
+// 统计浏览次数的内容类型，值为对应的表名
+const (
+	ViewTargetTopic    = "topics"
+	ViewTargetResource = "resources"
+)
+
+// viewRecordScript 记录一次浏览
+// KEYS[1] 去重键，KEYS[2] 待写入哈希，KEYS[3] 当天的独立访客；
+// ARGV 依次为内容ID、访客、去重窗口（秒）、独立访客的有效期（秒）
+// 返回1表示计入浏览次数，0表示窗口内的重复浏览
+var viewRecordScript = redis.NewScript(`
+redis.call('PFADD', KEYS[3], ARGV[2])
+redis.call('EXPIRE', KEYS[3], ARGV[4])
+if not redis.call('SET', KEYS[1], 1, 'NX', 'EX', ARGV[3]) then
+	return 0
+end
+redis.call('HINCRBY', KEYS[2], ARGV[1], 1)
+return 1
+`)
+
+// crawlerPattern 常见爬虫和自动化工具的User-Agent
+var crawlerPattern = regexp.MustCompile(`(?i)bot|spider|crawl|slurp|curl|wget|python-requests|headless`)
+
+// IsCrawler 判断请求是否来自爬虫，爬虫的访问不计入浏览次数
+func IsCrawler(userAgent string) bool {
+	return userAgent == "" || crawlerPattern.MatchString(userAgent)
+}
+
+// ViewVisitor 浏览去重使用的访客标识，登录用户按用户ID，未登录时按IP
+func ViewVisitor(userID uint, ip string) string {
+	if userID != 0 {
+		return "u:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "ip:" + ip
+}
+
+// DailyVisitors 某一天的独立访客估计数
+type DailyVisitors struct {
+	Date     string `json:"date"`
+	Visitors int64  `json:"visitors"`
+}
+
+// ViewCounter 浏览次数统计
+// 同一访客在去重窗口内的重复浏览只计一次，计数先累加在Redis中，由后台定时批量写入MySQL；
+// 每天的独立访客用HyperLogLog估计
+type ViewCounter struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+}
+
+// NewViewCounter 创建浏览次数统计并开始定时写入MySQL
+func NewViewCounter(db *gorm.DB, redisClient *redis.Client) *ViewCounter {
+	v := &ViewCounter{DB: db, Redis: redisClient}
+	if redisClient != nil {
+		go v.flushLoop()
+	}
+	return v
+}
+
+func viewTargetKey(targetType string, targetID uint) string {
+	return targetType + ":" + strconv.FormatUint(uint64(targetID), 10)
+}
+
+func viewUniqueKey(targetType string, targetID uint, day time.Time) string {
+	return viewUniqueKeyPrefix + viewTargetKey(targetType, targetID) + ":" + day.Format("20060102")
+}
+
+// Record 记录访客的一次浏览，返回是否计入浏览次数
+func (v *ViewCounter) Record(ctx context.Context, targetType string, targetID uint, visitor string) (bool, error) {
+	keys := []string{
+		viewSeenKeyPrefix + viewTargetKey(targetType, targetID) + ":" + visitor,
+		viewPendingKeyPrefix + targetType,
+		viewUniqueKey(targetType, targetID, time.Now()),
+	}
+	counted, err := viewRecordScript.Run(ctx, v.Redis, keys,
+		targetID, visitor, int(ViewDedupWindow.Seconds()), ViewUniqueDays*24*3600).Int()
+	if err != nil {
+		return false, err
+	}
+	return counted == 1, nil
+}
+
+// Pending 尚未写入MySQL的浏览次数，用于显示实时的浏览次数
+func (v *ViewCounter) Pending(ctx context.Context, targetType string, targetID uint) int {
+	n, err := v.Redis.HGet(ctx, viewPendingKeyPrefix+targetType, strconv.FormatUint(uint64(targetID), 10)).Int()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// UniqueVisitors 最近days天（含当天）每天的独立访客估计数，按日期从早到晚排列
+func (v *ViewCounter) UniqueVisitors(ctx context.Context, targetType string, targetID uint, days int) ([]DailyVisitors, error) {
+	now := time.Now()
+	pipe := v.Redis.Pipeline()
+	cmds := make([]*redis.IntCmd, days)
+	dates := make([]string, days)
+	for i := 0; i < days; i++ {
+		day := now.AddDate(0, 0, i-days+1)
+		dates[i] = day.Format("2006-01-02")
+		cmds[i] = pipe.PFCount(ctx, viewUniqueKey(targetType, targetID, day))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	result := make([]DailyVisitors, days)
+	for i, cmd := range cmds {
+		result[i] = DailyVisitors{Date: dates[i], Visitors: cmd.Val()}
+	}
+	return result, nil
+}
+
+// TotalUniqueVisitors 最近days天内去重后的独立访客估计数
+func (v *ViewCounter) TotalUniqueVisitors(ctx context.Context, targetType string, targetID uint, days int) (int64, error) {
+	now := time.Now()
+	keys := make([]string, days)
+	for i := 0; i < days; i++ {
+		keys[i] = viewUniqueKey(targetType, targetID, now.AddDate(0, 0, -i))
+	}
+	return v.Redis.PFCount(ctx, keys...).Result()
+}
+
+// flushLoop 定时将累加的浏览次数写入MySQL
+func (v *ViewCounter) flushLoop() {
+	ticker := time.NewTicker(viewFlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, targetType := range []string{ViewTargetTopic, ViewTargetResource} {
+			v.Flush(context.Background(), targetType)
+		}
+	}
+}
+
+// Flush 将某种类型累加的浏览次数写入MySQL，返回写入的记录数
+// 先将待写入哈希改名，多个实例同时执行时只有一个能取到数据，改名后新的浏览累加到新的哈希中
+func (v *ViewCounter) Flush(ctx context.Context, targetType string) int {
+	pendingKey := viewPendingKeyPrefix + targetType
+	flushingKey := fmt.Sprintf("%s:flushing:%d", pendingKey, time.Now().UnixNano())
+	if err := v.Redis.Rename(ctx, pendingKey, flushingKey).Err(); err != nil {
+		// 没有待写入的浏览次数
+		if !strings.Contains(err.Error(), "no such key") {
+			log.Printf("获取待写入的浏览次数失败: %v", err)
+		}
+		return 0
+	}
+
+	counts, err := v.Redis.HGetAll(ctx, flushingKey).Result()
+	if err != nil {
+		log.Printf("获取待写入的浏览次数失败: %v", err)
+		return 0
+	}
+
+	ids := make([]uint64, 0, len(counts))
+	for field := range counts {
+		if id, err := strconv.ParseUint(field, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	flushed := 0
+	for start := 0; start < len(ids); start += viewFlushBatch {
+		end := start + viewFlushBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+		if err := v.flushBatch(targetType, batch, counts); err != nil {
+			log.Printf("写入浏览次数失败: %v", err)
+			// 放回待写入哈希，下次再写入
+			pipe := v.Redis.Pipeline()
+			for _, id := range batch {
+				field := strconv.FormatUint(id, 10)
+				n, _ := strconv.ParseInt(counts[field], 10, 64)
+				pipe.HIncrBy(ctx, pendingKey, field, n)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				log.Printf("放回浏览次数失败: %v", err)
+			}
+			continue
+		}
+		flushed += len(batch)
+	}
+
+	v.Redis.Del(ctx, flushingKey)
+	return flushed
+}
+
+// flushBatch 用一条UPDATE语句为一批记录增加浏览次数
+// 浏览次数不算对内容的修改，不更新updated_at
+func (v *ViewCounter) flushBatch(table string, ids []uint64, counts map[string]string) error {
+	var sql strings.Builder
+	args := make([]interface{}, 0, len(ids)*2+1)
+	sql.WriteString("UPDATE " + table + " SET view_count = view_count + CASE id")
+	for _, id := range ids {
+		n, _ := strconv.ParseInt(counts[strconv.FormatUint(id, 10)], 10, 64)
+		sql.WriteString(" WHEN ? THEN ?")
+		args = append(args, id, n)
+	}
+	sql.WriteString(" ELSE 0 END WHERE id IN ?")
+	args = append(args, ids)
+	return v.DB.Exec(sql.String(), args...).Error
+}
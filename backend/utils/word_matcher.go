@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"unicode"
+)
+
+// WordMatch 一次匹配的结果，Start、End为原文中的rune下标（左闭右开）
+type WordMatch struct {
+	Start   int
+	End     int
+	Pattern int // 匹配到的词在词表中的序号
+}
+
+// acNode Aho-Corasick自动机的节点
+type acNode struct {
+	next    map[rune]int32
+	fail    int32
+	outputs []int // 以该节点结尾的词，包含沿失配链可以到达的词
+	depth   int   // 从根节点到该节点的字符数
+}
+
+// WordMatcher 基于Aho-Corasick自动机的多模式匹配，一次扫描找出文本中所有词表中的词
+// 匹配时忽略大小写和全角半角的差异，并跳过词中夹杂的空格和符号，例如“广 告”“广-告”中的空格和连字符
+type WordMatcher struct {
+	nodes   []acNode
+	lengths []int // 每个词去掉符号后的长度
+}
+
+// normalizeRune 统一大小写和全角半角
+func normalizeRune(r rune) rune {
+	switch {
+	case r == '　':
+		r = ' '
+	case r >= '！' && r <= '～':
+		r -= 0xFEE0
+	}
+	return unicode.ToLower(r)
+}
+
+// isNoiseRune 匹配时跳过的字符
+func isNoiseRune(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// NewWordMatcher 根据词表构建自动机，空词和只有符号的词会被忽略
+func NewWordMatcher(words []string) *WordMatcher {
+	m := &WordMatcher{nodes: []acNode{{next: map[rune]int32{}}}, lengths: make([]int, len(words))}
+
+	for i, word := range words {
+		state := int32(0)
+		for _, r := range word {
+			if isNoiseRune(r) {
+				continue
+			}
+			r = normalizeRune(r)
+			next, ok := m.nodes[state].next[r]
+			if !ok {
+				next = int32(len(m.nodes))
+				m.nodes = append(m.nodes, acNode{next: map[rune]int32{}, depth: m.nodes[state].depth + 1})
+				m.nodes[state].next[r] = next
+			}
+			state = next
+		}
+		m.lengths[i] = m.nodes[state].depth
+		if state != 0 {
+			m.nodes[state].outputs = append(m.nodes[state].outputs, i)
+		}
+	}
+
+	// 按层次遍历计算失配链接
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[state].next {
+			fail := m.nodes[state].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].next[r]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// FindAll 找出文本中所有词表中的词，重叠的匹配都会返回
+func (m *WordMatcher) FindAll(text string) []WordMatch {
+	if len(m.nodes) == 1 {
+		return nil
+	}
+
+	var matches []WordMatch
+	// positions 记录参与匹配的字符在原文中的下标，用于将匹配还原到原文
+	var positions []int
+	state := int32(0)
+	index := 0
+	for _, r := range text {
+		pos := index
+		index++
+		if isNoiseRune(r) {
+			continue
+		}
+		r = normalizeRune(r)
+		positions = append(positions, pos)
+
+		for state != 0 {
+			if _, ok := m.nodes[state].next[r]; ok {
+				break
+			}
+			state = m.nodes[state].fail
+		}
+		if next, ok := m.nodes[state].next[r]; ok {
+			state = next
+		}
+
+		for _, pattern := range m.nodes[state].outputs {
+			matches = append(matches, WordMatch{
+				Start:   positions[len(positions)-m.lengths[pattern]],
+				End:     pos + 1,
+				Pattern: pattern,
+			})
+		}
+	}
+	return matches
+}
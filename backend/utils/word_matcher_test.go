@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordMatcherFindAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		text  string
+		want  []WordMatch
+	}{
+		{"空词表", nil, "广告", nil},
+		{"忽略空词和符号", []string{"", " - "}, "广告", nil},
+		{"没有匹配", []string{"广告"}, "正常内容", nil},
+		{"简单匹配", []string{"广告"}, "这是广告", []WordMatch{{Start: 2, End: 4, Pattern: 0}}},
+		{"忽略大小写", []string{"spam"}, "Buy SPAM now", []WordMatch{{Start: 4, End: 8, Pattern: 0}}},
+		{"全角字符", []string{"qq"}, "加ＱＱ", []WordMatch{{Start: 1, End: 3, Pattern: 0}}},
+		{"跳过夹杂的符号", []string{"广告"}, "广 -告", []WordMatch{{Start: 0, End: 4, Pattern: 0}}},
+		{"词中的符号被忽略", []string{"广 告"}, "广告", []WordMatch{{Start: 0, End: 2, Pattern: 0}}},
+		{"多次出现", []string{"ab"}, "ab ab", []WordMatch{{Start: 0, End: 2, Pattern: 0}, {Start: 3, End: 5, Pattern: 0}}},
+		{"重叠匹配", []string{"abc", "bc", "c"}, "abc", []WordMatch{
+			{Start: 0, End: 3, Pattern: 0}, {Start: 1, End: 3, Pattern: 1}, {Start: 2, End: 3, Pattern: 2},
+		}},
+		{"沿失配链匹配", []string{"abd", "bc"}, "abc", []WordMatch{{Start: 1, End: 3, Pattern: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewWordMatcher(tt.words).FindAll(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}